@@ -0,0 +1,176 @@
+// Package apierrors maps the errors of the handlers, services and database to consistent JSON responses.
+// Set Handler as the ErrorHandler of the fiber app and return errors from the handlers.
+//
+//	app := fiber.New(fiber.Config{ErrorHandler: apierrors.Handler})
+//
+// Classified database errors (see mysql.Classify) are rendered as 404 (not found), 409 (duplicate key,
+// deleting a referenced record) or 422 (missing reference, check violation). Errors classified with
+// mysql.ClassifyTable keep the columns of their constraints. Records of other owners or tenants (ownership.ErrNotOwner,
+// tenancy.ErrOtherTenant) are rendered as 404 and requests without claims (ownership.ErrNoClaims) as 403.
+// Unknown errors are logged and rendered as 500 without details.
+package apierrors
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/filllabs/sincap-common/db/mysql"
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/db/ownership"
+	"github.com/filllabs/sincap-common/db/tenancy"
+	"github.com/filllabs/sincap-common/logging"
+	"github.com/filllabs/sincap-common/messages"
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+)
+
+// Codes of the errors
+const (
+	CodeNotFound   = "not_found"
+	CodeForbidden  = "forbidden"
+	CodeDuplicate  = "duplicate"
+	CodeReferenced = "referenced"
+	CodeReference  = "invalid_reference"
+	CodeCheck      = "check_violation"
+	CodeValidation = "validation"
+	CodeBusy       = "busy"
+	CodeTimeout    = "timeout"
+	CodeInternal   = "internal"
+)
+
+// Error is an error which is rendered to the client as {"error": "...", "code": "...", "field": "...", "fields": {...}}
+type Error struct {
+	Status  int    `json:"-"`
+	Message string `json:"error"`
+	Code    string `json:"code"`
+	// Field is the violating field of key, reference and check violations if known
+	Field string `json:"field,omitempty"`
+	// Fields are the messages of the validation errors by their fields (see messages.FieldErrors)
+	Fields map[string]string `json:"fields,omitempty"`
+	// Err is the cause, it is never rendered
+	Err error `json:"-"`
+	// key is the key of the message at the catalogue for the errors of the library (see messages)
+	key  string
+	args map[string]any
+}
+
+// Error returns the message
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New returns an error with the given status, code and message
+func New(status int, code string, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// From converts the given error to an Error. Errors are returned as is, fiber errors keep their status
+// validation errors are 422 and database errors are mapped by their classes. Others are 500.
+// Messages of the library are in the default language (see translations.DefaultLanguage), Handler localizes them.
+func From(err error) *Error {
+	var apiErr *Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if dbErr := FromDB(err); dbErr != nil {
+		return dbErr
+	}
+	if accessErr := fromAccess(err); accessErr != nil {
+		return accessErr
+	}
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) && len(validationErrs) > 0 {
+		return &Error{Status: fiber.StatusUnprocessableEntity, Code: CodeValidation, Message: err.Error(), Field: validationErrs[0].Field(),
+			Fields: messages.FieldErrors(translations.DefaultLanguage(), err), Err: err, key: messages.KeyValidationFailed, args: map[string]any{"count": len(validationErrs)}}
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return &Error{Status: fiberErr.Code, Code: codeOf(fiberErr.Code), Message: fiberErr.Message, Err: err}
+	}
+	return &Error{Status: fiber.StatusInternalServerError, Code: CodeInternal, Message: messages.Message(translations.DefaultLanguage(), messages.KeyInternal, nil), Err: err, key: messages.KeyInternal}
+}
+
+// FromDB maps the classified database errors. Returns nil if the error is not a known database error.
+func FromDB(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	classified := mysql.Classify(err)
+	var dbErr *mysql.Error
+	if !errors.As(classified, &dbErr) {
+		return nil
+	}
+	e := &Error{Field: dbErr.Column, Err: err}
+	switch dbErr.Class {
+	case mysql.ErrNotFound:
+		e.Status, e.Code, e.key = fiber.StatusNotFound, CodeNotFound, messages.KeyRecordNotFound
+	case mysql.ErrDuplicateKey:
+		e.Status, e.Code, e.key = fiber.StatusConflict, CodeDuplicate, messages.KeyDuplicate
+	case mysql.ErrForeignKey:
+		if dbErr.Code == mysql.CodeRowIsReferenced || dbErr.Code == mysql.CodeRowIsReferencedOld {
+			e.Status, e.Code, e.key = fiber.StatusConflict, CodeReferenced, messages.KeyReferenced
+		} else {
+			e.Status, e.Code, e.key = fiber.StatusUnprocessableEntity, CodeReference, messages.KeyInvalidReference
+		}
+	case mysql.ErrCheck:
+		e.Status, e.Code, e.key = fiber.StatusUnprocessableEntity, CodeCheck, messages.KeyCheckViolation
+	case mysql.ErrDeadlock, mysql.ErrLockTimeout:
+		e.Status, e.Code, e.key = fiber.StatusServiceUnavailable, CodeBusy, messages.KeyBusy
+	case mysql.ErrTimeout:
+		e.Status, e.Code, e.key = fiber.StatusGatewayTimeout, CodeTimeout, messages.KeyTimeout
+	default:
+		return nil
+	}
+	e.Message = messages.Message(translations.DefaultLanguage(), e.key, nil)
+	return e
+}
+
+// fromAccess converts the errors of the ownership and tenancy scopes, records of the others are not found
+// so their existence is not revealed
+func fromAccess(err error) *Error {
+	e := &Error{Err: err}
+	switch {
+	case errors.Is(err, ownership.ErrNotOwner), errors.Is(err, tenancy.ErrOtherTenant):
+		e.Status, e.Code, e.key = fiber.StatusNotFound, CodeNotFound, messages.KeyRecordNotFound
+	case errors.Is(err, ownership.ErrNoClaims):
+		e.Status, e.Code, e.key = fiber.StatusForbidden, CodeForbidden, messages.KeyForbidden
+	default:
+		return nil
+	}
+	e.Message = messages.Message(translations.DefaultLanguage(), e.key, nil)
+	return e
+}
+
+// Handler is a fiber error handler which renders the errors as JSON (see From).
+// Messages of the library are localized to the language of the request (see messages.Localize).
+func Handler(ctx *fiber.Ctx, err error) error {
+	e := From(err)
+	if e.Status >= fiber.StatusInternalServerError {
+		logging.Logger.Named("Server").Error("Request failed", zap.String("path", ctx.Path()), zap.Int("status", e.Status), zap.Error(err))
+	}
+	if e.key != "" {
+		lang := messages.Language(ctx.UserContext())
+		localized := *e
+		localized.Message = messages.Message(lang, e.key, e.args)
+		if e.Fields != nil {
+			localized.Fields = messages.FieldErrors(lang, e.Err)
+		}
+		e = &localized
+	}
+	return ctx.Status(e.Status).JSON(e)
+}
+
+// codeOf returns the snake case status text as the code of the status (Not Found => not_found)
+func codeOf(status int) string {
+	return strings.ReplaceAll(strings.ToLower(http.StatusText(status)), " ", "_")
+}
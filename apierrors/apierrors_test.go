@@ -0,0 +1,98 @@
+package apierrors
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/db/ownership"
+	"github.com/filllabs/sincap-common/db/tenancy"
+	"github.com/filllabs/sincap-common/validator"
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestHandler(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	errs := map[string]error{
+		"/notfound":   gorm.ErrRecordNotFound,
+		"/duplicate":  &driver.MySQLError{Number: 1062, Message: "Duplicate entry 'a@b.com' for key 'User.idx_User_Email'"},
+		"/referenced": &driver.MySQLError{Number: 1451, Message: "Cannot delete or update a parent row"},
+		"/reference":  &driver.MySQLError{Number: 1452, Message: "a foreign key constraint fails (`db`.`Order`, CONSTRAINT `fk_Order_User` FOREIGN KEY (`UserID`) REFERENCES `User` (`ID`))"},
+		"/check":      errors.New("CHECK constraint failed: Price"),
+		"/api":        New(fiber.StatusTeapot, "tea", "no coffee"),
+		"/fiber":      fiber.ErrForbidden,
+		"/unknown":    errors.New("secret details"),
+		"/owner":      fmt.Errorf("update: %w", ownership.ErrNotOwner),
+		"/tenant":     tenancy.ErrOtherTenant,
+		"/noclaims":   ownership.ErrNoClaims,
+	}
+	for path, err := range errs {
+		err := err
+		app.Get(path, func(c *fiber.Ctx) error { return err })
+	}
+
+	expected := map[string]Error{
+		"/notfound":   {Status: 404, Code: CodeNotFound, Message: "record not found"},
+		"/duplicate":  {Status: 409, Code: CodeDuplicate, Message: "record already exists", Field: "Email"},
+		"/referenced": {Status: 409, Code: CodeReferenced, Message: "record is referenced by other records"},
+		"/reference":  {Status: 422, Code: CodeReference, Message: "referenced record does not exist", Field: "UserID"},
+		"/check":      {Status: 422, Code: CodeCheck, Message: "invalid value", Field: "Price"},
+		"/api":        {Status: 418, Code: "tea", Message: "no coffee"},
+		"/fiber":      {Status: 403, Code: "forbidden", Message: "Forbidden"},
+		"/unknown":    {Status: 500, Code: CodeInternal, Message: "Internal Server Error"},
+		"/owner":      {Status: 404, Code: CodeNotFound, Message: "record not found"},
+		"/tenant":     {Status: 404, Code: CodeNotFound, Message: "record not found"},
+		"/noclaims":   {Status: 403, Code: CodeForbidden, Message: "access denied"},
+	}
+	for path, want := range expected {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		assert.Equal(t, want.Status, resp.StatusCode, path)
+		var body Error
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, want.Code, body.Code, path)
+		assert.Equal(t, want.Message, body.Message, path)
+		assert.Equal(t, want.Field, body.Field, path)
+	}
+}
+
+func TestHandlerLocalized(t *testing.T) {
+	app := fiber.New(fiber.Config{ErrorHandler: Handler})
+	app.Use(func(c *fiber.Ctx) error {
+		c.SetUserContext(context.WithValue(c.UserContext(), translations.LANG_KEY, "tr-TR"))
+		return c.Next()
+	})
+	app.Get("/notfound", func(c *fiber.Ctx) error { return gorm.ErrRecordNotFound })
+	app.Get("/api", func(c *fiber.Ctx) error { return New(fiber.StatusTeapot, "tea", "no coffee") })
+	app.Get("/validation", func(c *fiber.Ctx) error {
+		return validator.Validate.Struct(struct {
+			Name  string `validate:"required"`
+			Email string `validate:"required"`
+		}{})
+	})
+
+	expected := map[string]string{"/notfound": "kayıt bulunamadı", "/api": "no coffee", "/validation": "2 alan geçersiz"}
+	for path, want := range expected {
+		resp, err := app.Test(httptest.NewRequest("GET", path, nil))
+		assert.NoError(t, err)
+		var body Error
+		assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+		assert.Equal(t, want, body.Message, path)
+		if path == "/validation" {
+			assert.Equal(t, map[string]string{"Name": "Name zorunlu bir alandır", "Email": "Email zorunlu bir alandır"}, body.Fields)
+		}
+	}
+}
+
+func TestFromDefaultLanguage(t *testing.T) {
+	assert.NoError(t, translations.SetDefaultLanguage("tr-TR"))
+	defer translations.SetDefaultLanguage("en-US")
+	assert.Equal(t, "kayıt bulunamadı", From(gorm.ErrRecordNotFound).Message)
+}
@@ -7,13 +7,21 @@ import (
 	"errors"
 	"fmt"
 
+	jwtgo "github.com/dgrijalva/jwt-go"
 	"github.com/go-chi/jwtauth"
 	"github.com/golang-jwt/jwt/v4"
 )
 
-// FromContext decodes the given jwt from the context and returns a decrypted version of the claims
+// FromContext decodes the given jwt from the context and returns a decrypted version of the claims.
+// Tokens put by jwtauth.Verifier are supported as well.
 func FromContext(context context.Context, secret string) (*DecryptedClaims, error) {
-	token, _ := context.Value(jwtauth.TokenCtxKey).(*jwt.Token)
+	var token *jwt.Token
+	switch t := context.Value(jwtauth.TokenCtxKey).(type) {
+	case *jwt.Token:
+		token = t
+	case *jwtgo.Token:
+		token = fromVerifier(t)
+	}
 	eclaims, err := readEncrypted(token)
 	if err != nil {
 		return nil, fmt.Errorf("token error read token. %v", err)
@@ -36,6 +44,15 @@ func FromToken(token *jwt.Token, secret string) (*DecryptedClaims, error) {
 	return eclaims.Decrypt(secret)
 }
 
+// fromVerifier converts the token of jwtauth to a jwt/v4 token
+func fromVerifier(t *jwtgo.Token) *jwt.Token {
+	token := &jwt.Token{Raw: t.Raw, Header: t.Header, Claims: t.Claims, Signature: t.Signature, Valid: t.Valid}
+	if claims, ok := t.Claims.(jwtgo.MapClaims); ok {
+		token.Claims = jwt.MapClaims(claims)
+	}
+	return token
+}
+
 func readEncrypted(token *jwt.Token) (*EncryptedClaims, error) {
 	var claims jwt.MapClaims
 	if token != nil {
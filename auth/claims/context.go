@@ -0,0 +1,53 @@
+package claims
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/filllabs/sincap-common/locals"
+	"github.com/gofiber/fiber/v2"
+)
+
+type contextKey string
+
+const decryptedCtxKey contextKey = "decryptedClaims"
+
+// LocalsKey is the fiber locals key which Middleware stores the claims of the request.
+// It is read from c.Context() as well as c.UserContext(), see locals.Value.
+const LocalsKey = "decryptedClaims"
+
+// WithDecrypted returns a copy of the context which carries the given claims.
+// Request scoped helpers (ownership, audit etc.) read the current user with DecryptedFromContext.
+func WithDecrypted(ctx context.Context, c *DecryptedClaims) context.Context {
+	return context.WithValue(ctx, decryptedCtxKey, c)
+}
+
+// DecryptedFromContext returns the claims put by WithDecrypted or Middleware if any
+func DecryptedFromContext(ctx context.Context) (*DecryptedClaims, bool) {
+	return locals.Value[*DecryptedClaims](ctx, decryptedCtxKey, LocalsKey)
+}
+
+// Middleware copies the claims stored at the given locals key in to the request context.
+// It must be added after the middleware which authenticates the request and fills the locals.
+func Middleware(localsKey string) func(ctx *fiber.Ctx) error {
+	return func(ctx *fiber.Ctx) error {
+		if c, ok := ctx.Locals(localsKey).(*DecryptedClaims); ok {
+			ctx.Locals(LocalsKey, c)
+			ctx.SetUserContext(WithDecrypted(ctx.UserContext(), c))
+		}
+		return ctx.Next()
+	}
+}
+
+// HTTPMiddleware decrypts the claims of the jwt put by jwtauth.Verifier in to the request context (see FromContext).
+// It is the Middleware of the chi routers, requests without a valid token are passed as is.
+func HTTPMiddleware(secret string) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if c, err := FromContext(r.Context(), secret); err == nil {
+				r = r.WithContext(WithDecrypted(r.Context(), c))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
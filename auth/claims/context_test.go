@@ -0,0 +1,49 @@
+package claims
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/jwtauth"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPMiddleware(t *testing.T) {
+	secret := "0123456789abcdef0123456789abcdef"
+	var found *DecryptedClaims
+	handler := HTTPMiddleware(secret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		found, _ = DecryptedFromContext(r.Context())
+	}))
+
+	eclaims, err := (&DecryptedClaims{UserID: 7, Username: "user"}).Encrypt(secret)
+	assert.NoError(t, err)
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, eclaims).SignedString([]byte(secret))
+	assert.NoError(t, err)
+	token, err := jwtauth.New("HS256", []byte(secret), nil).Decode(signed)
+	assert.NoError(t, err)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(jwtauth.NewContext(r.Context(), token, nil)))
+	if assert.NotNil(t, found) {
+		assert.Equal(t, uint(7), found.UserID)
+		assert.Equal(t, "user", found.Username)
+	}
+
+	found = nil
+	v4Token, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return []byte(secret), nil })
+	assert.NoError(t, err)
+	handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(context.WithValue(r.Context(), jwtauth.TokenCtxKey, v4Token)))
+	assert.NotNil(t, found)
+
+	found = nil
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	assert.Nil(t, found)
+
+	found = nil
+	token.Valid = false
+	handler.ServeHTTP(httptest.NewRecorder(), r.WithContext(jwtauth.NewContext(r.Context(), token, nil)))
+	assert.Nil(t, found)
+}
@@ -0,0 +1,33 @@
+// Package backoff calculates the waits between the retries of failed operations.
+package backoff
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Jittered returns a random wait before the given retry (starting from 1). Its upper bound is base doubled on every
+// retry and limited by max, 0 max means unlimited. Randomness spreads the retries of concurrent failures.
+func Jittered(base time.Duration, max time.Duration, retry int) time.Duration {
+	ceiling := Ceiling(base, max, retry)
+	if ceiling <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+// Ceiling returns the upper bound of the wait before the given retry (see Jittered)
+func Ceiling(base time.Duration, max time.Duration, retry int) time.Duration {
+	if base <= 0 || retry < 1 {
+		return 0
+	}
+	ceiling := base << (retry - 1)
+	if ceiling <= 0 || ceiling>>(retry-1) != base {
+		ceiling = math.MaxInt64
+	}
+	if max > 0 && ceiling > max {
+		ceiling = max
+	}
+	return ceiling
+}
@@ -0,0 +1,26 @@
+package backoff
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCeiling(t *testing.T) {
+	assert.Equal(t, time.Second, Ceiling(time.Second, time.Minute, 1))
+	assert.Equal(t, 4*time.Second, Ceiling(time.Second, time.Minute, 3))
+	assert.Equal(t, time.Minute, Ceiling(time.Second, time.Minute, 10))
+	assert.Equal(t, time.Minute, Ceiling(time.Second, time.Minute, 100))
+	assert.Equal(t, time.Duration(math.MaxInt64), Ceiling(time.Second, 0, 100))
+	assert.Equal(t, time.Duration(0), Ceiling(0, time.Minute, 1))
+}
+
+func TestJittered(t *testing.T) {
+	for retry := 1; retry < 10; retry++ {
+		wait := Jittered(time.Millisecond, 20*time.Millisecond, retry)
+		assert.True(t, wait >= 0 && wait < Ceiling(time.Millisecond, 20*time.Millisecond, retry))
+	}
+	assert.Equal(t, time.Duration(0), Jittered(0, 0, 1))
+}
@@ -0,0 +1,58 @@
+// Package cache defines the backend interface of the caches and an in-memory implementation.
+package cache
+
+import (
+	"context"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+)
+
+// Backend stores encoded values by key
+type Backend interface {
+	// Get returns the value of the key, false if it is missing or expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set stores the value with the given ttl, 0 means no expiration
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Delete removes the key
+	Delete(ctx context.Context, key string) error
+}
+
+// Memory is an in-memory backend working with go-cache
+type Memory struct {
+	cache *gocache.Cache
+}
+
+// NewMemory returns an in-memory backend which purges the expired values with the given interval
+func NewMemory(cleanupInterval time.Duration) *Memory {
+	return &Memory{cache: gocache.New(gocache.NoExpiration, cleanupInterval)}
+}
+
+// Get returns the value of the key, false if it is missing or expired
+func (m *Memory) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	value, found := m.cache.Get(key)
+	if !found {
+		return nil, false, nil
+	}
+	return value.([]byte), true, nil
+}
+
+// Set stores the value with the given ttl, 0 means no expiration
+func (m *Memory) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if ttl <= 0 {
+		ttl = gocache.NoExpiration
+	}
+	m.cache.Set(key, value, ttl)
+	return nil
+}
+
+// Delete removes the key
+func (m *Memory) Delete(ctx context.Context, key string) error {
+	m.cache.Delete(key)
+	return nil
+}
+
+// Flush removes all keys
+func (m *Memory) Flush() {
+	m.cache.Flush()
+}
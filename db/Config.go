@@ -2,6 +2,7 @@ package db
 
 import (
 	"testing"
+	"time"
 
 	"github.com/filllabs/sincap-common/db/zapgorm"
 	"github.com/filllabs/sincap-common/logging"
@@ -20,31 +21,118 @@ type Config struct {
 	Args        []string `json:"args" yaml:"args"`
 	LogMode     bool     `json:"logMode" yaml:"logMode"`
 	AutoMigrate []string `json:"autoMigrate" yaml:"autoMigrate"`
+	// Tenants holds connection args for each tenant id (database per tenant mode).
+	// Statements of Get(name) with a tenant at the context (WithTenant or the tenancy middleware) run on the connection
+	// of the tenant. Tenant connections are also accessible via GetTenant(name, tenantID) or GetContext(ctx, name).
+	Tenants map[string][]string `json:"tenants,omitempty" yaml:"tenants,omitempty"`
+	// Replicas holds the DSNs of the read replicas. Reads are routed to the healthy replicas, writes to the primary.
+	Replicas []string `json:"replicas,omitempty" yaml:"replicas,omitempty"`
+	// ReplicaHealthCheck is the health check interval of the replicas in seconds. Default is 10.
+	ReplicaHealthCheck int `json:"replicaHealthCheck,omitempty" yaml:"replicaHealthCheck,omitempty"`
+	// MaxOpenConns is the maximum number of open connections of each pool. 0 means unlimited.
+	MaxOpenConns int `json:"maxOpenConns,omitempty" yaml:"maxOpenConns,omitempty"`
+	// MaxIdleConns is the maximum number of idle connections of each pool. 0 means the driver default (2).
+	MaxIdleConns int `json:"maxIdleConns,omitempty" yaml:"maxIdleConns,omitempty"`
+	// ConnMaxLifetime is the maximum lifetime of a connection in seconds. 0 means unlimited.
+	ConnMaxLifetime int `json:"connMaxLifetime,omitempty" yaml:"connMaxLifetime,omitempty"`
+	// ConnMaxIdleTime is the maximum idle time of a connection in seconds. 0 means unlimited.
+	ConnMaxIdleTime int `json:"connMaxIdleTime,omitempty" yaml:"connMaxIdleTime,omitempty"`
+	// StatementTimeout cancels the statements running longer than the given seconds. 0 means no timeout.
+	StatementTimeout int `json:"statementTimeout,omitempty" yaml:"statementTimeout,omitempty"`
+	// ConnectRetries is the number of retries of the first connection before giving up. Default is 0.
+	ConnectRetries int `json:"connectRetries,omitempty" yaml:"connectRetries,omitempty"`
+	// ConnectRetryInterval is the wait before the first retry in seconds, doubled on each retry up to a minute. Default is 1.
+	ConnectRetryInterval int `json:"connectRetryInterval,omitempty" yaml:"connectRetryInterval,omitempty"`
 }
 
+const maxConnectRetryInterval = time.Minute
+
 // Configure DB connection
 func Configure(dbConfs []Config) {
 
 	for i := range dbConfs {
 		conf := dbConfs[i]
-		args := make([]interface{}, len(conf.Args))
-		for i, v := range conf.Args {
-			args[i] = v
+		db[conf.Name] = open(conf.Name, conf.Args, conf)
+		for tenantID, args := range conf.Tenants {
+			name := tenantConnectionName(conf.Name, tenantID)
+			db[name] = open(name, args, conf)
+		}
+		if len(conf.Tenants) > 0 {
+			routeTenants(conf.Name, db[conf.Name])
+		}
+		// after routeTenants so the replicas only serve the shared reads
+		configureReplicas(conf.Name, db[conf.Name], conf)
+	}
+}
+
+// open connects to the given args, retries with backoff according to the config and stops the application if all attempts fail.
+func open(name string, args []string, conf Config) *gorm.DB {
+	wait := time.Second
+	if conf.ConnectRetryInterval > 0 {
+		wait = time.Duration(conf.ConnectRetryInterval) * time.Second
+	}
+	for attempt := 0; ; attempt++ {
+		DB, err := connect(args[0], conf, false)
+		if err == nil {
+			logging.Logger.Info("DB initialized", zap.String("name", name))
+			return DB
+		}
+		if attempt >= conf.ConnectRetries {
+			logging.Logger.Fatal("DB Could not open connection.", zap.String("name", name), zap.Int("attempts", attempt+1), zap.Error(err))
+		}
+		logging.Logger.Warn("DB Could not open connection. Retrying", zap.String("name", name), zap.Duration("wait", wait), zap.Error(err))
+		time.Sleep(wait)
+		if wait *= 2; wait > maxConnectRetryInterval {
+			wait = maxConnectRetryInterval
 		}
-		conn, err := gorm.Open(mysql.Open(conf.Args[0]), &gorm.Config{
-			NamingStrategy:                           AsIsNamingStrategy(),
-			Logger:                                   zapgorm.New(logging.Logger, conf.LogMode),
-			DisableForeignKeyConstraintWhenMigrating: true,
-			SkipDefaultTransaction:                   true,
-		})
+	}
+}
 
-		if err != nil {
-			logging.Logger.Fatal("DB Could not open connection.", zap.String("name", conf.Name), zap.Error(err))
+func connect(dsn string, conf Config, lazy bool) (*gorm.DB, error) {
+	conn, err := gorm.Open(mysql.Open(dsn), &gorm.Config{
+		NamingStrategy:                           AsIsNamingStrategy(),
+		Logger:                                   zapgorm.New(logging.Logger, conf.LogMode),
+		DisableForeignKeyConstraintWhenMigrating: true,
+		SkipDefaultTransaction:                   true,
+		DisableAutomaticPing:                     lazy,
+	})
+	if err != nil {
+		// gorm returns the opened pool even if the ping fails
+		if conn != nil {
+			Close(conn)
+		}
+		return nil, err
+	}
+	if err := configurePool(conn, conf); err != nil {
+		return nil, err
+	}
+	if conf.StatementTimeout > 0 {
+		if err := conn.Use(&statementTimeout{timeout: time.Duration(conf.StatementTimeout) * time.Second}); err != nil {
+			return nil, err
 		}
-		DB := conn.Session(&gorm.Session{FullSaveAssociations: true})
-		db[conf.Name] = DB
-		logging.Logger.Info("DB initialized", zap.String("name", conf.Name))
 	}
+	return conn.Session(&gorm.Session{FullSaveAssociations: true}), nil
+}
+
+// configurePool applies the pool settings of the config to the given connection
+func configurePool(conn *gorm.DB, conf Config) error {
+	sdb, err := conn.DB()
+	if err != nil {
+		return err
+	}
+	if conf.MaxOpenConns > 0 {
+		sdb.SetMaxOpenConns(conf.MaxOpenConns)
+	}
+	if conf.MaxIdleConns > 0 {
+		sdb.SetMaxIdleConns(conf.MaxIdleConns)
+	}
+	if conf.ConnMaxLifetime > 0 {
+		sdb.SetConnMaxLifetime(time.Duration(conf.ConnMaxLifetime) * time.Second)
+	}
+	if conf.ConnMaxIdleTime > 0 {
+		sdb.SetConnMaxIdleTime(time.Duration(conf.ConnMaxIdleTime) * time.Second)
+	}
+	return nil
 }
 
 // ConfigureTestDB returns new mock db connection for test and override db instance with mock db connection.
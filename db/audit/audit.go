@@ -0,0 +1,299 @@
+// Package audit provides a gorm plugin which records create, update and delete operations with
+// field level diffs to the AuditLog table. Actor is read from the claims at the statement context
+// (see claims.WithDecrypted) and request id from the fiber requestid middleware or WithRequestID.
+//
+//	db.DB().AutoMigrate(&audit.AuditLog{})
+//	db.DB().Use(audit.Plugin{Exclude: []string{"Session"}})
+//
+// Updates and deletes read the affected rows before and after the operation, so batch operations on
+// big tables should be excluded or run without the plugin. Fields tagged with `audit:"-"` and
+// auto create/update time fields are never recorded.
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+type contextKey string
+
+const requestIDCtxKey contextKey = "auditRequestID"
+
+// requestIDLocalsKey is the default locals key of the fiber requestid middleware
+const requestIDLocalsKey = "requestid"
+
+const snapshotKey = "sincap:audit:snapshot"
+
+var auditLogType = reflect.TypeOf(AuditLog{})
+
+// WithRequestID returns a copy of the context which carries the given request id
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDCtxKey, requestID)
+}
+
+// Plugin records all changes of the entities in to the AuditLog table
+type Plugin struct {
+	// Exclude holds table names which are not audited
+	Exclude []string
+}
+
+// Name returns the name of the plugin
+func (p Plugin) Name() string {
+	return "sincap:audit"
+}
+
+// Initialize registers audit callbacks to the given db
+func (p Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Create().After("gorm:create").Register("sincap:audit:create", p.afterCreate); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("sincap:audit:before_update", p.snapshot); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("sincap:audit:update", p.afterUpdate); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("sincap:audit:before_delete", p.snapshot); err != nil {
+		return err
+	}
+	return cb.Delete().After("gorm:delete").Register("sincap:audit:delete", p.afterDelete)
+}
+
+func (p Plugin) audited(db *gorm.DB) bool {
+	stmt := db.Statement
+	if db.Error != nil || stmt.Schema == nil || stmt.Schema.ModelType == auditLogType || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+	for _, table := range p.Exclude {
+		if table == stmt.Schema.Table {
+			return false
+		}
+	}
+	return true
+}
+
+func (p Plugin) afterCreate(db *gorm.DB) {
+	if !p.audited(db) {
+		return
+	}
+	stmt := db.Statement
+	var logs []AuditLog
+	for _, record := range records(stmt.ReflectValue) {
+		changes := map[string]Change{}
+		for _, field := range auditedFields(stmt.Schema) {
+			if value, isZero := field.ValueOf(stmt.Context, record); !isZero {
+				changes[field.DBName] = Change{New: value}
+			}
+		}
+		logs = append(logs, newLog(stmt, ActionCreate, record, changes))
+	}
+	write(db, logs)
+}
+
+// snapshot reads the rows which will be affected by the update or delete
+func (p Plugin) snapshot(db *gorm.DB) {
+	if !p.audited(db) {
+		return
+	}
+	stmt := db.Statement
+	var exprs []clause.Expression
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok {
+			exprs = append(exprs, where.Exprs...)
+		}
+	}
+	if rv := reflect.Indirect(stmt.ReflectValue); rv.Kind() == reflect.Struct {
+		for _, pk := range stmt.Schema.PrimaryFields {
+			if value, isZero := pk.ValueOf(stmt.Context, rv); !isZero {
+				exprs = append(exprs, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: value})
+			}
+		}
+	}
+	// gorm refuses global updates and deletes anyway
+	if len(exprs) == 0 {
+		return
+	}
+	rows, err := load(db, clause.Where{Exprs: exprs})
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	db.InstanceSet(snapshotKey, rows)
+}
+
+func (p Plugin) afterUpdate(db *gorm.DB) {
+	before, ok := snapshotOf(db)
+	if !ok || !p.audited(db) {
+		return
+	}
+	stmt := db.Statement
+	after, err := load(db, byPrimaryKeys(stmt, before))
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	afterByID := make(map[string]reflect.Value, after.Len())
+	for i := 0; i < after.Len(); i++ {
+		afterByID[entityID(stmt, after.Index(i))] = after.Index(i)
+	}
+	var logs []AuditLog
+	for i := 0; i < before.Len(); i++ {
+		old := before.Index(i)
+		current, found := afterByID[entityID(stmt, old)]
+		if !found {
+			continue
+		}
+		if changes := diff(stmt, old, current); len(changes) > 0 {
+			logs = append(logs, newLog(stmt, ActionUpdate, current, changes))
+		}
+	}
+	write(db, logs)
+}
+
+func (p Plugin) afterDelete(db *gorm.DB) {
+	before, ok := snapshotOf(db)
+	if !ok || !p.audited(db) {
+		return
+	}
+	stmt := db.Statement
+	var logs []AuditLog
+	for i := 0; i < before.Len(); i++ {
+		old := before.Index(i)
+		changes := map[string]Change{}
+		for _, field := range auditedFields(stmt.Schema) {
+			if value, isZero := field.ValueOf(stmt.Context, old); !isZero {
+				changes[field.DBName] = Change{Old: value}
+			}
+		}
+		logs = append(logs, newLog(stmt, ActionDelete, old, changes))
+	}
+	write(db, logs)
+}
+
+func snapshotOf(db *gorm.DB) (reflect.Value, bool) {
+	rows, ok := db.InstanceGet(snapshotKey)
+	if !ok {
+		return reflect.Value{}, false
+	}
+	return rows.(reflect.Value), true
+}
+
+// load reads the rows of the statement model matching the given where clause with the same connection and context.
+func load(db *gorm.DB, where clause.Where) (reflect.Value, error) {
+	stmt := db.Statement
+	rows := reflect.New(reflect.SliceOf(stmt.Schema.ModelType))
+	tx := db.Session(&gorm.Session{NewDB: true}).Table(stmt.Table)
+	if stmt.Unscoped {
+		tx = tx.Unscoped()
+	}
+	err := tx.Clauses(where).Find(rows.Interface()).Error
+	return rows.Elem(), err
+}
+
+func byPrimaryKeys(stmt *gorm.Statement, rows reflect.Value) clause.Where {
+	var ors []clause.Expression
+	for i := 0; i < rows.Len(); i++ {
+		var ands []clause.Expression
+		for _, pk := range stmt.Schema.PrimaryFields {
+			value, _ := pk.ValueOf(stmt.Context, rows.Index(i))
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: value})
+		}
+		ors = append(ors, clause.And(ands...))
+	}
+	return clause.Where{Exprs: []clause.Expression{clause.Or(ors...)}}
+}
+
+func diff(stmt *gorm.Statement, old reflect.Value, current reflect.Value) map[string]Change {
+	changes := map[string]Change{}
+	for _, field := range auditedFields(stmt.Schema) {
+		oldValue, _ := field.ValueOf(stmt.Context, old)
+		newValue, _ := field.ValueOf(stmt.Context, current)
+		if !reflect.DeepEqual(oldValue, newValue) {
+			changes[field.DBName] = Change{Old: oldValue, New: newValue}
+		}
+	}
+	return changes
+}
+
+func auditedFields(s *schema.Schema) []*schema.Field {
+	var fields []*schema.Field
+	for _, field := range s.Fields {
+		if field.DBName == "" || field.AutoCreateTime > 0 || field.AutoUpdateTime > 0 || field.Tag.Get("audit") == "-" {
+			continue
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func newLog(stmt *gorm.Statement, action string, record reflect.Value, changes map[string]Change) AuditLog {
+	log := AuditLog{
+		Entity:    stmt.Schema.Table,
+		EntityID:  entityID(stmt, record),
+		Action:    action,
+		RequestID: requestID(stmt.Context),
+	}
+	if c, ok := claims.DecryptedFromContext(stmt.Context); ok {
+		log.ActorID = c.UserID
+		log.ActorName = c.Username
+	}
+	if data, err := json.Marshal(changes); err == nil {
+		log.Changes = data
+	}
+	return log
+}
+
+// entityID joins the primary key values with "," (composite keys)
+func entityID(stmt *gorm.Statement, record reflect.Value) string {
+	ids := make([]string, len(stmt.Schema.PrimaryFields))
+	for i, pk := range stmt.Schema.PrimaryFields {
+		value, _ := pk.ValueOf(stmt.Context, record)
+		ids[i] = fmt.Sprint(value)
+	}
+	return strings.Join(ids, ",")
+}
+
+func requestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	if id, ok := ctx.Value(requestIDCtxKey).(string); ok {
+		return id
+	}
+	id, _ := ctx.Value(requestIDLocalsKey).(string)
+	return id
+}
+
+// records returns the struct values of the given struct or slice value
+func records(rv reflect.Value) []reflect.Value {
+	rv = reflect.Indirect(rv)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		values := make([]reflect.Value, 0, rv.Len())
+		for i := 0; i < rv.Len(); i++ {
+			values = append(values, reflect.Indirect(rv.Index(i)))
+		}
+		return values
+	case reflect.Struct:
+		return []reflect.Value{rv}
+	}
+	return nil
+}
+
+func write(db *gorm.DB, logs []AuditLog) {
+	if len(logs) == 0 {
+		return
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Create(&logs).Error; err != nil {
+		db.AddError(err)
+	}
+}
@@ -0,0 +1,72 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/db/mysql"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Price struct {
+	util.Model
+	Product  string
+	Amount   float64
+	Password string `audit:"-"`
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	DB := dbtest.Open(t, &Price{}, &AuditLog{})
+	assert.NoError(t, DB.Use(Plugin{}))
+	return DB
+}
+
+func TestPlugin(t *testing.T) {
+	DB := openDB(t)
+	ctx := claims.WithDecrypted(context.Background(), &claims.DecryptedClaims{UserID: 7, Username: "seray"})
+	ctx = WithRequestID(ctx, "req-1")
+	DB = DB.WithContext(ctx)
+
+	price := Price{Product: "coffee", Amount: 3.5, Password: "secret"}
+	assert.NoError(t, mysql.Create(DB, &price))
+	assert.NoError(t, mysql.Update(DB, &Price{Model: util.Model{ID: price.ID}}, map[string]any{"Amount": 4.0, "Password": "other"}))
+	assert.NoError(t, mysql.Delete(DB, &price))
+
+	logs, count, err := History(DB, &Price{}, price.ID, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Equal(t, ActionDelete, logs[0].Action)
+	assert.Equal(t, ActionUpdate, logs[1].Action)
+	assert.Equal(t, ActionCreate, logs[2].Action)
+
+	update := logs[1]
+	assert.Equal(t, "Price", update.Entity)
+	assert.Equal(t, uint(7), update.ActorID)
+	assert.Equal(t, "seray", update.ActorName)
+	assert.Equal(t, "req-1", update.RequestID)
+	changes := map[string]Change{}
+	assert.NoError(t, update.Changes.Unmarshal(&changes))
+	assert.Equal(t, map[string]Change{"Amount": {Old: 3.5, New: 4.0}}, changes)
+
+	assert.NotContains(t, string(logs[2].Changes), "secret")
+
+	logs, count, err = History(DB, &Price{}, price.ID, &qapi.Query{Filter: []qapi.Filter{{Name: "Action", Operation: qapi.EQ, Value: ActionUpdate}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Len(t, logs, 1)
+}
+
+func TestPluginExclude(t *testing.T) {
+	DB := dbtest.Open(t, &Price{}, &AuditLog{})
+	assert.NoError(t, DB.Use(Plugin{Exclude: []string{"Price"}}))
+
+	assert.NoError(t, DB.Create(&Price{Product: "tea"}).Error)
+	var count int64
+	assert.NoError(t, DB.Model(&AuditLog{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
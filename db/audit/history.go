@@ -0,0 +1,33 @@
+package audit
+
+import (
+	"fmt"
+
+	"github.com/filllabs/sincap-common/db/mysql"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"gorm.io/gorm"
+)
+
+// History lists the audit logs of the record with the given id. record is only used for resolving the table name.
+// query may contain any qapi filters (Action, ActorID, CreatedAt ...), sorting and pagination.
+// Logs are sorted by newest first if the query has no sort. Returns the logs and the total count.
+func History(DB *gorm.DB, record any, id any, query *qapi.Query) ([]AuditLog, int, error) {
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(record); err != nil {
+		return nil, 0, err
+	}
+	q := qapi.Query{}
+	if query != nil {
+		q = *query
+	}
+	q.Filter = append([]qapi.Filter{
+		{Name: "Entity", Operation: qapi.EQ, Value: stmt.Schema.Table},
+		{Name: "EntityID", Operation: qapi.EQ, Value: fmt.Sprint(id)},
+	}, q.Filter...)
+	if len(q.Sort) == 0 {
+		q.Sort = []string{"ID desc"}
+	}
+	var logs []AuditLog
+	count, err := mysql.List(DB, &logs, &q)
+	return logs, count, err
+}
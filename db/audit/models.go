@@ -0,0 +1,36 @@
+package audit
+
+import (
+	"time"
+
+	"github.com/filllabs/sincap-common/db/types"
+)
+
+const (
+	// ActionCreate is the action of the logs written after create
+	ActionCreate = "create"
+	// ActionUpdate is the action of the logs written after update
+	ActionUpdate = "update"
+	// ActionDelete is the action of the logs written after delete (soft or hard)
+	ActionDelete = "delete"
+)
+
+// AuditLog holds a single change of an entity record
+type AuditLog struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index"`
+	Entity    string    `gorm:"index:idx_audit_entity;size:128"`
+	EntityID  string    `gorm:"index:idx_audit_entity;size:128"`
+	Action    string    `gorm:"size:16"`
+	ActorID   uint      `gorm:"index"`
+	ActorName string    `gorm:"size:128"`
+	RequestID string    `gorm:"size:64"`
+	// Changes holds the changed columns as {"Column": {"old": ..., "new": ...}}
+	Changes types.JSON `gorm:"type:json"`
+}
+
+// Change holds the old and the new value of a column
+type Change struct {
+	Old any `json:"old,omitempty"`
+	New any `json:"new,omitempty"`
+}
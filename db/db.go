@@ -4,6 +4,11 @@
 package db
 
 import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
 	"github.com/filllabs/sincap-common/logging"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
@@ -23,6 +28,12 @@ func GetDefault() *gorm.DB {
 	return Get("default")
 }
 
+// Has checks if a connection with the given name is configured
+func Has(name string) bool {
+	_, ok := db[name]
+	return ok
+}
+
 // Get returns the DB connection with the given name.
 func Get(name string) *gorm.DB {
 	conn, ok := db[name]
@@ -48,6 +59,9 @@ func Close(db *gorm.DB) error {
 
 // CloseAll tries to close all db connections
 func CloseAll() {
+	for _, r := range resolvers {
+		r.close()
+	}
 	for name, con := range db {
 		sdb, err := con.DB()
 		if err != nil {
@@ -60,3 +74,53 @@ func CloseAll() {
 	}
 	logging.Logger.Named("DB").Info("connections closed")
 }
+
+// PingError holds the ping errors of the connections by name
+type PingError map[string]error
+
+// Error lists the failed connections with their errors
+func (e PingError) Error() string {
+	names := make([]string, 0, len(e))
+	for name := range e {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	msgs := make([]string, len(names))
+	for i, name := range names {
+		msgs[i] = name + ": " + e[name].Error()
+	}
+	return "DB ping failed. " + strings.Join(msgs, ", ")
+}
+
+// Ping checks all named connections with the given context (timeout). Returns PingError if any of them fails.
+// Replicas are not included since they are checked periodically and skipped while unhealthy.
+func Ping(ctx context.Context) error {
+	failed := PingError{}
+	for name, con := range db {
+		sdb, err := con.DB()
+		if err == nil {
+			err = sdb.PingContext(ctx)
+		}
+		if err != nil {
+			failed[name] = err
+		}
+	}
+	if len(failed) > 0 {
+		return failed
+	}
+	return nil
+}
+
+// Stats returns the pool statistics of all named connections by name
+func Stats() map[string]sql.DBStats {
+	stats := make(map[string]sql.DBStats, len(db))
+	for name, con := range db {
+		sdb, err := con.DB()
+		if err != nil {
+			logging.Logger.Named("DB").Error("Can't get sql DB connection", zap.String("name", name), zap.Error(err))
+			continue
+		}
+		stats[name] = sdb.Stats()
+	}
+	return stats
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPingAndStats(t *testing.T) {
+	ConfigureMockDB(t.Name())
+	defer delete(db, "default")
+
+	assert.NoError(t, Ping(context.Background()))
+	stats := Stats()
+	assert.Contains(t, stats, "default")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := Ping(ctx)
+	assert.Error(t, err)
+	assert.Contains(t, err.(PingError), "default")
+}
+
+func TestStatementTimeout(t *testing.T) {
+	conn := openSqlite(t, t.Name())
+	assert.NoError(t, conn.Use(&statementTimeout{timeout: time.Nanosecond}))
+	var items []Item
+	assert.ErrorIs(t, conn.Find(&items).Error, context.DeadlineExceeded)
+
+	conn = openSqlite(t, t.Name()+"long")
+	assert.NoError(t, conn.Use(&statementTimeout{timeout: time.Minute}))
+	assert.NoError(t, conn.Create(&Item{Name: "item"}).Error)
+	assert.NoError(t, conn.Find(&items).Error)
+	assert.Len(t, items, 1)
+}
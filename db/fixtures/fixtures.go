@@ -0,0 +1,543 @@
+// Package fixtures loads YAML or JSON fixture files to the database for tests. Files are maps of table names
+// to named records. Records are inserted in dependency order, so they may reference each other with
+// "$Table.name" (primary key) or "$Table.name.Field". String values may be templates (see Funcs), their data is
+// the table and the name of the record (.Table and .Name), not its field values.
+//
+//	User:
+//	  alice:
+//	    Name: Alice
+//	    CreatedAt: '{{ now "-24h" }}'
+//	Order:
+//	  first:
+//	    UserID: $User.alice
+//	    Note: 'fixture {{ .Table }}.{{ .Name }}' # fixture Order.first
+//	UserRole: # tables without a model are inserted as is (many2many tables)
+//	  alice_admin:
+//	    UserID: $User.alice
+//	    RoleID: 1
+//
+// A value starting with "$$" is written with a single "$".
+package fixtures
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/yosuke-furukawa/json5/encoding/json5"
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var (
+	// ErrDuplicate is returned when a record is defined more than once
+	ErrDuplicate = errors.New("fixtures: duplicate record")
+	// ErrUnknownReference is returned when a reference points to an undefined record or field
+	ErrUnknownReference = errors.New("fixtures: unknown reference")
+	// ErrCycle is returned when the records reference each other
+	ErrCycle = errors.New("fixtures: reference cycle")
+	// ErrUnknownField is returned when a record has a field which is not in the model
+	ErrUnknownField = errors.New("fixtures: unknown field")
+	// ErrFormat is returned when the file extension is not one of .yml, .yaml, .json or .json5
+	ErrFormat = errors.New("fixtures: unsupported file format")
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// record is a single named row of a fixture file
+type record struct {
+	table  string
+	name   string
+	values map[string]any
+	// loaded holds the created model pointer or the row map for tables without a model
+	loaded any
+}
+
+func (r *record) key() string {
+	return r.table + "." + r.name
+}
+
+// Fixtures loads fixture files for the registered models
+type Fixtures struct {
+	db      *gorm.DB
+	schemas map[string]*schema.Schema
+	tables  []string
+	records map[string]*record
+	seq     int
+}
+
+// New returns fixtures for the given models. Model tables are resolved with the naming strategy of the connection.
+func New(DB *gorm.DB, models ...any) (*Fixtures, error) {
+	f := &Fixtures{db: DB, schemas: map[string]*schema.Schema{}, records: map[string]*record{}}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: DB}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		f.schemas[stmt.Schema.Table] = stmt.Schema
+		f.tables = append(f.tables, stmt.Schema.Table)
+	}
+	return f, nil
+}
+
+// Load reads the given files or directories (all .yml, .yaml, .json and .json5 files) and inserts their records in a single transaction.
+// Records of the previous loads may be referenced.
+func (f *Fixtures) Load(paths ...string) (err error) {
+	var (
+		files   []string
+		records []*record
+	)
+	// forget the records of the failed load, the transaction is rolled back
+	defer func() {
+		if err != nil {
+			for _, r := range records {
+				delete(f.records, r.key())
+			}
+		}
+	}()
+	for _, p := range paths {
+		info, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			files = append(files, p)
+			continue
+		}
+		entries, err := os.ReadDir(p)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() && format(entry.Name()) != "" {
+				files = append(files, filepath.Join(p, entry.Name()))
+			}
+		}
+	}
+	for _, file := range files {
+		read, err := f.read(file)
+		records = append(records, read...)
+		if err != nil {
+			return err
+		}
+	}
+	ordered, err := f.order(records)
+	if err != nil {
+		return err
+	}
+	return f.db.Transaction(func(tx *gorm.DB) error {
+		for _, r := range ordered {
+			if err := f.insert(tx, r); err != nil {
+				return fmt.Errorf("fixtures: %s: %w", r.key(), err)
+			}
+		}
+		return nil
+	})
+}
+
+// Get returns the loaded model pointer (or the row map for tables without a model) of the given "Table.name"
+func (f *Fixtures) Get(key string) (any, bool) {
+	r, ok := f.records[key]
+	if !ok || r.loaded == nil {
+		return nil, false
+	}
+	return r.loaded, true
+}
+
+// Reset deletes all rows of the model tables and the tables loaded before and resets their auto increments.
+// Loaded records are forgotten.
+func (f *Fixtures) Reset() error {
+	tables := append([]string(nil), f.tables...)
+	for _, r := range f.records {
+		if _, ok := f.schemas[r.table]; !ok && !contains(tables, r.table) {
+			tables = append(tables, r.table)
+		}
+	}
+	if err := Reset(f.db, tables...); err != nil {
+		return err
+	}
+	f.records = map[string]*record{}
+	return nil
+}
+
+// Reset deletes all rows of the given tables and resets their auto increments. Foreign key checks are disabled while resetting on MySQL.
+func Reset(DB *gorm.DB, tables ...string) error {
+	return DB.Connection(func(conn *gorm.DB) error {
+		if conn.Dialector.Name() == "mysql" {
+			if err := conn.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+				return err
+			}
+			defer conn.Exec("SET FOREIGN_KEY_CHECKS = 1")
+			for _, table := range tables {
+				if err := conn.Exec("TRUNCATE TABLE ?", gorm.Expr(conn.Statement.Quote(table))).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		for _, table := range tables {
+			if err := conn.Exec("DELETE FROM ?", gorm.Expr(conn.Statement.Quote(table))).Error; err != nil {
+				return err
+			}
+			if conn.Dialector.Name() == "sqlite" && conn.Migrator().HasTable("sqlite_sequence") {
+				if err := conn.Exec("DELETE FROM sqlite_sequence WHERE name = ?", table).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+func format(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yml", ".yaml":
+		return "yaml"
+	case ".json", ".json5":
+		return "json"
+	}
+	return ""
+}
+
+// read parses the file and renders the templates of its records
+func (f *Fixtures) read(file string) ([]*record, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	tables := map[string]map[string]map[string]any{}
+	switch format(file) {
+	case "yaml":
+		err = yaml.Unmarshal(data, &tables)
+	case "json":
+		err = json5.Unmarshal(data, &tables)
+	default:
+		err = ErrFormat
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fixtures: %s: %w", file, err)
+	}
+	var records []*record
+	for table, rows := range tables {
+		for name, values := range rows {
+			r := &record{table: table, name: name, values: values}
+			if _, ok := f.records[r.key()]; ok {
+				return records, fmt.Errorf("%w: %s", ErrDuplicate, r.key())
+			}
+			for column, value := range values {
+				if values[column], err = f.render(r, value); err != nil {
+					return records, fmt.Errorf("fixtures: %s.%s: %w", r.key(), column, err)
+				}
+			}
+			f.records[r.key()] = r
+			records = append(records, r)
+		}
+	}
+	return records, nil
+}
+
+// order sorts the records so that the referenced ones come first
+func (f *Fixtures) order(records []*record) ([]*record, error) {
+	sort.Slice(records, func(i, j int) bool { return records[i].key() < records[j].key() })
+	deps := map[*record][]*record{}
+	for _, r := range records {
+		for _, ref := range references(r.values, nil) {
+			dep, ok := f.records[ref.key]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s in %s", ErrUnknownReference, ref.key, r.key())
+			}
+			if dep.loaded == nil && dep != r {
+				deps[r] = append(deps[r], dep)
+			}
+		}
+	}
+	const (
+		visiting = 1
+		visited  = 2
+	)
+	state := map[*record]int{}
+	ordered := make([]*record, 0, len(records))
+	var visit func(r *record) error
+	visit = func(r *record) error {
+		switch state[r] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("%w: %s", ErrCycle, r.key())
+		}
+		state[r] = visiting
+		for _, dep := range deps[r] {
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		state[r] = visited
+		ordered = append(ordered, r)
+		return nil
+	}
+	for _, r := range records {
+		if err := visit(r); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+func (f *Fixtures) insert(tx *gorm.DB, r *record) error {
+	values := make(map[string]any, len(r.values))
+	for column, value := range r.values {
+		resolved, err := f.resolve(value)
+		if err != nil {
+			return err
+		}
+		values[column] = resolved
+	}
+	s, ok := f.schemas[r.table]
+	if !ok {
+		if err := tx.Table(r.table).Create(values).Error; err != nil {
+			return err
+		}
+		r.loaded = values
+		return nil
+	}
+	model := reflect.New(s.ModelType)
+	for column, value := range values {
+		field := s.LookUpField(column)
+		if field == nil || field.DBName == "" {
+			return fmt.Errorf("%w: %s", ErrUnknownField, column)
+		}
+		converted, err := convert(field, value)
+		if err != nil {
+			return err
+		}
+		if err := field.Set(tx.Statement.Context, model.Elem(), converted); err != nil {
+			return err
+		}
+	}
+	if err := tx.Create(model.Interface()).Error; err != nil {
+		return err
+	}
+	r.loaded = model.Interface()
+	return nil
+}
+
+// convert prepares the decoded value for the field setter of gorm
+func convert(field *schema.Field, value any) (any, error) {
+	typ := field.FieldType
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	switch v := value.(type) {
+	case string:
+		if typ == timeType {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				return t, nil
+			}
+		}
+	case map[string]any, []any:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		if typ.Kind() == reflect.Slice && typ.Elem().Kind() == reflect.Uint8 {
+			return data, nil
+		}
+		target := reflect.New(field.FieldType)
+		if err := json.Unmarshal(data, target.Interface()); err != nil {
+			return nil, err
+		}
+		return target.Elem().Interface(), nil
+	}
+	return value, nil
+}
+
+// reference is a parsed "$Table.name.Field" value
+type reference struct {
+	key   string
+	field string
+}
+
+func parseReference(value string) (reference, bool) {
+	if !strings.HasPrefix(value, "$") || strings.HasPrefix(value, "$$") {
+		return reference{}, false
+	}
+	parts := strings.SplitN(value[1:], ".", 3)
+	if len(parts) < 2 {
+		return reference{}, false
+	}
+	ref := reference{key: parts[0] + "." + parts[1]}
+	if len(parts) == 3 {
+		ref.field = parts[2]
+	}
+	return ref, true
+}
+
+// references collects the references at the given value recursively
+func references(value any, refs []reference) []reference {
+	switch v := value.(type) {
+	case string:
+		if ref, ok := parseReference(v); ok {
+			refs = append(refs, ref)
+		}
+	case map[string]any:
+		for _, item := range v {
+			refs = references(item, refs)
+		}
+	case []any:
+		for _, item := range v {
+			refs = references(item, refs)
+		}
+	}
+	return refs
+}
+
+// resolve replaces the references with the values of the loaded records recursively
+func (f *Fixtures) resolve(value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "$$") {
+			return v[1:], nil
+		}
+		ref, ok := parseReference(v)
+		if !ok {
+			return v, nil
+		}
+		return f.lookup(ref)
+	case map[string]any:
+		resolved := make(map[string]any, len(v))
+		for key, item := range v {
+			r, err := f.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[key] = r
+		}
+		return resolved, nil
+	case []any:
+		resolved := make([]any, len(v))
+		for i, item := range v {
+			r, err := f.resolve(item)
+			if err != nil {
+				return nil, err
+			}
+			resolved[i] = r
+		}
+		return resolved, nil
+	}
+	return value, nil
+}
+
+func (f *Fixtures) lookup(ref reference) (any, error) {
+	r, ok := f.records[ref.key]
+	if !ok || r.loaded == nil {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownReference, ref.key)
+	}
+	if row, ok := r.loaded.(map[string]any); ok {
+		name := ref.field
+		if name == "" {
+			name = "ID"
+		}
+		value, ok := row[name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s", ErrUnknownReference, ref.key, name)
+		}
+		return value, nil
+	}
+	s := f.schemas[r.table]
+	field := s.PrioritizedPrimaryField
+	if ref.field != "" {
+		field = s.LookUpField(ref.field)
+	}
+	if field == nil {
+		return nil, fmt.Errorf("%w: %s.%s", ErrUnknownReference, ref.key, ref.field)
+	}
+	value, _ := field.ValueOf(f.db.Statement.Context, reflect.ValueOf(r.loaded).Elem())
+	return value, nil
+}
+
+func contains(arr []string, value string) bool {
+	for _, item := range arr {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+// render executes the string values containing "{{" as templates. The output is decoded as a YAML scalar,
+// so numbers and booleans keep their types.
+func (f *Fixtures) render(r *record, value any) (any, error) {
+	switch v := value.(type) {
+	case string:
+		if !strings.Contains(v, "{{") {
+			return v, nil
+		}
+		tmpl, err := template.New(r.key()).Funcs(f.Funcs()).Parse(v)
+		if err != nil {
+			return nil, err
+		}
+		var out strings.Builder
+		if err := tmpl.Execute(&out, struct{ Table, Name string }{r.table, r.name}); err != nil {
+			return nil, err
+		}
+		var decoded any
+		if err := yaml.Unmarshal([]byte(out.String()), &decoded); err != nil || decoded == nil {
+			return out.String(), nil
+		}
+		switch decoded.(type) {
+		case map[string]any, []any:
+			return out.String(), nil
+		}
+		return decoded, nil
+	case map[string]any:
+		for key, item := range v {
+			rendered, err := f.render(r, item)
+			if err != nil {
+				return nil, err
+			}
+			v[key] = rendered
+		}
+	case []any:
+		for i, item := range v {
+			rendered, err := f.render(r, item)
+			if err != nil {
+				return nil, err
+			}
+			v[i] = rendered
+		}
+	}
+	return value, nil
+}
+
+// Funcs returns the template functions of the fixture values.
+//   - now: current time in RFC3339, optionally shifted by a duration ({{ now "-48h" }})
+//   - env: value of the environment variable ({{ env "ADMIN_MAIL" }})
+//   - seq: a number incremented on every call
+func (f *Fixtures) Funcs() template.FuncMap {
+	return template.FuncMap{
+		"now": func(offset ...string) (string, error) {
+			t := time.Now()
+			if len(offset) > 0 {
+				d, err := time.ParseDuration(offset[0])
+				if err != nil {
+					return "", err
+				}
+				t = t.Add(d)
+			}
+			return t.Format(time.RFC3339Nano), nil
+		},
+		"env": os.Getenv,
+		"seq": func() int {
+			f.seq++
+			return f.seq
+		},
+	}
+}
@@ -0,0 +1,119 @@
+package fixtures
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/filllabs/sincap-common/db/types"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Author struct {
+	ID        uint
+	Name      string
+	CreatedAt time.Time
+	Active    bool
+}
+
+type Book struct {
+	ID       uint
+	AuthorID uint
+	Title    string
+	Author   *Author
+	Meta     types.JSON
+	Price    float64
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	DB := dbtest.Open(t, &Author{}, &Book{})
+	assert.NoError(t, DB.Exec("CREATE TABLE AuthorBook (AuthorID integer, BookID integer)").Error)
+	return DB
+}
+
+func write(t *testing.T, dir string, name string, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoad(t *testing.T) {
+	DB := openDB(t)
+	dir := t.TempDir()
+	write(t, dir, "books.yml", `
+Book:
+  dune:
+    AuthorID: $Author.frank
+    Title: 'Dune by {{ .Name }}'
+    Price: '{{ seq }}'
+    Meta:
+      tags: [scifi]
+AuthorBook:
+  frank_dune:
+    AuthorID: $Author.frank
+    BookID: $Book.dune
+`)
+	write(t, dir, "authors.json", `{
+  Author: {
+    frank: { Name: "Frank", CreatedAt: "{{ now \"-24h\" }}", Active: true },
+    dollar: { Name: "$$money" },
+  },
+}`)
+	write(t, dir, "README.md", "ignored")
+
+	f, err := New(DB, &Author{}, &Book{})
+	assert.NoError(t, err)
+	assert.NoError(t, f.Load(dir))
+
+	var book Book
+	assert.NoError(t, DB.Preload("Author").First(&book).Error)
+	assert.Equal(t, "Dune by dune", book.Title)
+	assert.Equal(t, float64(1), book.Price)
+	assert.Equal(t, "Frank", book.Author.Name)
+	assert.True(t, book.Author.Active)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), book.Author.CreatedAt, time.Minute)
+	assert.JSONEq(t, `{"tags":["scifi"]}`, string(book.Meta))
+
+	loaded, ok := f.Get("Author.dollar")
+	assert.True(t, ok)
+	assert.Equal(t, "$money", loaded.(*Author).Name)
+
+	var count int64
+	assert.NoError(t, DB.Table("AuthorBook").Where("AuthorID = ? AND BookID = ?", book.AuthorID, book.ID).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	// later loads may reference the previous ones
+	assert.NoError(t, f.Load(write(t, t.TempDir(), "more.yaml", "Book:\n  other:\n    AuthorID: $Author.frank.ID\n    Title: Other\n")))
+
+	assert.NoError(t, f.Reset())
+	assert.NoError(t, DB.Model(&Book{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+	assert.NoError(t, DB.Table("AuthorBook").Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+
+	// ids start over after reset
+	assert.NoError(t, f.Load(dir))
+	loaded, _ = f.Get("Book.dune")
+	assert.Equal(t, uint(1), loaded.(*Book).ID)
+}
+
+func TestLoadErrors(t *testing.T) {
+	DB := openDB(t)
+	dir := t.TempDir()
+	f, err := New(DB, &Author{}, &Book{})
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, f.Load(write(t, dir, "unknown.yml", "Book:\n  a:\n    AuthorID: $Author.nobody\n")), ErrUnknownReference)
+	assert.ErrorIs(t, f.Load(write(t, dir, "cycle.yml", "Book:\n  a:\n    Title: $Book.b.Title\n  b:\n    Title: $Book.a.Title\n")), ErrCycle)
+	assert.ErrorIs(t, f.Load(write(t, dir, "field.yml", "Author:\n  a:\n    Age: 3\n")), ErrUnknownField)
+	assert.ErrorIs(t, f.Load(write(t, dir, "fixtures.txt", "")), ErrFormat)
+
+	// failed loads are forgotten
+	assert.NoError(t, f.Load(write(t, dir, "ok.yml", "Author:\n  a:\n    Name: A\n")))
+	assert.ErrorIs(t, f.Load(write(t, dir, "dup.yml", "Author:\n  a:\n    Name: B\n")), ErrDuplicate)
+}
@@ -0,0 +1,58 @@
+package migrate
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/filllabs/sincap-common/logging"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCommand is returned when the migration command can't be parsed
+var ErrInvalidCommand = errors.New("migrate: invalid command, use up, down, status or to {version}")
+
+// Run executes the given migration command (up, down, status or to {version}). Status is logged.
+// It is usually read from flags.
+//
+//	fs, _ := flags.Parse(append(flags.Defaults, flags.Migrate...)...)
+//	fs.Parse(os.Args[1:])
+//	if fs.Lookup("command").Value.String() == "migrate" {
+//		err = migrate.Run(m, fs.Lookup("migrate").Value.String())
+//	}
+func Run(m *Migrator, command string) error {
+	args := strings.Fields(command)
+	if len(args) == 0 {
+		return ErrInvalidCommand
+	}
+	switch {
+	case args[0] == "up" && len(args) == 1:
+		return m.Up()
+	case args[0] == "down" && len(args) == 1:
+		return m.Down()
+	case args[0] == "to" && len(args) == 2:
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return ErrInvalidCommand
+		}
+		return m.To(version)
+	case args[0] == "status" && len(args) == 1:
+		statuses, err := m.Status()
+		if err != nil {
+			return err
+		}
+		logger := logging.Logger.Named("Migrate")
+		for _, s := range statuses {
+			fields := []zap.Field{zap.Int64("version", s.Version), zap.String("name", s.Name), zap.Bool("applied", s.Applied)}
+			if s.AppliedAt != nil {
+				fields = append(fields, zap.Time("appliedAt", *s.AppliedAt))
+			}
+			if s.Modified {
+				fields = append(fields, zap.Bool("modified", true))
+			}
+			logger.Info("Migration", fields...)
+		}
+		return nil
+	}
+	return ErrInvalidCommand
+}
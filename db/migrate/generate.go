@@ -0,0 +1,98 @@
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrNoChanges is returned by Generate when the database is up to date with the models
+var ErrNoChanges = errors.New("migrate: no changes")
+
+var (
+	createTable = regexp.MustCompile("(?i)^CREATE TABLE\\s+(`[^`]+`|\"[^\"]+\"|\\S+)")
+	addColumn   = regexp.MustCompile("(?i)^ALTER TABLE\\s+(`[^`]+`|\"[^\"]+\"|\\S+)\\s+ADD\\s+(?:COLUMN\\s+)?(`[^`]+`|\"[^\"]+\"|\\S+)")
+	createIndex = regexp.MustCompile("(?i)^CREATE\\s+(?:UNIQUE\\s+)?INDEX\\s+(`[^`]+`|\"[^\"]+\"|\\S+)\\s+ON\\s+(`[^`]+`|\"[^\"]+\"|\\S+)")
+)
+
+// recorder is a connection pool which records the executed statements instead of running them. Queries are passed to the pool.
+type recorder struct {
+	gorm.ConnPool
+	dialector  gorm.Dialector
+	statements []string
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	r.statements = append(r.statements, r.dialector.Explain(query, args...))
+	return driver.RowsAffected(0), nil
+}
+
+// Diff returns the statements which gorm AutoMigrate would execute for the given models and their reverts.
+// Statements which can't be reverted automatically are added to down as comments.
+func Diff(DB *gorm.DB, models ...any) (up []string, down []string, err error) {
+	ctx := DB.Statement.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	tx := DB.Session(&gorm.Session{Context: ctx})
+	rec := &recorder{ConnPool: tx.Statement.ConnPool, dialector: tx.Dialector}
+	tx.Statement.ConnPool = rec
+	if err := tx.AutoMigrate(models...); err != nil {
+		return nil, nil, err
+	}
+	up = rec.statements
+	for i := len(up) - 1; i >= 0; i-- {
+		down = append(down, revert(DB.Dialector.Name(), up[i]))
+	}
+	return up, down, nil
+}
+
+// Generate writes a new SQL migration with the differences between the database and the given models to the directory.
+// Version is the current UTC time (yyyyMMddHHmmss). Returns the path of the up file or ErrNoChanges.
+// Generated files should be reviewed before applying, especially the down file.
+func Generate(DB *gorm.DB, dir string, name string, models ...any) (string, error) {
+	up, down, err := Diff(DB, models...)
+	if err != nil {
+		return "", err
+	}
+	if len(up) == 0 {
+		return "", ErrNoChanges
+	}
+	version, _ := strconv.ParseInt(time.Now().UTC().Format("20060102150405"), 10, 64)
+	base := filepath.Join(dir, fmt.Sprintf("%d_%s", version, strings.ReplaceAll(strings.ToLower(strings.TrimSpace(name)), " ", "_")))
+	if err := os.WriteFile(base+".up.sql", []byte(strings.Join(up, ";\n")+";\n"), 0644); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".down.sql", []byte(strings.Join(down, "\n")+"\n"), 0644); err != nil {
+		return "", err
+	}
+	return base + ".up.sql", nil
+}
+
+// revert returns the statement reverting the given one or a comment if it is not possible
+func revert(dialect string, stmt string) string {
+	stmt = strings.TrimSpace(stmt)
+	if m := createTable.FindStringSubmatch(stmt); m != nil {
+		return "DROP TABLE " + m[1] + ";"
+	}
+	if m := addColumn.FindStringSubmatch(stmt); m != nil && !strings.EqualFold(m[2], "CONSTRAINT") {
+		return "ALTER TABLE " + m[1] + " DROP COLUMN " + m[2] + ";"
+	}
+	if m := createIndex.FindStringSubmatch(stmt); m != nil {
+		if dialect == "mysql" {
+			return "DROP INDEX " + m[1] + " ON " + m[2] + ";"
+		}
+		return "DROP INDEX " + m[1] + ";"
+	}
+	return "-- TODO revert: " + strings.ReplaceAll(stmt, "\n", " ")
+}
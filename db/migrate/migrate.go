@@ -0,0 +1,340 @@
+// Package migrate runs versioned migrations and keeps the applied versions at the SchemaMigration table.
+// Migrations are either SQL files ({version}_{name}.up.sql and {version}_{name}.down.sql) or Go functions.
+//
+//	migrations, err := migrate.Load(os.DirFS("migrations"), ".")
+//	migrations = append(migrations, migrate.Go(20240102150405, "seed_roles", seedRoles, dropRoles))
+//	m, err := migrate.New(db.DB(), migrations...)
+//	err = m.Up()
+//
+// Checksums of the applied SQL migrations are verified before every run, so an applied migration file must never be edited.
+// On MySQL runs are serialized between instances with GET_LOCK.
+package migrate
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/filllabs/sincap-common/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+const lockName = "sincap_migrate"
+
+// lockTimeout is the time to wait for the lock of an other instance in seconds
+const lockTimeout = 60
+
+var (
+	// ErrDuplicateVersion is returned when two migrations have the same version
+	ErrDuplicateVersion = errors.New("migrate: duplicate version")
+	// ErrChecksumMismatch is returned when an applied migration is modified
+	ErrChecksumMismatch = errors.New("migrate: checksum mismatch")
+	// ErrUnknownVersion is returned when the target version is not defined
+	ErrUnknownVersion = errors.New("migrate: unknown version")
+	// ErrMissingDown is returned when a migration without down is reverted
+	ErrMissingDown = errors.New("migrate: missing down migration")
+	// ErrMissingUp is returned when a migration file has only the down part
+	ErrMissingUp = errors.New("migrate: missing up migration")
+	// ErrLocked is returned when the lock can't be acquired in time
+	ErrLocked = errors.New("migrate: locked by another instance")
+)
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// SchemaMigration holds an applied migration
+type SchemaMigration struct {
+	Version   int64  `gorm:"primaryKey;autoIncrement:false"`
+	Name      string `gorm:"size:255"`
+	Checksum  string `gorm:"size:64"`
+	AppliedAt time.Time
+}
+
+// Migration is a single versioned migration. Use SQL or Go for creating.
+type Migration struct {
+	Version int64
+	Name    string
+	// Checksum is the hash of the SQL, empty for Go migrations which are not verified
+	Checksum string
+	Up       func(tx *gorm.DB) error
+	Down     func(tx *gorm.DB) error
+}
+
+// Status holds the state of a migration
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+	// Modified is true if the applied migration is changed after it is applied
+	Modified bool
+}
+
+// SQL returns a migration which executes the given SQL statements. Down may be empty if the migration is irreversible.
+func SQL(version int64, name string, up string, down string) Migration {
+	sum := sha256.Sum256([]byte(up + "\n--down--\n" + down))
+	m := Migration{Version: version, Name: name, Checksum: hex.EncodeToString(sum[:]), Up: execAll(up)}
+	if down != "" {
+		m.Down = execAll(down)
+	}
+	return m
+}
+
+// Go returns a migration which calls the given functions. Down may be nil if the migration is irreversible.
+func Go(version int64, name string, up func(tx *gorm.DB) error, down func(tx *gorm.DB) error) Migration {
+	return Migration{Version: version, Name: name, Up: up, Down: down}
+}
+
+// Load reads the SQL migrations at the given directory of the file system. Files which do not match
+// {version}_{name}.up.sql or {version}_{name}.down.sql are ignored.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+	type parts struct {
+		name     string
+		up, down string
+		hasUp    bool
+	}
+	files := map[int64]*parts{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		data, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		p, ok := files[version]
+		if !ok {
+			p = &parts{name: match[2]}
+			files[version] = p
+		} else if p.name != match[2] {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, version)
+		}
+		if match[3] == "up" {
+			p.up, p.hasUp = string(data), true
+		} else {
+			p.down = string(data)
+		}
+	}
+	migrations := make([]Migration, 0, len(files))
+	for version, p := range files {
+		if !p.hasUp {
+			return nil, fmt.Errorf("%w: %d_%s", ErrMissingUp, version, p.name)
+		}
+		migrations = append(migrations, SQL(version, p.name, p.up, p.down))
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func execAll(script string) func(tx *gorm.DB) error {
+	return func(tx *gorm.DB) error {
+		for _, stmt := range splitStatements(script) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// Migrator applies and reverts the migrations
+type Migrator struct {
+	db         *gorm.DB
+	migrations []Migration
+}
+
+// New returns a migrator for the given migrations. Migrations are sorted by version.
+func New(DB *gorm.DB, migrations ...Migration) (*Migrator, error) {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].Version == sorted[i-1].Version {
+			return nil, fmt.Errorf("%w: %d", ErrDuplicateVersion, sorted[i].Version)
+		}
+	}
+	return &Migrator{db: DB, migrations: sorted}, nil
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up() error {
+	if len(m.migrations) == 0 {
+		return nil
+	}
+	return m.To(m.migrations[len(m.migrations)-1].Version)
+}
+
+// Down reverts the last applied migration
+func (m *Migrator) Down() error {
+	return m.withLock(func(conn *gorm.DB, applied map[int64]SchemaMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			if _, ok := applied[m.migrations[i].Version]; ok {
+				return m.revert(conn, m.migrations[i])
+			}
+		}
+		return nil
+	})
+}
+
+// To applies or reverts the migrations until the given version is the last applied one. 0 reverts all migrations.
+func (m *Migrator) To(version int64) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("%w: %d", ErrUnknownVersion, version)
+	}
+	return m.withLock(func(conn *gorm.DB, applied map[int64]SchemaMigration) error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			mig := m.migrations[i]
+			if _, ok := applied[mig.Version]; ok && mig.Version > version {
+				if err := m.revert(conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= version {
+				if err := m.apply(conn, mig); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the state of all migrations sorted by version
+func (m *Migrator) Status() ([]Status, error) {
+	var statuses []Status
+	err := m.db.Connection(func(conn *gorm.DB) error {
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			s := Status{Version: mig.Version, Name: mig.Name}
+			if a, ok := applied[mig.Version]; ok {
+				appliedAt := a.AppliedAt
+				s.Applied = true
+				s.AppliedAt = &appliedAt
+				s.Modified = mig.Checksum != "" && a.Checksum != mig.Checksum
+			}
+			statuses = append(statuses, s)
+		}
+		return nil
+	})
+	return statuses, err
+}
+
+func (m *Migrator) find(version int64) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// withLock runs the given function with a single connection holding the lock after verifying the applied migrations
+func (m *Migrator) withLock(fn func(conn *gorm.DB, applied map[int64]SchemaMigration) error) error {
+	return m.db.Connection(func(conn *gorm.DB) error {
+		if err := lock(conn); err != nil {
+			return err
+		}
+		defer unlock(conn)
+		applied, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, mig := range m.migrations {
+			if a, ok := applied[mig.Version]; ok && mig.Checksum != "" && a.Checksum != mig.Checksum {
+				return fmt.Errorf("%w: %d_%s", ErrChecksumMismatch, mig.Version, mig.Name)
+			}
+		}
+		return fn(conn, applied)
+	})
+}
+
+func (m *Migrator) apply(conn *gorm.DB, mig Migration) error {
+	logging.Logger.Named("Migrate").Info("Applying", zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := mig.Up(tx); err != nil {
+			return err
+		}
+		return tx.Create(&SchemaMigration{Version: mig.Version, Name: mig.Name, Checksum: mig.Checksum, AppliedAt: time.Now()}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: %d_%s up: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+func (m *Migrator) revert(conn *gorm.DB, mig Migration) error {
+	if mig.Down == nil {
+		return fmt.Errorf("%w: %d_%s", ErrMissingDown, mig.Version, mig.Name)
+	}
+	logging.Logger.Named("Migrate").Info("Reverting", zap.Int64("version", mig.Version), zap.String("name", mig.Name))
+	err := conn.Transaction(func(tx *gorm.DB) error {
+		if err := mig.Down(tx); err != nil {
+			return err
+		}
+		return tx.Where("Version = ?", mig.Version).Delete(&SchemaMigration{}).Error
+	})
+	if err != nil {
+		return fmt.Errorf("migrate: %d_%s down: %w", mig.Version, mig.Name, err)
+	}
+	return nil
+}
+
+func appliedVersions(conn *gorm.DB) (map[int64]SchemaMigration, error) {
+	if err := conn.AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	var rows []SchemaMigration
+	if err := conn.Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]SchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// lock acquires the named lock of MySQL for the connection. Other dialects are not locked.
+func lock(conn *gorm.DB) error {
+	if conn.Dialector.Name() != "mysql" {
+		return nil
+	}
+	var acquired sql.NullInt64
+	if err := conn.Raw("SELECT GET_LOCK(?, ?)", lockName, lockTimeout).Row().Scan(&acquired); err != nil {
+		return err
+	}
+	if !acquired.Valid || acquired.Int64 != 1 {
+		return ErrLocked
+	}
+	return nil
+}
+
+func unlock(conn *gorm.DB) {
+	if conn.Dialector.Name() != "mysql" {
+		return
+	}
+	if err := conn.Exec("SELECT RELEASE_LOCK(?)", lockName).Error; err != nil {
+		logging.Logger.Named("Migrate").Error("Can't release lock", zap.Error(err))
+	}
+}
@@ -0,0 +1,110 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+var files = fstest.MapFS{
+	"migrations/1_create_user.up.sql":   {Data: []byte("CREATE TABLE User (ID integer primary key, Name text); -- users\nINSERT INTO User (Name) VALUES ('a;b');")},
+	"migrations/1_create_user.down.sql": {Data: []byte("DROP TABLE User;")},
+	"migrations/2_add_email.up.sql":     {Data: []byte("ALTER TABLE User ADD COLUMN Email text;")},
+	"migrations/2_add_email.down.sql":   {Data: []byte("ALTER TABLE User DROP COLUMN Email;")},
+	"migrations/README.md":              {Data: []byte("ignored")},
+}
+
+func TestMigrator(t *testing.T) {
+	DB := dbtest.Open(t)
+	migrations, err := Load(files, "migrations")
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+
+	seeded := false
+	migrations = append(migrations, Go(3, "seed", func(tx *gorm.DB) error {
+		seeded = true
+		return tx.Exec("INSERT INTO User (Name, Email) VALUES ('c', 'c@d.com')").Error
+	}, nil))
+	m, err := New(DB, migrations...)
+	assert.NoError(t, err)
+
+	assert.NoError(t, Run(m, "to 2"))
+	statuses, err := m.Status()
+	assert.NoError(t, err)
+	assert.True(t, statuses[0].Applied)
+	assert.True(t, statuses[1].Applied)
+	assert.False(t, statuses[2].Applied)
+	assert.False(t, seeded)
+
+	assert.NoError(t, Run(m, "up"))
+	assert.True(t, seeded)
+	var count int64
+	assert.NoError(t, DB.Table("User").Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+
+	// go migration without down can't be reverted
+	assert.ErrorIs(t, Run(m, "down"), ErrMissingDown)
+	assert.NoError(t, Run(m, "status"))
+	assert.ErrorIs(t, Run(m, "sideways"), ErrInvalidCommand)
+	assert.ErrorIs(t, m.To(42), ErrUnknownVersion)
+
+	// modified migrations are rejected
+	modified, err := New(DB, SQL(1, "create_user", "CREATE TABLE User (ID integer primary key);", ""))
+	assert.NoError(t, err)
+	assert.ErrorIs(t, modified.Up(), ErrChecksumMismatch)
+
+	m, err = New(DB, migrations[:2]...)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Down())
+	assert.True(t, DB.Migrator().HasTable("User"))
+	assert.False(t, DB.Migrator().HasColumn("User", "Email"))
+	assert.NoError(t, m.To(0))
+	assert.False(t, DB.Migrator().HasTable("User"))
+}
+
+func TestNewDuplicate(t *testing.T) {
+	_, err := New(nil, SQL(1, "a", "", ""), SQL(1, "b", "", ""))
+	assert.ErrorIs(t, err, ErrDuplicateVersion)
+}
+
+func TestSplitStatements(t *testing.T) {
+	stmts := splitStatements("-- header\nCREATE TABLE a (b text DEFAULT ';');\n/* block; */ INSERT INTO a VALUES ('it\\'s;');\n# comment;\n;")
+	assert.Equal(t, []string{"CREATE TABLE a (b text DEFAULT ';')", "INSERT INTO a VALUES ('it\\'s;')"}, stmts)
+}
+
+type Product struct {
+	ID    uint
+	Name  string `gorm:"index"`
+	Price float64
+}
+
+func TestGenerate(t *testing.T) {
+	DB := dbtest.Open(t)
+	dir := t.TempDir()
+	path, err := Generate(DB, dir, "Create Product", &Product{})
+	assert.NoError(t, err)
+	assert.Contains(t, filepath.Base(path), "_create_product.up.sql")
+	assert.False(t, DB.Migrator().HasTable("Product"))
+
+	down, err := os.ReadFile(filepath.Join(dir, filepath.Base(path[:len(path)-len(".up.sql")])+".down.sql"))
+	assert.NoError(t, err)
+	assert.Contains(t, string(down), "DROP TABLE `Product`;")
+	assert.Contains(t, string(down), "DROP INDEX `idx_Product_Name`;")
+
+	migrations, err := Load(os.DirFS(dir), ".")
+	assert.NoError(t, err)
+	m, err := New(DB, migrations...)
+	assert.NoError(t, err)
+	assert.NoError(t, m.Up())
+	assert.True(t, DB.Migrator().HasIndex(&Product{}, "Name"))
+
+	_, err = Generate(DB, dir, "nothing", &Product{})
+	assert.ErrorIs(t, err, ErrNoChanges)
+	assert.NoError(t, m.Down())
+	assert.False(t, DB.Migrator().HasTable("Product"))
+}
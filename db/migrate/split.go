@@ -0,0 +1,59 @@
+package migrate
+
+import "strings"
+
+// splitStatements splits the given script by semicolons which are not in quotes or comments.
+// Comment only and empty statements are dropped.
+func splitStatements(script string) []string {
+	var (
+		statements []string
+		current    strings.Builder
+		quote      byte
+		hasCode    bool
+	)
+	flush := func() {
+		if stmt := strings.TrimSpace(current.String()); stmt != "" && hasCode {
+			statements = append(statements, stmt)
+		}
+		current.Reset()
+		hasCode = false
+	}
+	for i := 0; i < len(script); i++ {
+		c := script[i]
+		switch {
+		case quote != 0:
+			current.WriteByte(c)
+			if c == '\\' && quote != '`' && i+1 < len(script) {
+				i++
+				current.WriteByte(script[i])
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			hasCode = true
+			current.WriteByte(c)
+		case c == '#' || (c == '-' && strings.HasPrefix(script[i:], "-- ")) || strings.HasPrefix(script[i:], "--\n"):
+			end := strings.IndexByte(script[i:], '\n')
+			if end < 0 {
+				end = len(script) - i
+			}
+			i += end - 1
+		case c == '/' && strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				end = len(script) - i - 2
+			}
+			i += end + 3
+		case c == ';':
+			flush()
+		default:
+			if c != ' ' && c != '\t' && c != '\n' && c != '\r' {
+				hasCode = true
+			}
+			current.WriteByte(c)
+		}
+	}
+	flush()
+	return statements
+}
@@ -14,10 +14,20 @@ import (
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // List calls ListByQuery or ListAll according to the query parameter
 func List(DB *gorm.DB, records any, query *qapi.Query) (int, error) {
+	var count int
+	err := retry(DB, func() (err error) {
+		count, err = list(DB, records, query)
+		return err
+	})
+	return count, err
+}
+
+func list(DB *gorm.DB, records any, query *qapi.Query) (int, error) {
 	value := reflect.ValueOf(records)
 	if value.Kind() != reflect.Pointer {
 		return 0, fmt.Errorf("records must be a pointer")
@@ -76,11 +86,13 @@ func List(DB *gorm.DB, records any, query *qapi.Query) (int, error) {
 
 // Create Record
 func Create(DB *gorm.DB, record any) error {
-	result := DB.Model(record).Create(record)
-	if result.Error != nil {
-		logging.Logger.Error("Create error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(result.Error), zap.Any("record", record))
+	err := retry(DB, func() error {
+		return DB.Model(record).Create(record).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Create error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(err), zap.Any("record", record))
 	}
-	return result.Error
+	return err
 }
 
 // Read Record
@@ -89,17 +101,19 @@ func Read(DB *gorm.DB, record any, id any, preloads ...string) error {
 		DB = addPreloads(reflection.DepointerField(reflect.TypeOf(record)), DB, preloads)
 	}
 
-	// if id is string so add ID=? to query in order to support (gorm wants qull cond if id is string, if number it works by default)
-	if _, ok := id.(string); ok {
-		_, tableName := queryapi.GetTableName(record)
-		id = fmt.Sprintf("`%s`.`ID`='%s'", tableName, id)
+	// conditions are built from the primary fields, so string and composite keys are parameterised too
+	key, err := PrimaryKey(DB, record, id)
+	if err != nil {
+		logging.Logger.Error("Read error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(err), zap.Any("id", id))
+		return err
 	}
-
-	result := DB.First(record, id)
-	if result.Error != nil {
-		logging.Logger.Error("Read error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(result.Error), zap.Any("id", id))
+	err = retry(DB, func() error {
+		return DB.Clauses(clause.Where{Exprs: []clause.Expression{key}}).First(record).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Read error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(err), zap.Any("id", id))
 	}
-	return result.Error
+	return err
 }
 
 // Update Updates the record with the given fields
@@ -115,11 +129,13 @@ func Read(DB *gorm.DB, record any, id any, preloads ...string) error {
 func Update(DB *gorm.DB, model any, fieldsParams ...map[string]any) error {
 	if len(fieldsParams) == 0 {
 		// update full record
-		result := DB.Save(model)
-		if result.Error != nil {
-			logging.Logger.Error("Update error", zap.Any("Model", reflect.TypeOf(model)), zap.Error(result.Error), zap.Any("record", model))
+		err := retry(DB, func() error {
+			return DB.Save(model).Error
+		})
+		if err != nil {
+			logging.Logger.Error("Update error", zap.Any("Model", reflect.TypeOf(model)), zap.Error(err), zap.Any("record", model))
 		}
-		return result.Error
+		return err
 	}
 	// error if fields more than 1 or first element is not a map
 	if len(fieldsParams) > 1 || fieldsParams[0] == nil {
@@ -136,29 +152,43 @@ func Update(DB *gorm.DB, model any, fieldsParams ...map[string]any) error {
 			fields[k] = j
 		}
 	}
-	result := DB.Model(model).Updates(fields)
-	if result.Error != nil {
-		logging.Logger.Error("Update error", zap.Any("Model", reflect.TypeOf(model)), zap.Error(result.Error), zap.Any("record", fields))
+	err := retry(DB, func() error {
+		return DB.Model(model).Updates(fields).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Update error", zap.Any("Model", reflect.TypeOf(model)), zap.Error(err), zap.Any("record", fields))
 	}
-	return result.Error
+	return err
 }
 
 // Delete Record
 func Delete(DB *gorm.DB, record any) error {
-	result := DB.Delete(record)
-	if result.Error != nil {
-		logging.Logger.Error("Delete error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(result.Error), zap.Any("record", record))
+	err := retry(DB, func() error {
+		return DB.Delete(record).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Delete error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(err), zap.Any("record", record))
 	}
-	return result.Error
+	return err
 }
 
-// DeleteAll Record
+// DeleteAll deletes the records with the given ids (see PrimaryKeys), the record itself if there are no ids
 func DeleteAll(DB *gorm.DB, record any, ids ...any) error {
-	result := DB.Delete(record, ids...)
-	if result.Error != nil {
-		logging.Logger.Error("Delete error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(result.Error), zap.Any("record", record))
+	if len(ids) == 0 {
+		return Delete(DB, record)
+	}
+	keys, err := PrimaryKeys(DB, record, ids...)
+	if err != nil {
+		logging.Logger.Error("Delete error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(err), zap.Any("ids", ids))
+		return err
+	}
+	err = retry(DB, func() error {
+		return DB.Clauses(clause.Where{Exprs: []clause.Expression{keys}}).Delete(record).Error
+	})
+	if err != nil {
+		logging.Logger.Error("Delete error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(err), zap.Any("record", record))
 	}
-	return result.Error
+	return err
 }
 
 // Associations opens "save_associations" for the given DB
@@ -0,0 +1,200 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+
+	driver "github.com/go-sql-driver/mysql"
+	"gorm.io/gorm"
+)
+
+// MySQL server error numbers which are classified, Error.Code holds them
+const (
+	CodeDuplicateEntry      = 1062
+	CodeDuplicateKeyName    = 1586
+	CodeRowIsReferenced     = 1451
+	CodeNoReferencedRow     = 1452
+	CodeRowIsReferencedOld  = 1217
+	CodeNoReferencedRowOld  = 1216
+	CodeLockWaitTimeout     = 1205
+	CodeDeadlock            = 1213
+	CodeQueryTimeout        = 3024
+	CodeQueryInterruptedOld = 1317
+	CodeCheckViolated       = 3819
+	CodeCheckViolatedMaria  = 4025
+)
+
+var (
+	// ErrDuplicateKey is the class of unique or primary key violations
+	ErrDuplicateKey = errors.New("mysql: duplicate key")
+	// ErrForeignKey is the class of foreign key violations
+	ErrForeignKey = errors.New("mysql: foreign key violation")
+	// ErrDeadlock is the class of deadlocks (1213). It is retryable.
+	ErrDeadlock = errors.New("mysql: deadlock")
+	// ErrLockTimeout is the class of lock wait timeouts (1205). It is retryable.
+	ErrLockTimeout = errors.New("mysql: lock wait timeout")
+	// ErrCheck is the class of check constraint violations
+	ErrCheck = errors.New("mysql: check constraint violation")
+	// ErrTimeout is the class of statement timeouts and cancelled contexts
+	ErrTimeout = errors.New("mysql: timeout")
+	// ErrNotFound is the class of gorm.ErrRecordNotFound
+	ErrNotFound = errors.New("mysql: record not found")
+)
+
+var (
+	// Duplicate entry 'a@b.com' for key 'User.idx_User_Email' (the table is not given before MySQL 8)
+	duplicateKey = regexp.MustCompile(`for key '(?:([^'.]+)\.)?([^']+)'`)
+	// ... FOREIGN KEY (`UserID`) REFERENCES ...
+	foreignKey = regexp.MustCompile("FOREIGN KEY \\(`([^`]+)`\\)")
+	// Check constraint 'chk_Product_Price' is violated.
+	checkConstraint = regexp.MustCompile(`[Cc]heck constraint '([^']+)'`)
+	// UNIQUE constraint failed: User.Email (sqlite)
+	sqliteConstraint = regexp.MustCompile(`constraint failed: (?:([^.,\s]+)\.)?([^,\s]+)`)
+)
+
+// namePrefixes are the prefixes of the gorm index and constraint names, followed by the table (idx_Order_Item_SKU)
+var namePrefixes = []string{"idx_", "uni_", "chk_", "fk_"}
+
+// Error is a classified database error. errors.Is matches both its class (ErrDeadlock ...) and the original error.
+type Error struct {
+	// Class is one of ErrDuplicateKey, ErrForeignKey, ErrCheck, ErrDeadlock, ErrLockTimeout, ErrTimeout or ErrNotFound
+	Class error
+	// Code is the MySQL error number if any
+	Code uint16
+	// Column is the violating column of key and check violations. It is the constraint name (e.g. chk_Product_Price)
+	// if the table of the constraint is unknown, see ClassifyTable.
+	Column string
+	Err    error
+}
+
+// Error returns the message of the original error
+func (e *Error) Error() string {
+	return e.Class.Error() + ": " + e.Err.Error()
+}
+
+// Unwrap returns the original error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether the target is the class of the error
+func (e *Error) Is(target error) bool {
+	return target == e.Class
+}
+
+// Classify wraps the given error with its class. Unknown errors, nil and already classified errors are returned as is.
+// Errors of the other dialects (sqlite) are classified by their messages.
+func Classify(err error) error {
+	return ClassifyTable(err, "")
+}
+
+// ClassifyTable classifies the error of a statement on the given table like Classify. Gorm names the indexes and
+// constraints with the table (uni_Order_Item_SKU), the table resolves their columns if the message does not have it.
+func ClassifyTable(err error, table string) error {
+	if err == nil {
+		return nil
+	}
+	var classified *Error
+	if errors.As(err, &classified) {
+		return err
+	}
+	class, code := classOf(err)
+	if class == nil {
+		return err
+	}
+	return &Error{Class: class, Code: code, Column: columnOf(class, err.Error(), table), Err: err}
+}
+
+// IsRetryable reports whether the error is a deadlock or a lock wait timeout
+func IsRetryable(err error) bool {
+	return errors.Is(err, ErrDeadlock) || errors.Is(err, ErrLockTimeout)
+}
+
+func classOf(err error) (error, uint16) {
+	var mysqlErr *driver.MySQLError
+	if errors.As(err, &mysqlErr) {
+		switch mysqlErr.Number {
+		case CodeDuplicateEntry, CodeDuplicateKeyName:
+			return ErrDuplicateKey, mysqlErr.Number
+		case CodeRowIsReferenced, CodeNoReferencedRow, CodeRowIsReferencedOld, CodeNoReferencedRowOld:
+			return ErrForeignKey, mysqlErr.Number
+		case CodeDeadlock:
+			return ErrDeadlock, mysqlErr.Number
+		case CodeLockWaitTimeout:
+			return ErrLockTimeout, mysqlErr.Number
+		case CodeQueryTimeout, CodeQueryInterruptedOld:
+			return ErrTimeout, mysqlErr.Number
+		case CodeCheckViolated, CodeCheckViolatedMaria:
+			return ErrCheck, mysqlErr.Number
+		}
+		return nil, mysqlErr.Number
+	}
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound, 0
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return ErrDuplicateKey, 0
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return ErrForeignKey, 0
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(err, context.Canceled):
+		return ErrTimeout, 0
+	}
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "UNIQUE constraint failed"):
+		return ErrDuplicateKey, 0
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return ErrForeignKey, 0
+	case strings.Contains(msg, "CHECK constraint failed"):
+		return ErrCheck, 0
+	case strings.Contains(msg, "database is locked"), strings.Contains(msg, "database table is locked"):
+		return ErrLockTimeout, 0
+	}
+	return nil, 0
+}
+
+// columnOf parses the violating column from the error message of key and check violations
+func columnOf(class error, msg string, table string) string {
+	var name string
+	switch class {
+	case ErrDuplicateKey:
+		if match := duplicateKey.FindStringSubmatch(msg); match != nil {
+			if match[1] != "" {
+				table = match[1]
+			}
+			name = match[2]
+		}
+	case ErrForeignKey:
+		if match := foreignKey.FindStringSubmatch(msg); match != nil {
+			return match[1]
+		}
+	case ErrCheck:
+		if match := checkConstraint.FindStringSubmatch(msg); match != nil {
+			name = match[1]
+		}
+	default:
+		return ""
+	}
+	if name == "" {
+		if match := sqliteConstraint.FindStringSubmatch(msg); match != nil {
+			return match[2]
+		}
+		return ""
+	}
+	return trimNamePrefix(name, table)
+}
+
+// trimNamePrefix returns the column of the gorm index or constraint name of the table, the name if it is not one of them
+func trimNamePrefix(name string, table string) string {
+	if table == "" {
+		return name
+	}
+	for _, prefix := range namePrefixes {
+		if prefix += table + "_"; strings.HasPrefix(name, prefix) && len(name) > len(prefix) {
+			return name[len(prefix):]
+		}
+	}
+	return name
+}
@@ -0,0 +1,209 @@
+package mysql
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// ErrInvalidID is returned when the id does not fit the primary keys of the model
+var ErrInvalidID = errors.New("mysql: invalid id")
+
+// PrimaryKey returns the parameterised condition matching the primary keys of the model with the id.
+// The id of a single primary key is its value. The id of a composite primary key is the values joined with ","
+// in the order of the primary fields (same as events.Event.ID), a slice of the values in the same order
+// or a map of the values by the field names.
+func PrimaryKey(DB *gorm.DB, model any, id any) (clause.Expression, error) {
+	fields, err := primaryFields(DB, model)
+	if err != nil {
+		return nil, err
+	}
+	return keyCondition(fields, id)
+}
+
+// PrimaryKeys returns the parameterised condition matching any of the ids (see PrimaryKey).
+// Slices are flattened for the single primary keys.
+func PrimaryKeys(DB *gorm.DB, model any, ids ...any) (clause.Expression, error) {
+	fields, err := primaryFields(DB, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(fields) == 1 {
+		var values []any
+		for _, id := range ids {
+			for _, v := range flatten(id) {
+				value, err := convertKey(fields[0], v)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+		}
+		if len(values) == 0 {
+			return nil, ErrInvalidID
+		}
+		return clause.IN{Column: keyColumn(fields[0]), Values: values}, nil
+	}
+	exprs := make([]clause.Expression, 0, len(ids))
+	for _, id := range ids {
+		expr, err := keyCondition(fields, id)
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, expr)
+	}
+	if len(exprs) == 0 {
+		return nil, ErrInvalidID
+	}
+	return clause.Or(exprs...), nil
+}
+
+// ParseID converts the strings (e.g. path params) to the types of the primary fields of the model.
+// params are either one string per primary field or a single string joined with ",".
+// Returns a value for the single primary keys and a slice of values for the composite ones.
+func ParseID(DB *gorm.DB, model any, params ...string) (any, error) {
+	fields, err := primaryFields(DB, model)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 1 && len(fields) > 1 {
+		params = strings.Split(params[0], ",")
+	}
+	if len(params) != len(fields) {
+		return nil, ErrInvalidID
+	}
+	values := make([]any, len(fields))
+	for i, field := range fields {
+		if values[i], err = convertKey(field, params[i]); err != nil {
+			return nil, err
+		}
+	}
+	if len(values) == 1 {
+		return values[0], nil
+	}
+	return values, nil
+}
+
+func primaryFields(DB *gorm.DB, model any) ([]*schema.Field, error) {
+	stmt := &gorm.Statement{DB: DB}
+	if err := stmt.Parse(model); err != nil {
+		return nil, err
+	}
+	if len(stmt.Schema.PrimaryFields) == 0 {
+		return nil, fmt.Errorf("%s has no primary key", stmt.Schema.Name)
+	}
+	return stmt.Schema.PrimaryFields, nil
+}
+
+func keyCondition(fields []*schema.Field, id any) (clause.Expression, error) {
+	values, err := keyValues(fields, id)
+	if err != nil {
+		return nil, err
+	}
+	exprs := make([]clause.Expression, len(fields))
+	for i, field := range fields {
+		value, err := convertKey(field, values[i])
+		if err != nil {
+			return nil, err
+		}
+		exprs[i] = clause.Eq{Column: keyColumn(field), Value: value}
+	}
+	return clause.And(exprs...), nil
+}
+
+// keyValues splits the id to the values of the primary fields
+func keyValues(fields []*schema.Field, id any) ([]any, error) {
+	if len(fields) == 1 {
+		return []any{id}, nil
+	}
+	switch v := id.(type) {
+	case string:
+		parts := strings.Split(v, ",")
+		values := make([]any, len(parts))
+		for i, p := range parts {
+			values[i] = p
+		}
+		id = values
+	case map[string]any:
+		values := make([]any, len(fields))
+		for i, field := range fields {
+			value, ok := v[field.Name]
+			if !ok {
+				if value, ok = v[field.DBName]; !ok {
+					return nil, ErrInvalidID
+				}
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	values := flatten(id)
+	if len(values) != len(fields) {
+		return nil, ErrInvalidID
+	}
+	return values, nil
+}
+
+// convertKey converts the strings to the type of the field, other values are used as is
+func convertKey(field *schema.Field, value any) (any, error) {
+	s, ok := value.(string)
+	if !ok {
+		if value == nil {
+			return nil, ErrInvalidID
+		}
+		return value, nil
+	}
+	if s == "" {
+		return nil, ErrInvalidID
+	}
+	typ := field.FieldType
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	// e.g. uuid.UUID
+	if u, ok := reflect.New(typ).Interface().(encoding.TextUnmarshaler); ok {
+		if err := u.UnmarshalText([]byte(s)); err != nil {
+			return nil, ErrInvalidID
+		}
+		return reflect.ValueOf(u).Elem().Interface(), nil
+	}
+	switch typ.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidID
+		}
+		return i, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		i, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidID
+		}
+		return i, nil
+	}
+	return s, nil
+}
+
+func keyColumn(field *schema.Field) clause.Column {
+	return clause.Column{Table: clause.CurrentTable, Name: field.DBName}
+}
+
+// flatten returns the elements of the slices except []byte, the value itself otherwise
+func flatten(value any) []any {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{value}
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
@@ -0,0 +1,86 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Token struct {
+	ID   string `gorm:"primaryKey;size:36"`
+	Name string
+}
+
+type Price struct {
+	TenantID uint   `gorm:"primaryKey"`
+	Code     string `gorm:"primaryKey;size:3"`
+	Rate     float64
+}
+
+func openKeysDB(t *testing.T) *gorm.DB {
+	DB := dbtest.Open(t, &Token{}, &Price{})
+	assert.NoError(t, DB.Create(&[]Token{{ID: "0190b5a4-7c1e-7d2a-9f3b-2c4d5e6f7a8b", Name: "a"}, {ID: "b", Name: "b"}}).Error)
+	assert.NoError(t, DB.Create(&[]Price{{TenantID: 1, Code: "USD", Rate: 1}, {TenantID: 1, Code: "EUR", Rate: 2}, {TenantID: 2, Code: "USD", Rate: 3}}).Error)
+	return DB
+}
+
+func TestReadKeys(t *testing.T) {
+	DB := openKeysDB(t)
+
+	var token Token
+	assert.NoError(t, Read(DB, &token, "0190b5a4-7c1e-7d2a-9f3b-2c4d5e6f7a8b"))
+	assert.Equal(t, "a", token.Name)
+	// conditions are parameterised
+	assert.ErrorIs(t, Read(DB, &Token{}, "x' OR '1'='1"), gorm.ErrRecordNotFound)
+
+	var price Price
+	assert.NoError(t, Read(DB, &price, "2,USD"))
+	assert.Equal(t, 3.0, price.Rate)
+	price = Price{}
+	assert.NoError(t, Read(DB, &price, []any{1, "EUR"}))
+	assert.Equal(t, 2.0, price.Rate)
+	price = Price{}
+	assert.NoError(t, Read(DB, &price, map[string]any{"TenantID": 1, "Code": "USD"}))
+	assert.Equal(t, 1.0, price.Rate)
+	assert.ErrorIs(t, Read(DB, &Price{}, "1"), ErrInvalidID)
+}
+
+func TestDeleteAllKeys(t *testing.T) {
+	DB := openKeysDB(t)
+
+	assert.NoError(t, DeleteAll(DB, &Token{}, []string{"b"}))
+	assert.NoError(t, DeleteAll(DB, &Price{}, "1,USD", []any{2, "USD"}))
+	var tokens int64
+	assert.NoError(t, DB.Model(&Token{}).Count(&tokens).Error)
+	assert.Equal(t, int64(1), tokens)
+	var prices []Price
+	assert.NoError(t, DB.Find(&prices).Error)
+	assert.Equal(t, []Price{{TenantID: 1, Code: "EUR", Rate: 2}}, prices)
+}
+
+func TestParseID(t *testing.T) {
+	DB := openKeysDB(t)
+
+	id, err := ParseID(DB, &StreamSample{}, "12")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(12), id)
+	_, err = ParseID(DB, &StreamSample{}, "abc")
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	id, err = ParseID(DB, &Token{}, "b")
+	assert.NoError(t, err)
+	assert.Equal(t, "b", id)
+	_, err = ParseID(DB, &Token{}, "")
+	assert.ErrorIs(t, err, ErrInvalidID)
+
+	id, err = ParseID(DB, &Price{}, "1", "USD")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{uint64(1), "USD"}, id)
+	id, err = ParseID(DB, &Price{}, "1,USD")
+	assert.NoError(t, err)
+	assert.Equal(t, []any{uint64(1), "USD"}, id)
+	_, err = ParseID(DB, &Price{}, "x,USD")
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
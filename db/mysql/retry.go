@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/filllabs/sincap-common/backoff"
+	"github.com/filllabs/sincap-common/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type retryKey string
+
+const retryCtxKey retryKey = "retryPolicy"
+
+// RetryPolicy retries the retryable errors (deadlocks and lock wait timeouts by default) with jittered exponential backoff.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts including the first one
+	MaxAttempts int
+	// BaseDelay is the upper bound of the first wait, doubled on every retry
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound of all waits, 0 is unlimited
+	MaxDelay time.Duration
+	// Retryable decides which errors are retried. Default is IsRetryable.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy tries 3 times, waiting a random time up to 50ms and 100ms before the retries
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+var (
+	retryPolicy *RetryPolicy
+	retryMu     sync.RWMutex
+)
+
+// SetRetryPolicy enables retries for all CRUD calls and transactions of the package. nil disables retries (default).
+func SetRetryPolicy(policy *RetryPolicy) {
+	retryMu.Lock()
+	defer retryMu.Unlock()
+	retryPolicy = policy
+}
+
+// WithRetryPolicy returns a copy of the context which overrides the package retry policy for the statements using it.
+// nil disables retries for the context.
+func WithRetryPolicy(ctx context.Context, policy *RetryPolicy) context.Context {
+	return context.WithValue(ctx, retryCtxKey, policy)
+}
+
+// policyOf returns the retry policy of the context or the package, nil if retries are disabled
+func policyOf(ctx context.Context) *RetryPolicy {
+	if ctx != nil {
+		if policy, ok := ctx.Value(retryCtxKey).(*RetryPolicy); ok {
+			return policy
+		}
+	}
+	retryMu.RLock()
+	defer retryMu.RUnlock()
+	return retryPolicy
+}
+
+// Do calls fn until it succeeds, returns a non retryable error, the attempts are exhausted or the context is done.
+// Errors are classified (see Classify) to decide the retries, the last error is returned as is.
+func (p *RetryPolicy) Do(ctx context.Context, fn func() error) error {
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsRetryable
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= p.MaxAttempts || !retryable(Classify(err)) {
+			return err
+		}
+		wait := backoff.Jittered(p.BaseDelay, p.MaxDelay, attempt)
+		logging.Logger.Named("DB").Warn("Retrying", zap.Int("attempt", attempt), zap.Duration("wait", wait), zap.Error(err))
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// retry runs fn with the retry policy of the statement context. Statements in transactions are not retried
+// since MySQL rolls back the whole transaction on deadlocks, retry the transaction instead (see Transaction).
+// Errors are returned as is, so comparisons like err == gorm.ErrRecordNotFound keep working.
+func retry(DB *gorm.DB, fn func() error) error {
+	ctx := DB.Statement.Context
+	policy := policyOf(ctx)
+	if policy == nil || inTransaction(DB) {
+		return fn()
+	}
+	return policy.Do(ctx, fn)
+}
+
+func inTransaction(DB *gorm.DB) bool {
+	_, ok := DB.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+// Transaction runs fn in a transaction and retries the whole transaction on retryable errors if the retry policy is enabled.
+// fn may be called more than once so it must not have side effects out of the transaction.
+func Transaction(DB *gorm.DB, fn func(tx *gorm.DB) error, opts ...*sql.TxOptions) error {
+	return retry(DB, func() error {
+		return DB.Transaction(fn, opts...)
+	})
+}
@@ -0,0 +1,132 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	driver "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type UniqueSample struct {
+	ID   uint
+	Code string `gorm:"uniqueIndex"`
+}
+
+func TestClassify(t *testing.T) {
+	assert.Nil(t, Classify(nil))
+	plain := errors.New("plain")
+	assert.Equal(t, plain, Classify(plain))
+
+	cases := map[uint16]error{1062: ErrDuplicateKey, 1452: ErrForeignKey, 1451: ErrForeignKey, 1213: ErrDeadlock, 1205: ErrLockTimeout, 3024: ErrTimeout}
+	for code, class := range cases {
+		err := Classify(&driver.MySQLError{Number: code, Message: "test"})
+		assert.ErrorIs(t, err, class)
+		var classified *Error
+		assert.True(t, errors.As(err, &classified))
+		assert.Equal(t, code, classified.Code)
+		// classified errors are not wrapped again
+		assert.Equal(t, err, Classify(err))
+	}
+
+	columns := map[string]*driver.MySQLError{
+		"Email":    {Number: 1062, Message: "Duplicate entry 'a@b.com' for key 'User.idx_User_Email'"},
+		"SKU":      {Number: 1062, Message: "Duplicate entry 'x' for key 'Order_Item.uni_Order_Item_SKU'"},
+		"UserID":   {Number: 1452, Message: "Cannot add or update a child row: a foreign key constraint fails (`db`.`Order`, CONSTRAINT `fk_Order_User` FOREIGN KEY (`UserID`) REFERENCES `User` (`ID`))"},
+		"Price":    {Number: 3819, Message: "Check constraint 'chk_Product_Price' is violated."},
+		"Quantity": {Number: 3819, Message: "Check constraint 'chk_Order_Item_Quantity' is violated."},
+	}
+	tables := map[string]string{"Price": "Product", "Quantity": "Order_Item"}
+	for column, mysqlErr := range columns {
+		var classified *Error
+		assert.True(t, errors.As(ClassifyTable(mysqlErr, tables[column]), &classified))
+		assert.Equal(t, column, classified.Column)
+	}
+	// constraint names are kept if the table is unknown
+	var classified *Error
+	assert.True(t, errors.As(Classify(&driver.MySQLError{Number: 1062, Message: "Duplicate entry 'x' for key 'uni_Order_Item_SKU'"}), &classified))
+	assert.Equal(t, "uni_Order_Item_SKU", classified.Column)
+
+	notFound := Classify(gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, notFound, ErrNotFound)
+	assert.ErrorIs(t, notFound, gorm.ErrRecordNotFound)
+	assert.False(t, IsRetryable(notFound))
+	assert.True(t, IsRetryable(Classify(&driver.MySQLError{Number: 1213})))
+}
+
+func TestCRUDErrors(t *testing.T) {
+	DB := openStreamDB(t)
+	assert.NoError(t, DB.AutoMigrate(&UniqueSample{}))
+	assert.NoError(t, Create(DB, &UniqueSample{Code: "a"}))
+	err := Classify(Create(DB, &UniqueSample{Code: "a"}))
+	assert.ErrorIs(t, err, ErrDuplicateKey)
+	assert.Equal(t, "Code", err.(*Error).Column)
+	// errors are not wrapped without a retry policy
+	assert.Equal(t, gorm.ErrRecordNotFound, Read(DB, &UniqueSample{}, 42))
+	err = Read(DB.WithContext(WithRetryPolicy(context.Background(), &DefaultRetryPolicy)), &UniqueSample{}, 42)
+	assert.Equal(t, gorm.ErrRecordNotFound, err)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	policy := &RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}
+	calls := 0
+	err := policy.Do(context.Background(), func() error {
+		calls++
+		return &driver.MySQLError{Number: 1213}
+	})
+	assert.Equal(t, &driver.MySQLError{Number: 1213}, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = policy.Do(context.Background(), func() error {
+		calls++
+		if calls < 2 {
+			return &driver.MySQLError{Number: 1205}
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+
+	calls = 0
+	err = policy.Do(context.Background(), func() error {
+		calls++
+		return &driver.MySQLError{Number: 1062}
+	})
+	assert.ErrorIs(t, Classify(err), ErrDuplicateKey)
+	assert.Equal(t, 1, calls)
+}
+
+func TestTransactionRetry(t *testing.T) {
+	DB := openStreamDB(t)
+	policy := &RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond}
+
+	// disabled by default
+	calls := 0
+	err := Transaction(DB, func(tx *gorm.DB) error {
+		calls++
+		return &driver.MySQLError{Number: 1213}
+	})
+	assert.Equal(t, &driver.MySQLError{Number: 1213}, err)
+	assert.Equal(t, 1, calls)
+
+	calls = 0
+	DB = DB.WithContext(WithRetryPolicy(context.Background(), policy))
+	err = Transaction(DB, func(tx *gorm.DB) error {
+		calls++
+		// statements in the transaction are not retried on their own
+		assert.True(t, inTransaction(tx))
+		if calls == 1 {
+			return &driver.MySQLError{Number: 1213}
+		}
+		return Create(tx, &StreamSample{Name: "retried"})
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, calls)
+	var count int64
+	assert.NoError(t, DB.Model(&StreamSample{}).Where("Name = ?", "retried").Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/filllabs/sincap-common/db/queryapi"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"gorm.io/gorm"
+)
+
+// Each walks all records matching the query in keyset ordered batches and calls fn for every batch.
+// records must be a pointer to slice, it is refilled with every batch and passed to fn.
+// Filters, fields, _q and preloads of the query are applied same as List.
+// Offset, Limit and Sort are ignored because batches are always ordered by ID.
+// Returning an error from fn stops the iteration and the error is returned.
+//
+//	Each(DB, &[]User{}, query, 500, func(batch any) error {
+//		users := batch.(*[]User)
+//		...
+//	})
+func Each(DB *gorm.DB, records any, query *qapi.Query, batchSize int, fn func(batch any) error) error {
+	value := reflect.ValueOf(records)
+	if value.Kind() != reflect.Pointer {
+		return fmt.Errorf("records must be a pointer")
+	}
+
+	elem := value.Elem()
+	if elem.Kind() != reflect.Slice {
+		return fmt.Errorf("records must be a pointer to slice")
+	}
+	if batchSize <= 0 {
+		return fmt.Errorf("batchSize must be greater than 0")
+	}
+
+	entityType, tableName := queryapi.GetTableName(records)
+	if _, hasID := entityType.FieldByName("ID"); !hasID {
+		return fmt.Errorf("%s must have an ID field for keyset iteration", entityType.Name())
+	}
+	idColumn := "`" + tableName + "`.`ID`"
+
+	// copy only the parts of the query which are meaningful for keyset iteration
+	keysetQuery := qapi.Query{}
+	var preloads []string
+	if query != nil {
+		keysetQuery.Q = query.Q
+		keysetQuery.Filter = query.Filter
+		preloads = query.Preloads
+		if len(query.Fields) > 0 {
+			keysetQuery.Fields = query.Fields
+			// ID is needed to continue from the last record of the batch
+			if !hasField(query.Fields, "ID") {
+				keysetQuery.Fields = append([]string{"ID"}, query.Fields...)
+			}
+		}
+	}
+
+	var lastID any
+	for {
+		db, err := queryapi.GenerateDB(&keysetQuery, DB, records)
+		if err != nil {
+			return err
+		}
+		db = db.Table(tableName)
+		if lastID != nil {
+			db = db.Where(idColumn+" > ?", lastID)
+		}
+		db = addPreloads(entityType, db, preloads)
+
+		// reset the slice so every batch starts empty
+		elem.Set(reflect.MakeSlice(elem.Type(), 0, batchSize))
+		result := db.Order(idColumn).Limit(batchSize).Find(records)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		length := elem.Len()
+		if length == 0 {
+			return nil
+		}
+		// read the key before fn since it may modify the batch
+		lastID = reflect.Indirect(elem.Index(length - 1)).FieldByName("ID").Interface()
+
+		if err := fn(records); err != nil {
+			return err
+		}
+		if length < batchSize {
+			return nil
+		}
+	}
+}
+
+func hasField(fields []string, name string) bool {
+	for _, f := range fields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
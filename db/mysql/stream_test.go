@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type StreamSample struct {
+	ID   uint
+	Name string
+	Age  uint
+}
+
+func openStreamDB(t *testing.T) *gorm.DB {
+	DB := dbtest.Open(t, &StreamSample{})
+	for i := 1; i <= 25; i++ {
+		assert.NoError(t, DB.Create(&StreamSample{Name: "sample", Age: uint(i)}).Error)
+	}
+	return DB
+}
+
+func TestEach(t *testing.T) {
+	DB := openStreamDB(t)
+	var batches []int
+	var ids []uint
+	err := Each(DB, &[]StreamSample{}, &qapi.Query{}, 10, func(batch any) error {
+		records := batch.(*[]StreamSample)
+		batches = append(batches, len(*records))
+		for _, r := range *records {
+			ids = append(ids, r.ID)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{10, 10, 5}, batches)
+	assert.Len(t, ids, 25)
+	assert.Equal(t, uint(1), ids[0])
+	assert.Equal(t, uint(25), ids[24])
+}
+
+func TestEachFilter(t *testing.T) {
+	DB := openStreamDB(t)
+	count := 0
+	query := &qapi.Query{Filter: []qapi.Filter{{Name: "Age", Operation: qapi.GT, Value: "20"}}}
+	err := Each(DB, &[]StreamSample{}, query, 2, func(batch any) error {
+		for _, r := range *batch.(*[]StreamSample) {
+			assert.Greater(t, r.Age, uint(20))
+			count++
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, count)
+}
+
+func TestEachStops(t *testing.T) {
+	DB := openStreamDB(t)
+	stop := errors.New("stop")
+	calls := 0
+	err := Each(DB, &[]StreamSample{}, nil, 10, func(batch any) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestEachInvalid(t *testing.T) {
+	assert.Error(t, Each(nil, []StreamSample{}, nil, 10, nil))
+	assert.Error(t, Each(nil, &StreamSample{}, nil, 10, nil))
+	assert.Error(t, Each(nil, &[]StreamSample{}, nil, 0, nil))
+}
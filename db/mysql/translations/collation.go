@@ -0,0 +1,68 @@
+package translations
+
+import (
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// collationPattern validates the collations which are written into the SQL
+var collationPattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// DefaultCollations are the collations of the base languages for the languages without Language.Collation.
+// It is empty since the collations depend on the server, MySQL 8 applications may opt in with
+//
+//	translations.DefaultCollations = translations.MySQL8Collations
+var DefaultCollations map[string]string
+
+// MySQL8Collations are the MySQL 8 collations of the base languages which sort differently than utf8mb4_0900_ai_ci.
+// They do not exist on MySQL 5.7 and MariaDB.
+var MySQL8Collations = map[string]string{
+	"cs": "utf8mb4_cs_0900_ai_ci",
+	"da": "utf8mb4_da_0900_ai_ci",
+	"de": "utf8mb4_de_pb_0900_ai_ci",
+	"eo": "utf8mb4_eo_0900_ai_ci",
+	"es": "utf8mb4_es_0900_ai_ci",
+	"et": "utf8mb4_et_0900_ai_ci",
+	"hr": "utf8mb4_hr_0900_ai_ci",
+	"hu": "utf8mb4_hu_0900_ai_ci",
+	"is": "utf8mb4_is_0900_ai_ci",
+	"lt": "utf8mb4_lt_0900_ai_ci",
+	"lv": "utf8mb4_lv_0900_ai_ci",
+	"pl": "utf8mb4_pl_0900_ai_ci",
+	"ro": "utf8mb4_ro_0900_ai_ci",
+	"ru": "utf8mb4_ru_0900_ai_ci",
+	"sk": "utf8mb4_sk_0900_ai_ci",
+	"sl": "utf8mb4_sl_0900_ai_ci",
+	"sv": "utf8mb4_sv_0900_ai_ci",
+	"tr": "utf8mb4_tr_0900_ai_ci",
+	"vi": "utf8mb4_vi_0900_ai_ci",
+}
+
+// Collation returns the collation to sort and search the texts of the language. It is the collation of the language
+// on the Language table or the one of its base language at DefaultCollations (e.g. utf8mb4_tr_0900_ai_ci for tr-TR).
+// Returns "" if the column collation should be used.
+func Collation(DB *gorm.DB, lang string) string {
+	if DB != nil {
+		if languages, err := listLanguages(DB); err == nil {
+			for _, l := range languages {
+				if strings.EqualFold(l.Code, lang) && l.Collation != "" {
+					if !collationPattern.MatchString(l.Collation) {
+						return ""
+					}
+					return l.Collation
+				}
+			}
+		}
+	}
+	return DefaultCollations[strings.ToLower(baseOf(lang))]
+}
+
+// collate returns the SQL of the expression with the collation, the expression itself if there is no collation
+func collate(expr string, collation string) string {
+	if collation == "" {
+		return expr
+	}
+	return expr + " COLLATE " + collation
+}
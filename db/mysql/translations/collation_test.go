@@ -0,0 +1,48 @@
+package translations
+
+import (
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCollation(t *testing.T) {
+	DB := dbtest.Open(t, &Language{})
+	assert.NoError(t, DB.Create(&[]Language{
+		{Code: "tr-TR"},
+		{Code: "de-DE", Collation: "utf8mb4_de_0900_ai_ci"},
+		{Code: "xx-XX", Collation: "a b"},
+	}).Error)
+	langCodesCACHE.Flush()
+	defer langCodesCACHE.Flush()
+
+	// only the collations of the Language table are used by default
+	assert.Equal(t, "", Collation(DB, "tr-TR"))
+	assert.Equal(t, "utf8mb4_de_0900_ai_ci", Collation(DB, "de-DE"))
+	DefaultCollations = MySQL8Collations
+	defer func() { DefaultCollations = nil }()
+
+	assert.Equal(t, "utf8mb4_tr_0900_ai_ci", Collation(DB, "tr-TR"))
+	assert.Equal(t, "utf8mb4_de_0900_ai_ci", Collation(DB, "de-DE"))
+	assert.Equal(t, "utf8mb4_de_pb_0900_ai_ci", Collation(nil, "de-AT"))
+	assert.Equal(t, "", Collation(DB, "en-US"))
+	assert.Equal(t, "", Collation(DB, "xx-XX"))
+	assert.ErrorIs(t, (&Language{Code: "xx-XX", Collation: "a;b"}).Validate(), ErrInvalidCollation)
+
+	DryDB := DB.Session(&gorm.Session{DryRun: true})
+	var sql string
+	assert.NoError(t, DB.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}))
+	_, err := List(DryDB, &[]MockCountry{}, &qapi.Query{Sort: []string{"Name desc"}, Q: "ı"}, []string{"tr-TR"})
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "ORDER BY LOWER(COALESCE(JSON_UNQUOTE(JSON_EXTRACT(Name, '$.\"tr-TR\"')), JSON_UNQUOTE(JSON_EXTRACT(Name, '$.\"en-US\"')))) COLLATE utf8mb4_tr_0900_ai_ci desc")
+	assert.Contains(t, sql, "'$.\"en-US\"')))) COLLATE utf8mb4_tr_0900_ai_ci LIKE LOWER(?)")
+
+	_, err = List(DryDB, &[]MockCountry{}, &qapi.Query{Sort: []string{"Name"}}, []string{"en-US"})
+	assert.NoError(t, err)
+	assert.NotContains(t, sql, "COLLATE")
+}
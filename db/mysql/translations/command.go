@@ -0,0 +1,66 @@
+package translations
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/filllabs/sincap-common/logging"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidCommand is returned when the translations command can't be parsed
+var ErrInvalidCommand = errors.New("translations: invalid command, use export {format} {source} {target} {file} or import {format} {file} [dry-run]")
+
+// RunExchange executes the given exchange command. It is usually read from flags.
+// Import reports are logged and the diff is written to stdout on dry runs.
+//
+//	export xliff en-US de-DE products.xlf
+//	import po products.po dry-run
+//
+//	fs, _ := flags.Parse(append(flags.Defaults, flags.Translations...)...)
+//	fs.Parse(os.Args[1:])
+//	if fs.Lookup("command").Value.String() == "translations" {
+//		err = translations.RunExchange(e, fs.Lookup("translations").Value.String())
+//	}
+func RunExchange(e *Exchange, command string) error {
+	args := strings.Fields(command)
+	switch {
+	case len(args) == 5 && args[0] == "export":
+		f, err := os.Create(args[4])
+		if err != nil {
+			return err
+		}
+		if err := e.Export(f, Format(args[1]), args[2], args[3]); err != nil {
+			f.Close()
+			return err
+		}
+		return f.Close()
+	case (len(args) == 3 || (len(args) == 4 && args[3] == "dry-run")) && args[0] == "import":
+		f, err := os.Open(args[2])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dryRun := len(args) == 4
+		report, err := e.Import(f, Format(args[1]), dryRun)
+		if err != nil {
+			return err
+		}
+		logging.Logger.Named("Translations").Info("Import",
+			zap.String("file", args[2]),
+			zap.String("source", report.SourceLang),
+			zap.String("target", report.TargetLang),
+			zap.Bool("dryRun", dryRun),
+			zap.Int("added", report.Count(Added)),
+			zap.Int("changed", report.Count(Changed)),
+			zap.Int("unchanged", report.Count(Unchanged)),
+			zap.Int("stale", report.Count(Stale)),
+			zap.Int("invalid", report.Count(Invalid)))
+		if dryRun {
+			return report.WriteDiff(os.Stdout)
+		}
+		return nil
+	}
+	return ErrInvalidCommand
+}
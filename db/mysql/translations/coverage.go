@@ -0,0 +1,227 @@
+package translations
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// scanBatchSize is the number of the records read at once while scanning the models (coverage, exchange)
+const scanBatchSize = 500
+
+// FieldCoverage is the completeness of a Translations field in a language
+type FieldCoverage struct {
+	Entity   string
+	Field    string
+	Language string
+	// Total is the number of the records
+	Total int
+	// Missing is the number of the records without a translation or with an empty one
+	Missing int
+	// IDs holds the primary keys (joined with ",") of the missing records
+	IDs []string
+}
+
+// Ratio returns the ratio of the translated records, 1 if there are no records
+func (c FieldCoverage) Ratio() float64 {
+	if c.Total == 0 {
+		return 1
+	}
+	return float64(c.Total-c.Missing) / float64(c.Total)
+}
+
+// Coverage scans the Translations fields of the models and reports the missing or empty translations
+// per model, field and language. Languages are read from the Language table (see ListCodes).
+// Results are ordered by the models, their fields and the languages.
+func Coverage(DB *gorm.DB, models ...any) ([]FieldCoverage, error) {
+	langs, err := ListCodes(DB)
+	if err != nil {
+		return nil, err
+	}
+	return CoverageOf(DB, langs, models...)
+}
+
+// CoverageOf is same as Coverage for the given languages
+func CoverageOf(DB *gorm.DB, langs []string, models ...any) ([]FieldCoverage, error) {
+	var coverages []FieldCoverage
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: DB}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		s := stmt.Schema
+		fields := translationFields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		start := len(coverages)
+		for _, field := range fields {
+			for _, lang := range langs {
+				coverages = append(coverages, FieldCoverage{Entity: s.Table, Field: field.Name, Language: lang})
+			}
+		}
+		err := scanRecords(DB, s, func(rows reflect.Value) error {
+			for i := 0; i < rows.Len(); i++ {
+				rv := rows.Index(i)
+				id := ""
+				for j, field := range fields {
+					t := translationsOf(rv, field)
+					for k, lang := range langs {
+						c := &coverages[start+j*len(langs)+k]
+						c.Total++
+						if t != nil && strings.TrimSpace(t.Get(lang)) != "" {
+							continue
+						}
+						if id == "" {
+							id = recordID(DB, s, rv)
+						}
+						c.Missing++
+						c.IDs = append(c.IDs, id)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return coverages, nil
+}
+
+// scanRecords reads the records of the model in batches ordered by the primary keys and calls fn with the slice of
+// every batch. Batches continue after the keys of the last record, so composite primary keys work as well
+// (FindInBatches needs a single one).
+func scanRecords(DB *gorm.DB, s *schema.Schema, fn func(rows reflect.Value) error) error {
+	if len(s.PrimaryFields) == 0 {
+		return fmt.Errorf("%s has no primary key", s.Name)
+	}
+	rows := reflect.New(reflect.SliceOf(s.ModelType))
+	var last []any
+	for {
+		db := DB.Table(s.Table)
+		if last != nil {
+			db = db.Where(keysetAfter(s.PrimaryFields, last))
+		}
+		for _, field := range s.PrimaryFields {
+			db = db.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}})
+		}
+		rows.Elem().Set(reflect.MakeSlice(rows.Elem().Type(), 0, scanBatchSize))
+		if err := db.Limit(scanBatchSize).Find(rows.Interface()).Error; err != nil {
+			return err
+		}
+		n := rows.Elem().Len()
+		if n == 0 {
+			return nil
+		}
+		// read the keys before fn since it may modify the batch
+		record := rows.Elem().Index(n - 1)
+		last = make([]any, len(s.PrimaryFields))
+		for i, field := range s.PrimaryFields {
+			last[i], _ = field.ValueOf(DB.Statement.Context, record)
+		}
+		if err := fn(rows.Elem()); err != nil {
+			return err
+		}
+		if n < scanBatchSize {
+			return nil
+		}
+	}
+}
+
+// keysetAfter returns the condition of the records ordered after the keys: a > ? OR (a = ? AND b > ?) ...
+func keysetAfter(fields []*schema.Field, keys []any) clause.Expression {
+	ors := make([]clause.Expression, len(fields))
+	for i, field := range fields {
+		ands := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			ands = append(ands, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: fields[j].DBName}, Value: keys[j]})
+		}
+		ands = append(ands, clause.Gt{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: keys[i]})
+		ors[i] = clause.And(ands...)
+	}
+	return clause.Or(ors...)
+}
+
+// CoverageHandler responds the coverage of the models as json. The languages can be limited with the lang query
+// parameter (e.g. ?lang=ar-SA&lang=tr-TR) and the IDs are omitted with ?ids=false.
+// It reports every record of the tables, so it must be mounted behind an admin authorization.
+// Errors are returned to the error handler of the app (e.g. apierrors.Handler), so the database errors are not exposed.
+//
+//	admin.Get("/translations/coverage", translations.CoverageHandler(db.DB(), &Product{}, &Category{}))
+func CoverageHandler(DB *gorm.DB, models ...any) func(ctx *fiber.Ctx) error {
+	return func(ctx *fiber.Ctx) error {
+		var langs []string
+		for _, lang := range ctx.Context().QueryArgs().PeekMulti("lang") {
+			langs = append(langs, strings.Split(string(lang), ",")...)
+		}
+		var coverages []FieldCoverage
+		var err error
+		if len(langs) > 0 {
+			coverages, err = CoverageOf(DB.WithContext(ctx.UserContext()), langs, models...)
+		} else {
+			coverages, err = Coverage(DB.WithContext(ctx.UserContext()), models...)
+		}
+		if err != nil {
+			return err
+		}
+		if ctx.Query("ids") == "false" {
+			for i := range coverages {
+				coverages[i].IDs = nil
+			}
+		}
+		return ctx.JSON(coverages)
+	}
+}
+
+// MissingSuffix marks the filters of the Translations fields matching the records without a translation
+// in the language, e.g. _filter=Name.@missing=ar-SA. "!=" matches the translated ones and "|=" the records missing any of the languages.
+const MissingSuffix = ".@missing"
+
+// splitMissingFilters returns a copy of the query without the missing filters and their conditions
+func splitMissingFilters(query *qapi.Query, tableName string, multiLangFields []string) (*qapi.Query, []string, error) {
+	var conditions []string
+	var filters []qapi.Filter
+	for _, filter := range query.Filter {
+		if !strings.HasSuffix(filter.Name, MissingSuffix) {
+			filters = append(filters, filter)
+			continue
+		}
+		name := strings.TrimSuffix(filter.Name, MissingSuffix)
+		if !contains(multiLangFields, name) {
+			return nil, nil, fmt.Errorf("%s is not a translations field", name)
+		}
+		langs := []string{filter.Value}
+		if filter.Operation == qapi.IN {
+			langs = strings.Split(filter.Value, "|")
+		}
+		missing := make([]string, len(langs))
+		for i, lang := range langs {
+			if !langCodePattern.MatchString(lang) {
+				return nil, nil, ErrInvalidLanguage
+			}
+			col, path := splitTranslationField(name)
+			missing[i] = fmt.Sprintf("TRIM(COALESCE(%s, '')) = ''", translatedPath(column(tableName, col), path, []string{lang}))
+		}
+		switch filter.Operation {
+		case qapi.EQ, qapi.IN:
+			conditions = append(conditions, "("+strings.Join(missing, " OR ")+")")
+		case qapi.NEQ:
+			conditions = append(conditions, "NOT ("+missing[0]+")")
+		default:
+			return nil, nil, qapi.ErrInvalidOp
+		}
+	}
+	if len(conditions) == 0 {
+		return query, nil, nil
+	}
+	q := *query
+	q.Filter = filters
+	return &q, conditions, nil
+}
@@ -0,0 +1,119 @@
+package translations
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestCoverage(t *testing.T) {
+	DB := newExchangeDB(t)
+	assert.NoError(t, DB.Exec("CREATE TABLE `Language` (`code` TEXT)").Error)
+	assert.NoError(t, DB.Exec("INSERT INTO `Language` VALUES ('en-US'), ('de-DE')").Error)
+	langCodesCACHE.Flush()
+	defer langCodesCACHE.Flush()
+
+	coverages, err := Coverage(DB, &MockCountry{}, &MockUser{})
+	assert.NoError(t, err)
+	assert.Equal(t, []FieldCoverage{
+		{Entity: "MockCountry", Field: "Name", Language: "en-US", Total: 3, Missing: 1, IDs: []string{"3"}},
+		{Entity: "MockCountry", Field: "Name", Language: "de-DE", Total: 3, Missing: 2, IDs: []string{"2", "3"}},
+	}, coverages)
+	assert.InDelta(t, 1.0/3, coverages[1].Ratio(), 0.001)
+	assert.Equal(t, 1.0, FieldCoverage{}.Ratio())
+
+	app := fiber.New()
+	app.Get("/coverage", CoverageHandler(DB, &MockCountry{}))
+	res, err := app.Test(httptest.NewRequest("GET", "/coverage?lang=de-DE&ids=false", nil))
+	assert.NoError(t, err)
+	body, _ := io.ReadAll(res.Body)
+	var response []FieldCoverage
+	assert.NoError(t, json.Unmarshal(body, &response))
+	assert.Equal(t, []FieldCoverage{{Entity: "MockCountry", Field: "Name", Language: "de-DE", Total: 3, Missing: 2}}, response)
+
+	// errors go to the error handler of the app
+	var handled error
+	app = fiber.New(fiber.Config{ErrorHandler: func(ctx *fiber.Ctx, err error) error {
+		handled = err
+		return ctx.SendStatus(fiber.StatusInternalServerError)
+	}})
+	app.Get("/coverage", CoverageHandler(DB, &MockCity{}))
+	res, err = app.Test(httptest.NewRequest("GET", "/coverage", nil))
+	assert.NoError(t, err)
+	assert.Equal(t, fiber.StatusInternalServerError, res.StatusCode)
+	assert.Error(t, handled)
+	body, _ = io.ReadAll(res.Body)
+	assert.NotContains(t, string(body), "MockCity")
+}
+
+type MockLabel struct {
+	Namespace string `gorm:"primaryKey"`
+	Key       string `gorm:"primaryKey"`
+	Text      *Translations
+}
+
+func TestCoverageCompositeKey(t *testing.T) {
+	DB := dbtest.Open(t, &MockLabel{})
+	var labels []MockLabel
+	for _, namespace := range []string{"admin", "shop"} {
+		for i := 0; i < scanBatchSize/2+10; i++ {
+			text := &Translations{}
+			text.Set("en-US", "label")
+			labels = append(labels, MockLabel{Namespace: namespace, Key: fmt.Sprintf("key%d", i), Text: text})
+		}
+	}
+	assert.NoError(t, DB.CreateInBatches(labels, 100).Error)
+
+	coverages, err := CoverageOf(DB, []string{"en-US", "de-DE"}, &MockLabel{})
+	assert.NoError(t, err)
+	assert.Len(t, coverages, 2)
+	assert.Equal(t, len(labels), coverages[0].Total)
+	assert.Equal(t, 0, coverages[0].Missing)
+	assert.Equal(t, len(labels), coverages[1].Missing)
+	ids := map[string]bool{}
+	for _, id := range coverages[1].IDs {
+		ids[id] = true
+	}
+	assert.Len(t, ids, len(labels))
+	assert.True(t, ids["shop,key0"])
+}
+
+func TestMissingFilter(t *testing.T) {
+	DB := dbtest.Open(t).Session(&gorm.Session{DryRun: true})
+	var sql string
+	assert.NoError(t, DB.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}))
+
+	query := &qapi.Query{Filter: []qapi.Filter{{Name: "Name.@missing", Operation: qapi.EQ, Value: "ar-SA"}, {Name: "Code", Operation: qapi.EQ, Value: "SA"}}}
+	_, err := List(DB, &[]MockCountry{}, query, []string{"en-US"})
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "TRIM(COALESCE(JSON_UNQUOTE(JSON_EXTRACT(`MockCountry`.`Name`, '$.\"ar-SA\"')), '')) = ''")
+	assert.Contains(t, sql, "`Code` = ?")
+	assert.Len(t, query.Filter, 2)
+
+	query = &qapi.Query{Filter: []qapi.Filter{{Name: "Name.@missing", Operation: qapi.NEQ, Value: "ar-SA"}}}
+	_, err = List(DB, &[]MockCountry{}, query, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "NOT (TRIM(")
+
+	query = &qapi.Query{Filter: []qapi.Filter{{Name: "Name.@missing", Operation: qapi.IN, Value: "ar-SA|tr-TR"}}}
+	_, err = List(DB, &[]MockCountry{}, query, []string{"en-US"})
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "'$.\"ar-SA\"')), '')) = '' OR TRIM(")
+
+	_, err = List(DB, &[]MockCountry{}, &qapi.Query{Filter: []qapi.Filter{{Name: "Name.@missing", Operation: qapi.EQ, Value: "a'b"}}}, nil)
+	assert.ErrorIs(t, err, ErrInvalidLanguage)
+	_, err = List(DB, &[]MockCountry{}, &qapi.Query{Filter: []qapi.Filter{{Name: "Code.@missing", Operation: qapi.EQ, Value: "ar-SA"}}}, nil)
+	assert.Error(t, err)
+	_, err = List(DB, &[]MockCountry{}, &qapi.Query{Filter: []qapi.Filter{{Name: "Name.@missing", Operation: qapi.LK, Value: "ar-SA"}}}, nil)
+	assert.ErrorIs(t, err, qapi.ErrInvalidOp)
+}
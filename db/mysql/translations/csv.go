@@ -0,0 +1,50 @@
+package translations
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+)
+
+var csvHeader = []string{"Entity", "ID", "Field"}
+
+// writeCSV writes the units with the header "Entity,ID,Field,{source},{target}"
+func writeCSV(w io.Writer, source string, target string, units []Unit) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append(csvHeader, source, target)); err != nil {
+		return err
+	}
+	for _, u := range units {
+		if err := cw.Write([]string{u.Entity, u.ID, u.Field, u.Source, u.Target}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func readCSV(r io.Reader) (string, string, []Unit, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = len(csvHeader) + 2
+	header, err := cr.Read()
+	if err != nil {
+		return "", "", nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	for i, name := range csvHeader {
+		if header[i] != name {
+			return "", "", nil, fmt.Errorf("%w: column %d must be %s", ErrInvalidFile, i+1, name)
+		}
+	}
+	var units []Unit
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", "", nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+		}
+		units = append(units, Unit{Entity: record[0], ID: record[1], Field: record[2], Source: record[3], Target: record[4]})
+	}
+	return header[3], header[4], units, nil
+}
@@ -0,0 +1,396 @@
+package translations
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Format is a file format of the translation exchange
+type Format string
+
+// Supported formats of the translation exchange
+const (
+	XLIFF Format = "xliff"
+	PO    Format = "po"
+	CSV   Format = "csv"
+)
+
+var (
+	// ErrUnknownFormat is returned for the formats other than XLIFF, PO and CSV
+	ErrUnknownFormat = errors.New("translations: unknown format")
+	// ErrInvalidFile is returned when the imported file can't be parsed
+	ErrInvalidFile = errors.New("translations: invalid file")
+)
+
+// Unit is a single text of a Translations field of a record
+type Unit struct {
+	// Entity is the table name of the record
+	Entity string
+	// ID holds the primary key values joined with ","
+	ID     string
+	Field  string
+	Source string
+	Target string
+}
+
+// key returns the reference of the unit used by the formats ("{Entity}/{ID}/{Field}")
+func (u Unit) key() string {
+	return u.Entity + "/" + u.ID + "/" + u.Field
+}
+
+// parseKey parses the reference of the unit, IDs may contain "/"
+func parseKey(key string) (Unit, bool) {
+	first, last := strings.Index(key, "/"), strings.LastIndex(key, "/")
+	if first <= 0 || last <= first+1 || last == len(key)-1 {
+		return Unit{}, false
+	}
+	return Unit{Entity: key[:first], ID: key[first+1 : last], Field: key[last+1:]}, true
+}
+
+// Status is the result of an imported unit
+type Status string
+
+// Statuses of the imported units
+const (
+	// Added means the record had no translation in the target language
+	Added Status = "added"
+	// Changed means the translation in the target language is replaced
+	Changed Status = "changed"
+	// Unchanged means the translation is the same or empty
+	Unchanged Status = "unchanged"
+	// Stale means the source text is changed after the export, the unit is not imported
+	Stale Status = "stale"
+	// Invalid means the unit does not fit the models, see Change.Error
+	Invalid Status = "invalid"
+)
+
+// Change is an imported unit with its result
+type Change struct {
+	Unit
+	Status Status
+	// Old is the current translation in the target language
+	Old   string
+	Error string
+}
+
+// Report is the result of an import
+type Report struct {
+	SourceLang string
+	TargetLang string
+	DryRun     bool
+	Changes    []Change
+}
+
+// Count returns the number of the changes with the status
+func (r *Report) Count(status Status) int {
+	count := 0
+	for _, c := range r.Changes {
+		if c.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// WriteDiff writes the added (+), changed (~), stale (!) and invalid (x) units in a human readable form
+func (r *Report) WriteDiff(w io.Writer) error {
+	for _, c := range r.Changes {
+		var line string
+		switch c.Status {
+		case Added:
+			line = fmt.Sprintf("+ %s: %q", c.key(), c.Target)
+		case Changed:
+			line = fmt.Sprintf("~ %s: %q -> %q", c.key(), c.Old, c.Target)
+		case Stale:
+			line = fmt.Sprintf("! %s: source changed, %s", c.key(), c.Error)
+		case Invalid:
+			line = fmt.Sprintf("x %s: %s", c.key(), c.Error)
+		default:
+			continue
+		}
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d added, %d changed, %d unchanged, %d stale, %d invalid\n",
+		r.Count(Added), r.Count(Changed), r.Count(Unchanged), r.Count(Stale), r.Count(Invalid))
+	return err
+}
+
+// Exchange exports the Translations fields of the registered models to the files of the CAT tools and imports them back
+//
+//	e := translations.NewExchange(db.DB(), &Product{}, &Category{})
+//	err := e.Export(w, translations.XLIFF, "en-US", "de-DE")
+//	report, err := e.Import(r, translations.XLIFF, true) // dry run
+type Exchange struct {
+	db     *gorm.DB
+	models map[string]*schema.Schema
+}
+
+// NewExchange returns an exchange for the Translations fields of the models
+func NewExchange(DB *gorm.DB, models ...any) (*Exchange, error) {
+	e := &Exchange{db: DB, models: map[string]*schema.Schema{}}
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: DB}
+		if err := stmt.Parse(model); err != nil {
+			return nil, err
+		}
+		if len(stmt.Schema.PrimaryFields) == 0 {
+			return nil, fmt.Errorf("%s has no primary key", stmt.Schema.Name)
+		}
+		e.models[stmt.Schema.Table] = stmt.Schema
+	}
+	return e, nil
+}
+
+// Units returns the units of all records of the models ordered by entity, primary key and field.
+// Records are read in batches and texts without both source and target are skipped.
+func (e *Exchange) Units(source string, target string) ([]Unit, error) {
+	var units []Unit
+	for _, entity := range e.entities() {
+		s := e.models[entity]
+		fields := translationFields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		err := scanRecords(e.db, s, func(rows reflect.Value) error {
+			for i := 0; i < rows.Len(); i++ {
+				rv := rows.Index(i)
+				id := recordID(e.db, s, rv)
+				for _, field := range fields {
+					t := translationsOf(rv, field)
+					u := Unit{Entity: entity, ID: id, Field: field.Name}
+					if t != nil {
+						u.Source, u.Target = t.Get(source), t.Get(target)
+					}
+					if u.Source != "" || u.Target != "" {
+						units = append(units, u)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return units, nil
+}
+
+// Export writes the units of the languages in the format
+func (e *Exchange) Export(w io.Writer, format Format, source string, target string) error {
+	if !langCodePattern.MatchString(source) || !langCodePattern.MatchString(target) {
+		return ErrInvalidLanguage
+	}
+	units, err := e.Units(source, target)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case XLIFF:
+		return writeXLIFF(w, source, target, units)
+	case PO:
+		return writePO(w, source, target, units)
+	case CSV:
+		return writeCSV(w, source, target, units)
+	}
+	return ErrUnknownFormat
+}
+
+// Import reads the units in the format and writes their targets to the records in a transaction.
+// Units are validated against the models, the records and the languages. Units whose sources differ from the current
+// texts are reported as stale and skipped. If dryRun is true nothing is written and the report tells what would change.
+// Only the target language is written (see Update), so the other languages changed meanwhile are kept.
+func (e *Exchange) Import(r io.Reader, format Format, dryRun bool) (*Report, error) {
+	var source, target string
+	var units []Unit
+	var err error
+	switch format {
+	case XLIFF:
+		source, target, units, err = readXLIFF(r)
+	case PO:
+		source, target, units, err = readPO(r)
+	case CSV:
+		source, target, units, err = readCSV(r)
+	default:
+		return nil, ErrUnknownFormat
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !langCodePattern.MatchString(source) || !langCodePattern.MatchString(target) {
+		return nil, ErrInvalidLanguage
+	}
+	if codes, err := ListCodes(e.db); err == nil && len(codes) > 0 && !contains(codes, target) {
+		return nil, fmt.Errorf("%w: %s is not a language", ErrInvalidLanguage, target)
+	}
+
+	report := &Report{SourceLang: source, TargetLang: target, DryRun: dryRun}
+	loaded := map[string]map[string]reflect.Value{}
+	updated := map[string]map[string]string{}
+	for _, u := range units {
+		change := e.check(u, source, target, loaded)
+		report.Changes = append(report.Changes, change)
+		if change.Status != Added && change.Status != Changed {
+			continue
+		}
+		if updated[u.Entity] == nil {
+			updated[u.Entity] = map[string]string{}
+		}
+		updated[u.Entity][u.ID+"\x00"+u.Field] = u.Target
+	}
+	if dryRun || report.Count(Added)+report.Count(Changed) == 0 {
+		return report, nil
+	}
+	err = e.db.Transaction(func(tx *gorm.DB) error {
+		for _, entity := range sortedKeys(updated) {
+			for _, key := range sortedKeys(updated[entity]) {
+				parts := strings.SplitN(key, "\x00", 2)
+				rv := loaded[entity][parts[0]]
+				text := map[string]any{parts[1]: map[string]any{target: updated[entity][key]}}
+				if err := Update(tx, rv.Addr().Interface(), text); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return report, err
+}
+
+// check validates the unit and returns its change, loads the record of the unit on demand
+func (e *Exchange) check(u Unit, source string, target string, loaded map[string]map[string]reflect.Value) Change {
+	change := Change{Unit: u}
+	s, ok := e.models[u.Entity]
+	if !ok {
+		change.Status, change.Error = Invalid, "unknown entity"
+		return change
+	}
+	field := s.LookUpField(u.Field)
+	if field == nil || !isTranslations(field) {
+		change.Status, change.Error = Invalid, "unknown field"
+		return change
+	}
+	if loaded[u.Entity] == nil {
+		loaded[u.Entity] = map[string]reflect.Value{}
+	}
+	rv, ok := loaded[u.Entity][u.ID]
+	if !ok {
+		var err error
+		if rv, err = e.load(s, u.ID); err != nil {
+			change.Status, change.Error = Invalid, err.Error()
+			return change
+		}
+		loaded[u.Entity][u.ID] = rv
+	}
+	if !rv.IsValid() {
+		change.Status, change.Error = Invalid, "unknown record"
+		return change
+	}
+	t := translationsOf(rv, field)
+	current := ""
+	if t != nil {
+		current, change.Old = t.Get(source), t.Get(target)
+	}
+	switch {
+	case u.Target == "" || u.Target == change.Old:
+		change.Status = Unchanged
+	case u.Source != current:
+		change.Status, change.Error = Stale, fmt.Sprintf("now %q", current)
+	case !samePlaceholders(u.Source, u.Target):
+		change.Status, change.Error = Invalid, "placeholders of the source and the target differ"
+	case change.Old == "":
+		change.Status = Added
+	default:
+		change.Status = Changed
+	}
+	return change
+}
+
+// load reads the record of the model by its id (see recordID), the zero Value if there is no such record
+func (e *Exchange) load(s *schema.Schema, id string) (reflect.Value, error) {
+	values := strings.SplitN(id, ",", len(s.PrimaryFields))
+	if len(values) != len(s.PrimaryFields) {
+		return reflect.Value{}, nil
+	}
+	tx := e.db.Table(s.Table)
+	for i, pk := range s.PrimaryFields {
+		tx = tx.Where(clause.Eq{Column: clause.Column{Name: pk.DBName}, Value: values[i]})
+	}
+	record := reflect.New(s.ModelType)
+	if err := tx.Take(record.Interface()).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return reflect.Value{}, nil
+		}
+		return reflect.Value{}, err
+	}
+	return record.Elem(), nil
+}
+
+// recordID returns the primary key values of the record joined with ","
+func recordID(DB *gorm.DB, s *schema.Schema, rv reflect.Value) string {
+	ids := make([]string, len(s.PrimaryFields))
+	for i, pk := range s.PrimaryFields {
+		value, _ := pk.ValueOf(DB.Statement.Context, rv)
+		ids[i] = fmt.Sprint(value)
+	}
+	return strings.Join(ids, ",")
+}
+
+func (e *Exchange) entities() []string {
+	entities := make([]string, 0, len(e.models))
+	for entity := range e.models {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+	return entities
+}
+
+var translationsType = reflect.TypeOf((*Translations)(nil))
+
+func isTranslations(field *schema.Field) bool {
+	return field.FieldType == translationsType
+}
+
+func translationFields(s *schema.Schema) []*schema.Field {
+	var fields []*schema.Field
+	for _, field := range s.Fields {
+		if isTranslations(field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func translationsOf(rv reflect.Value, field *schema.Field) *Translations {
+	t, _ := rv.FieldByIndex(field.StructField.Index).Interface().(*Translations)
+	return t
+}
+
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}|%[-+# 0]*[0-9]*(?:\.[0-9]+)?[sdvfqxt]`)
+
+// samePlaceholders reports whether the texts have the same {placeholders} and printf verbs
+func samePlaceholders(source string, target string) bool {
+	a, b := placeholderPattern.FindAllString(source, -1), placeholderPattern.FindAllString(target, -1)
+	sort.Strings(a)
+	sort.Strings(b)
+	return reflect.DeepEqual(a, b)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,169 @@
+package translations
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func newExchangeDB(t *testing.T) *gorm.DB {
+	DB := dbtest.Open(t, &MockCountry{})
+	germany, turkey := &Translations{}, &Translations{}
+	germany.Set("en-US", "Germany")
+	germany.Set("de-DE", "Deutschland")
+	turkey.Set("en-US", "Turkey, \"{name}\"\nline")
+	assert.NoError(t, DB.Create(&[]MockCountry{{ID: 1, Code: "DE", Name: germany}, {ID: 2, Code: "TR", Name: turkey}, {ID: 3, Code: "XX"}}).Error)
+	return DB
+}
+
+func TestExchange(t *testing.T) {
+	DB := newExchangeDB(t)
+	e, err := NewExchange(DB, &MockCountry{})
+	assert.NoError(t, err)
+
+	units, err := e.Units("en-US", "de-DE")
+	assert.NoError(t, err)
+	assert.Equal(t, []Unit{
+		{Entity: "MockCountry", ID: "1", Field: "Name", Source: "Germany", Target: "Deutschland"},
+		{Entity: "MockCountry", ID: "2", Field: "Name", Source: "Turkey, \"{name}\"\nline"},
+	}, units)
+
+	for _, format := range []Format{XLIFF, PO, CSV} {
+		var buf bytes.Buffer
+		assert.NoError(t, e.Export(&buf, format, "en-US", "de-DE"), format)
+		source, target, read, err := readFormat(format, bytes.NewReader(buf.Bytes()))
+		assert.NoError(t, err, format)
+		assert.Equal(t, "en-US", source, format)
+		assert.Equal(t, "de-DE", target, format)
+		assert.Equal(t, units, read, format)
+	}
+	assert.ErrorIs(t, e.Export(&bytes.Buffer{}, "xls", "en-US", "de-DE"), ErrUnknownFormat)
+	assert.ErrorIs(t, e.Export(&bytes.Buffer{}, CSV, "en-US", "de'DE"), ErrInvalidLanguage)
+}
+
+func TestExchangeImport(t *testing.T) {
+	DB := newExchangeDB(t)
+	e, err := NewExchange(DB, &MockCountry{})
+	assert.NoError(t, err)
+
+	file := "Entity,ID,Field,en-US,de-DE\n" +
+		"MockCountry,1,Name,Germany,Deutschland!\n" +
+		"MockCountry,2,Name,\"Turkey, \"\"{name}\"\"\nline\",\"Türkei, \"\"{name}\"\"\"\n" +
+		"MockCountry,3,Name,Unknown,Unbekannt\n" +
+		"MockCountry,4,Name,Nowhere,Nirgendwo\n" +
+		"MockCountry,1,Code,DE,DE\n" +
+		"MockCity,1,Name,Berlin,Berlin\n"
+
+	report, err := e.Import(strings.NewReader(file), CSV, true)
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, []Status{Changed, Added, Stale, Invalid, Invalid, Invalid}, statuses(report))
+	var diff bytes.Buffer
+	assert.NoError(t, report.WriteDiff(&diff))
+	assert.Contains(t, diff.String(), `~ MockCountry/1/Name: "Deutschland" -> "Deutschland!"`)
+	assert.Contains(t, diff.String(), "x MockCountry/4/Name: unknown record")
+	assert.Contains(t, diff.String(), "1 added, 1 changed, 0 unchanged, 1 stale, 3 invalid")
+
+	var country MockCountry
+	assert.NoError(t, DB.First(&country, 1).Error)
+	assert.Equal(t, "Deutschland", country.Name.Get("de-DE"))
+
+	report, err = e.Import(strings.NewReader(file), CSV, false)
+	assert.NoError(t, err)
+	assert.False(t, report.DryRun)
+	assert.NoError(t, DB.First(&country, 1).Error)
+	assert.Equal(t, "Deutschland!", country.Name.Get("de-DE"))
+	assert.Equal(t, "Germany", country.Name.Get("en-US"))
+	country = MockCountry{}
+	assert.NoError(t, DB.First(&country, 2).Error)
+	assert.Equal(t, "Türkei, \"{name}\"", country.Name.Get("de-DE"))
+
+	report, err = e.Import(strings.NewReader(file), CSV, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []Status{Unchanged, Unchanged, Stale, Invalid, Invalid, Invalid}, statuses(report))
+
+	// placeholders must be kept
+	report, err = e.Import(strings.NewReader("Entity,ID,Field,en-US,de-DE\nMockCountry,2,Name,\"Turkey, \"\"{name}\"\"\nline\",Türkei\n"), CSV, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []Status{Invalid}, statuses(report))
+
+	// only the target language is written, the languages changed after reading the records are kept
+	assert.NoError(t, DB.Callback().Update().Before("gorm:update").Register("test:concurrent", func(tx *gorm.DB) {
+		tx.Session(&gorm.Session{NewDB: true}).Exec("UPDATE MockCountry SET Name = JSON_SET(Name, '$.\"fr-FR\"', 'Allemagne') WHERE ID = 1")
+	}))
+	_, err = e.Import(strings.NewReader("Entity,ID,Field,en-US,de-DE\nMockCountry,1,Name,Germany,Deutschland\n"), CSV, false)
+	assert.NoError(t, err)
+	country = MockCountry{}
+	assert.NoError(t, DB.First(&country, 1).Error)
+	assert.Equal(t, "Deutschland", country.Name.Get("de-DE"))
+	assert.Equal(t, "Allemagne", country.Name.Get("fr-FR"))
+
+	_, err = e.Import(strings.NewReader("Entity,ID\n"), CSV, true)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+	_, err = e.Import(strings.NewReader(`<xliff xmlns="urn:oasis:names:tc:xliff:document:2.0" version="1.2"/>`), XLIFF, true)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+	_, err = e.Import(strings.NewReader("msgctxt \"MockCountry\"\nmsgid \"a\"\nmsgstr \"b\"\n"), PO, true)
+	assert.ErrorIs(t, err, ErrInvalidFile)
+}
+
+func TestExchangeCompositeKey(t *testing.T) {
+	DB := dbtest.Open(t, &MockLabel{})
+	var labels []MockLabel
+	for _, namespace := range []string{"admin", "shop"} {
+		for i := 0; i < scanBatchSize/2+10; i++ {
+			text := &Translations{}
+			text.Set("en-US", fmt.Sprintf("%s %d", namespace, i))
+			labels = append(labels, MockLabel{Namespace: namespace, Key: fmt.Sprintf("key%d", i), Text: text})
+		}
+	}
+	assert.NoError(t, DB.CreateInBatches(labels, 100).Error)
+	e, err := NewExchange(DB, &MockLabel{})
+	assert.NoError(t, err)
+
+	units, err := e.Units("en-US", "de-DE")
+	assert.NoError(t, err)
+	assert.Len(t, units, len(labels))
+	assert.Equal(t, Unit{Entity: "MockLabel", ID: "admin,key0", Field: "Text", Source: "admin 0"}, units[0])
+
+	var buf bytes.Buffer
+	assert.NoError(t, e.Export(&buf, CSV, "en-US", "de-DE"))
+	file := strings.Replace(buf.String(), "\"shop,key7\",Text,shop 7,", "\"shop,key7\",Text,shop 7,Laden 7", 1)
+	report, err := e.Import(strings.NewReader(file), CSV, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Count(Added))
+	assert.Equal(t, 0, report.Count(Invalid))
+	var label MockLabel
+	assert.NoError(t, DB.First(&label, "Namespace = ? AND `Key` = ?", "shop", "key7").Error)
+	assert.Equal(t, "Laden 7", label.Text.Get("de-DE"))
+	assert.Equal(t, "shop 7", label.Text.Get("en-US"))
+}
+
+func TestSamePlaceholders(t *testing.T) {
+	assert.True(t, samePlaceholders("{a} and {b}", "{b} und {a}"))
+	assert.True(t, samePlaceholders("%d items", "%d Artikel"))
+	assert.False(t, samePlaceholders("{a}", "{b}"))
+	assert.False(t, samePlaceholders("%s", ""))
+}
+
+func readFormat(format Format, r *bytes.Reader) (string, string, []Unit, error) {
+	switch format {
+	case XLIFF:
+		return readXLIFF(r)
+	case PO:
+		return readPO(r)
+	}
+	return readCSV(r)
+}
+
+func statuses(report *Report) []Status {
+	s := make([]Status, len(report.Changes))
+	for i, c := range report.Changes {
+		s[i] = c.Status
+	}
+	return s
+}
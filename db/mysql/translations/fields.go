@@ -0,0 +1,260 @@
+package translations
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/filllabs/sincap-common/db/types"
+	"github.com/filllabs/sincap-common/logging"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/filllabs/sincap-common/reflection"
+	"go.uber.org/zap"
+	"gorm.io/gorm/schema"
+)
+
+// pathsTag is the struct tag listing the paths of the translations objects ({"en-US": "...", "tr-TR": "..."})
+// inside a types.JSON field, separated by commas
+//
+//	Attributes types.JSON `translations:"seo.title,seo.description"`
+const pathsTag = "translations"
+
+// jsonPathPattern matches the paths of the pathsTag, keys are joined with dots
+var jsonPathPattern = regexp.MustCompile(`^[A-Za-z0-9_]+(\.[A-Za-z0-9_]+)*$`)
+
+var (
+	jsonType    = reflect.TypeOf(types.JSON{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	valuerType  = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+)
+
+// translationFieldsCache holds the translatable fields by the model types
+var translationFieldsCache sync.Map
+
+// modelColumn is a column of a model with the struct field mapped to it
+type modelColumn struct {
+	Name  string
+	Field reflect.StructField
+}
+
+// modelColumns returns the columns of the model type. Fields of the embedded structs (anonymous ones or the ones
+// tagged with embedded) are flattened with their embeddedPrefix like GORM does.
+func modelColumns(t reflect.Type) []modelColumn {
+	return appendColumns(nil, t, "")
+}
+
+func appendColumns(columns []modelColumn, t reflect.Type, prefix string) []modelColumn {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if embedded, ok := embeddedType(field); ok {
+			settings := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")
+			columns = appendColumns(columns, embedded, prefix+settings["EMBEDDEDPREFIX"])
+			continue
+		}
+		if shouldSkipField(field) {
+			continue
+		}
+		columns = append(columns, modelColumn{Name: prefix + getColumnName(field), Field: field})
+	}
+	return columns
+}
+
+// embeddedType returns the struct type of the field if its fields are the columns of the model
+func embeddedType(field reflect.StructField) (reflect.Type, bool) {
+	t := reflection.DepointerField(field.Type)
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if _, ok := schema.ParseTagSetting(field.Tag.Get("gorm"), ";")["EMBEDDED"]; ok {
+		return t, true
+	}
+	if !field.Anonymous || shouldSkipField(field) {
+		return nil, false
+	}
+	// anonymous scanners (e.g. Translations) are columns themselves
+	if reflect.PointerTo(t).Implements(scannerType) || t.Implements(valuerType) {
+		return nil, false
+	}
+	return t, true
+}
+
+// translationField is a translatable field of a model. Name is the name of the struct field and Column is its
+// column, the paths of the types.JSON fields are appended to both (e.g. Attributes.seo.title).
+type translationField struct {
+	Name   string
+	Column string
+}
+
+// findTranslationFields returns the translatable fields of the record: the columns of the Translations fields
+// (also the ones of the embedded structs) and the paths of the types.JSON fields tagged with translations
+// as <Column>.<path> (e.g. Attributes.seo.title).
+func findTranslationFields(record any) []string {
+	fields := translationFieldsOf(record)
+	columns := make([]string, len(fields))
+	for i, field := range fields {
+		columns[i] = field.Column
+	}
+	return columns
+}
+
+// translationFieldsOf returns the translatable fields of the record with their struct field names (see findTranslationFields)
+func translationFieldsOf(record any) []translationField {
+	recordType := reflect.TypeOf(record)
+	if recordType.Kind() == reflect.Ptr {
+		recordType = recordType.Elem()
+	}
+
+	// Handle slice types to get the element type
+	if recordType.Kind() == reflect.Slice {
+		recordType = recordType.Elem()
+		if recordType.Kind() == reflect.Ptr {
+			recordType = recordType.Elem()
+		}
+	}
+
+	if recordType.Kind() != reflect.Struct {
+		return nil
+	}
+	if fields, ok := translationFieldsCache.Load(recordType); ok {
+		return fields.([]translationField)
+	}
+
+	translationFields := []translationField{}
+	for _, column := range modelColumns(recordType) {
+		if column.Field.Type.AssignableTo(translationsType) {
+			translationFields = append(translationFields, translationField{Name: column.Field.Name, Column: column.Name})
+			continue
+		}
+		for _, path := range translationPaths(column.Field) {
+			translationFields = append(translationFields, translationField{Name: column.Field.Name + "." + path, Column: column.Name + "." + path})
+		}
+	}
+	translationFieldsCache.Store(recordType, translationFields)
+	return translationFields
+}
+
+// translationPaths returns the valid paths of the pathsTag of the types.JSON field
+func translationPaths(field reflect.StructField) []string {
+	tag, ok := field.Tag.Lookup(pathsTag)
+	if !ok || reflection.DepointerField(field.Type) != jsonType {
+		return nil
+	}
+	var paths []string
+	for _, path := range strings.Split(tag, ",") {
+		path = strings.TrimSpace(path)
+		if !jsonPathPattern.MatchString(path) {
+			logging.Logger.Named("Translations").Warn("Invalid translations path", zap.String("field", field.Name), zap.String("path", path))
+			continue
+		}
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// translationColumns returns the fields which are Translations columns, leaving out the paths of the JSON fields
+func translationColumns(fields []translationField) []translationField {
+	columns := make([]translationField, 0, len(fields))
+	for _, field := range fields {
+		if _, path := splitTranslationField(field.Column); path == "" {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// translationColumn returns the column of the translatable field with the given name. Struct field names are
+// matched first, columns are accepted as well like GORM does.
+func translationColumn(fields []translationField, name string) (string, bool) {
+	for _, field := range fields {
+		if field.Name == name {
+			return field.Column, true
+		}
+	}
+	for _, field := range fields {
+		if field.Column == name {
+			return field.Column, true
+		}
+	}
+	return "", false
+}
+
+// resolveTranslationNames returns a copy of the query whose filters and sorts of the translatable fields
+// are named by their columns (see translationColumn)
+func resolveTranslationNames(query *qapi.Query, fields []translationField) *qapi.Query {
+	if len(fields) == 0 {
+		return query
+	}
+	q := *query
+	q.Filter = make([]qapi.Filter, len(query.Filter))
+	for i, filter := range query.Filter {
+		name, suffix := filter.Name, ""
+		if strings.HasSuffix(name, MissingSuffix) {
+			name, suffix = strings.TrimSuffix(name, MissingSuffix), MissingSuffix
+		}
+		if column, ok := translationColumn(fields, name); ok {
+			filter.Name = column + suffix
+		}
+		q.Filter[i] = filter
+	}
+	q.Sort = make([]string, len(query.Sort))
+	for i, sort := range query.Sort {
+		name, direction, _ := strings.Cut(sort, " ")
+		if column, ok := translationColumn(fields, name); ok {
+			sort = strings.TrimSpace(column + " " + direction)
+		}
+		q.Sort[i] = sort
+	}
+	return &q
+}
+
+// splitTranslationField returns the column and the JSON path of the translatable field (see findTranslationFields)
+func splitTranslationField(name string) (string, string) {
+	if i := strings.IndexByte(name, '.'); i >= 0 {
+		return name[:i], name[i+1:]
+	}
+	return name, ""
+}
+
+// translatedColumn returns the select expression of the column with its translatable values in the first language
+// of the chain having a translation. keyed wraps the texts in objects keyed by the first language like Translations.
+func translatedColumn(name string, multiLangFields []string, langs []string, keyed bool) string {
+	text := func(path string) string {
+		t := translatedPath(safeMySQLNaming(name), path, langs)
+		if keyed {
+			return fmt.Sprintf("JSON_OBJECT('%s', %s)", langs[0], t)
+		}
+		return t
+	}
+	if contains(multiLangFields, name) {
+		return fmt.Sprintf("%s AS `%s`", text(""), name)
+	}
+	var sets []string
+	for _, field := range multiLangFields {
+		if column, path := splitTranslationField(field); column == name && path != "" {
+			sets = append(sets, fmt.Sprintf("'%s', %s", jsonPath(path, ""), text(path)))
+		}
+	}
+	if len(sets) == 0 {
+		return safeMySQLNaming(name)
+	}
+	return fmt.Sprintf("JSON_SET(%s, %s) AS `%s`", safeMySQLNaming(name), strings.Join(sets, ", "), name)
+}
+
+// jsonPath returns the JSON path of the dotted path and the language, both are optional
+func jsonPath(path string, lang string) string {
+	var sb strings.Builder
+	sb.WriteString("$")
+	if path != "" {
+		for _, key := range strings.Split(path, ".") {
+			sb.WriteString(".\"" + key + "\"")
+		}
+	}
+	if lang != "" {
+		sb.WriteString(".\"" + lang + "\"")
+	}
+	return sb.String()
+}
@@ -0,0 +1,117 @@
+package translations
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/filllabs/sincap-common/db/types"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type MockNamed struct {
+	Name *Translations
+}
+
+type MockSEO struct {
+	Title *Translations
+	Slug  string
+}
+
+type MockArticle struct {
+	ID uint
+	MockNamed
+	SEO        MockSEO    `gorm:"embedded;embeddedPrefix:SEO"`
+	Attributes types.JSON `translations:"seo.title, summary,bad path"`
+}
+
+func TestFindTranslationFields(t *testing.T) {
+	assert.Equal(t, []string{"Name", "SEOTitle", "Attributes.seo.title", "Attributes.summary"}, findTranslationFields(&[]MockArticle{}))
+	assert.Equal(t, []translationField{{Name: "Name", Column: "Name"}, {Name: "Title", Column: "SEOTitle"}}, translationColumns(translationFieldsOf(MockArticle{})))
+
+	columns := modelColumns(reflect.TypeOf(MockArticle{}))
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = column.Name
+	}
+	assert.Equal(t, []string{"ID", "Name", "SEOTitle", "SEOSlug", "Attributes"}, names)
+
+	selects := buildTranslatedSelectClause(reflect.TypeOf(MockArticle{}), findTranslationFields(MockArticle{}), []string{"tr-TR"})
+	assert.Equal(t, []string{
+		"`ID`",
+		"JSON_OBJECT('tr-TR', JSON_UNQUOTE(JSON_EXTRACT(`Name`, '$.\"tr-TR\"'))) AS `Name`",
+		"JSON_OBJECT('tr-TR', JSON_UNQUOTE(JSON_EXTRACT(`SEOTitle`, '$.\"tr-TR\"'))) AS `SEOTitle`",
+		"`SEOSlug`",
+		"JSON_SET(`Attributes`, " +
+			"'$.\"seo\".\"title\"', JSON_OBJECT('tr-TR', JSON_UNQUOTE(JSON_EXTRACT(`Attributes`, '$.\"seo\".\"title\".\"tr-TR\"'))), " +
+			"'$.\"summary\"', JSON_OBJECT('tr-TR', JSON_UNQUOTE(JSON_EXTRACT(`Attributes`, '$.\"summary\".\"tr-TR\"')))) AS `Attributes`",
+	}, selects)
+}
+
+type MockPage struct {
+	ID       uint
+	Headline *Translations `gorm:"column:Heading"`
+	Meta     MockSEO       `gorm:"embedded;embeddedPrefix:Meta"`
+}
+
+func TestTranslationFieldNames(t *testing.T) {
+	fields := translationFieldsOf(&MockPage{})
+	assert.Equal(t, []translationField{{Name: "Headline", Column: "Heading"}, {Name: "Title", Column: "MetaTitle"}}, fields)
+
+	DB := dbtest.Open(t)
+	DryDB := DB.Session(&gorm.Session{DryRun: true})
+	var sql string
+	capture := func(tx *gorm.DB) { sql = tx.Statement.SQL.String() }
+	assert.NoError(t, DB.Callback().Update().After("gorm:update").Register("test:capture", capture))
+	assert.NoError(t, DB.Callback().Query().After("gorm:query").Register("test:capture", capture))
+
+	assert.NoError(t, Update(DryDB, &MockPage{ID: 1}, map[string]any{"Headline": map[string]any{"de-DE": "Titel"}}))
+	assert.Contains(t, sql, "`Heading`=JSON_SET(COALESCE(`Heading`, JSON_OBJECT()), ?, ?)")
+	assert.NoError(t, Update(DryDB, &MockPage{ID: 1}, map[string]any{"MetaTitle": map[string]any{"de-DE": "Titel"}}))
+	assert.Contains(t, sql, "`MetaTitle`=JSON_SET(COALESCE(`MetaTitle`, JSON_OBJECT()), ?, ?)")
+
+	assert.NoError(t, RemoveLanguage(DryDB, &MockPage{ID: 1}, "de-DE"))
+	assert.Contains(t, sql, "`Heading`=JSON_REMOVE(COALESCE(`Heading`, JSON_OBJECT()), ?)")
+	assert.Contains(t, sql, "`MetaTitle`=JSON_REMOVE(COALESCE(`MetaTitle`, JSON_OBJECT()), ?)")
+	assert.NoError(t, RemoveLanguage(DryDB, &MockPage{ID: 1}, "de-DE", "Title"))
+	assert.Contains(t, sql, "`MetaTitle`=JSON_REMOVE(COALESCE(`MetaTitle`, JSON_OBJECT()), ?)")
+	assert.NotContains(t, sql, "`Heading`")
+	assert.Error(t, RemoveLanguage(DryDB, &MockPage{ID: 1}, "de-DE", "Slug"))
+
+	_, err := List(DryDB, &[]MockPage{}, &qapi.Query{
+		Filter: []qapi.Filter{{Name: "Headline", Operation: qapi.LK, Value: "go"}, {Name: "Title" + MissingSuffix, Operation: qapi.EQ, Value: "de-DE"}},
+		Sort:   []string{"Title desc"},
+	}, []string{"en-US"})
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "LOWER(JSON_UNQUOTE(JSON_EXTRACT(Heading, '$.\"en-US\"'))) LIKE LOWER(?)")
+	assert.Contains(t, sql, "TRIM(COALESCE(JSON_UNQUOTE(JSON_EXTRACT(`MockPage`.`MetaTitle`, '$.\"de-DE\"')), '')) = ''")
+	assert.Contains(t, sql, "ORDER BY LOWER(JSON_UNQUOTE(JSON_EXTRACT(MetaTitle, '$.\"en-US\"'))) desc")
+}
+
+func TestListNestedTranslations(t *testing.T) {
+	DB := dbtest.Open(t)
+	DryDB := DB.Session(&gorm.Session{DryRun: true})
+	var sql string
+	assert.NoError(t, DB.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		sql = tx.Statement.SQL.String()
+	}))
+
+	_, err := List(DryDB, &[]MockArticle{}, &qapi.Query{
+		Filter: []qapi.Filter{{Name: "Attributes.seo.title", Operation: qapi.LK, Value: "go"}},
+		Sort:   []string{"SEOTitle desc"},
+		Q:      "x",
+	}, []string{"en-US"})
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "LOWER(JSON_UNQUOTE(JSON_EXTRACT(Attributes, '$.\"seo\".\"title\".\"en-US\"'))) LIKE LOWER(?)")
+	assert.Contains(t, sql, "LOWER(JSON_UNQUOTE(JSON_EXTRACT(`Attributes`, '$.\"summary\".\"en-US\"'))) LIKE LOWER(?)")
+	assert.Contains(t, sql, "LOWER(JSON_UNQUOTE(JSON_EXTRACT(`SEOTitle`, '$.\"en-US\"'))) LIKE LOWER(?)")
+	assert.Contains(t, sql, "ORDER BY LOWER(JSON_UNQUOTE(JSON_EXTRACT(SEOTitle, '$.\"en-US\"'))) desc")
+	assert.NotContains(t, sql, "SELECT `AttributesID`")
+
+	_, err = List(DryDB, &[]MockArticle{}, &qapi.Query{Sort: []string{"Attributes.summary"}, Fields: []string{"ID", "Attributes"}}, []string{"en-US"})
+	assert.NoError(t, err)
+	assert.Contains(t, sql, "SELECT `ID`, JSON_SET(`Attributes`, '$.\"seo\".\"title\"', JSON_UNQUOTE(")
+	assert.Contains(t, sql, "ORDER BY LOWER(JSON_UNQUOTE(JSON_EXTRACT(Attributes, '$.\"summary\".\"en-US\"'))) ASC")
+}
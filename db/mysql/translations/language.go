@@ -0,0 +1,137 @@
+package translations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"github.com/filllabs/sincap-common/events"
+	"gorm.io/gorm"
+)
+
+// Directions of the languages
+const (
+	LTR = "ltr"
+	RTL = "rtl"
+)
+
+var (
+	// ErrInvalidDirection is returned for the directions other than LTR and RTL
+	ErrInvalidDirection = errors.New("translations: invalid direction")
+	// ErrInvalidCollation is returned for the collations which are not plain names
+	ErrInvalidCollation = errors.New("translations: invalid collation")
+)
+
+// Language is a row of the Language table which lists the languages of the translations.
+// Disabled languages are not negotiated and the default one replaces DEFAULT_LANG_CODE (see DefaultLanguage).
+// Use it with the migrations of the application (e.g. db.AutoMigrate(&translations.Language{})).
+type Language struct {
+	Code       string `gorm:"primaryKey;size:35"`
+	Name       string `gorm:"size:64"`
+	NativeName string `gorm:"size:64"`
+	Direction  string `gorm:"size:3;default:ltr"`
+	// Fallbacks holds the codes to read when a translation is missing, in order and joined with "," (e.g. "de-DE,en-US")
+	Fallbacks string
+	// Enabled is true if it is not given (nil) and NULL for the rows created before the column, they are enabled
+	Enabled *bool `gorm:"default:true"`
+	Default bool
+	// Collation is used to sort and search the translations in the language (e.g. utf8mb4_tr_0900_ai_ci), see Collation
+	Collation string `gorm:"size:64"`
+}
+
+// IsEnabled reports whether the language is negotiated, languages are enabled unless Enabled is false
+func (l *Language) IsEnabled() bool {
+	return l.Enabled == nil || *l.Enabled
+}
+
+// TableName returns the name of the Language table
+func (Language) TableName() string {
+	return "Language"
+}
+
+// Validate checks the code, the direction, the collation and the fallbacks of the language
+func (l *Language) Validate() error {
+	if !langCodePattern.MatchString(l.Code) {
+		return ErrInvalidLanguage
+	}
+	if l.Direction != "" && l.Direction != LTR && l.Direction != RTL {
+		return ErrInvalidDirection
+	}
+	if l.Collation != "" && !collationPattern.MatchString(l.Collation) {
+		return ErrInvalidCollation
+	}
+	for _, fallback := range strings.Split(l.Fallbacks, ",") {
+		fallback = strings.TrimSpace(fallback)
+		if fallback == "" {
+			continue
+		}
+		if !langCodePattern.MatchString(fallback) || strings.EqualFold(fallback, l.Code) {
+			return fmt.Errorf("%w: invalid fallback %s", ErrInvalidLanguage, fallback)
+		}
+	}
+	return nil
+}
+
+// defaultLangCode is the default language set by SetDefaultLanguage
+var defaultLangCode atomic.Value
+
+// DefaultLanguage returns the code of the default language of the Language table if the languages are loaded,
+// the one set by SetDefaultLanguage or DEFAULT_LANG_CODE.
+func DefaultLanguage() string {
+	if value, found := langCodesCACHE.Get("languages"); found {
+		for _, l := range value.([]language) {
+			if l.Default {
+				return l.Code
+			}
+		}
+	}
+	if code, ok := defaultLangCode.Load().(string); ok {
+		return code
+	}
+	return DEFAULT_LANG_CODE
+}
+
+// SetDefaultLanguage sets the default language of the application (e.g. from the config).
+// The default row of the Language table has priority over it.
+func SetDefaultLanguage(code string) error {
+	if !langCodePattern.MatchString(code) {
+		return ErrInvalidLanguage
+	}
+	defaultLangCode.Store(code)
+	return nil
+}
+
+// InvalidateLanguages drops the cached languages, so they are read from the Language table again
+func InvalidateLanguages() {
+	langCodesCACHE.Delete("languages")
+}
+
+// InvalidateOnChange invalidates the cached languages on every change of the Language table published to the bus
+// (see services.GormService.WithEvents). Returns the unsubscribe function.
+func InvalidateOnChange(bus *events.Bus) func() {
+	return bus.Subscribe(Language{}.TableName(), func(_ context.Context, _ events.Event) error {
+		InvalidateLanguages()
+		return nil
+	})
+}
+
+// SetDefault makes the language the only default one of the Language table and invalidates the cached languages
+func SetDefault(DB *gorm.DB, code string) error {
+	if !langCodePattern.MatchString(code) {
+		return ErrInvalidLanguage
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Language{}).Where("`Code` = ?", code).Update("Default", true)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return tx.Model(&Language{}).Where("`Code` <> ? AND `Default` = ?", code, true).Update("Default", false).Error
+	})
+	InvalidateLanguages()
+	return err
+}
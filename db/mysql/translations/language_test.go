@@ -0,0 +1,52 @@
+package translations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filllabs/sincap-common/events"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestLanguages(t *testing.T) {
+	DB := dbtest.Open(t)
+	assert.NoError(t, DB.Exec("CREATE TABLE `Language` (`Code` TEXT PRIMARY KEY)").Error)
+	assert.NoError(t, DB.Exec("INSERT INTO `Language` VALUES ('en-US'), ('tr-TR')").Error)
+	// the columns added later are optional for the old rows
+	assert.NoError(t, DB.AutoMigrate(&Language{}))
+	disabled := false
+	assert.NoError(t, DB.Create(&Language{Code: "ar-SA", Direction: RTL, Enabled: &disabled}).Error)
+	// languages are enabled by default
+	assert.NoError(t, DB.Create(&Language{Code: "fr-FR"}).Error)
+	var fr Language
+	assert.NoError(t, DB.First(&fr, "`Code` = ?", "fr-FR").Error)
+	assert.True(t, fr.IsEnabled())
+	assert.NoError(t, DB.Delete(&fr).Error)
+	langCodesCACHE.Flush()
+	defer langCodesCACHE.Flush()
+
+	codes, err := ListCodes(DB)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en-US", "tr-TR"}, codes)
+	assert.Equal(t, DEFAULT_LANG_CODE, DefaultLanguage())
+
+	bus := events.NewBus()
+	unsubscribe := InvalidateOnChange(bus)
+	defer unsubscribe()
+	assert.NoError(t, DB.Model(&Language{}).Where("`Code` = ?", "ar-SA").Update("Enabled", true).Error)
+	assert.NoError(t, SetDefault(DB, "tr-TR"))
+	bus.Publish(context.Background(), events.Event{Type: events.Updated, Entity: "Language", ID: "ar-SA"})
+
+	codes, err = ListCodes(DB)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en-US", "tr-TR", "ar-SA"}, codes)
+	assert.Equal(t, "tr-TR", DefaultLanguage())
+	assert.Equal(t, "tr-TR", Negotiate("fr-FR", codes))
+	assert.ErrorIs(t, SetDefault(DB, "fr-FR"), gorm.ErrRecordNotFound)
+
+	assert.NoError(t, (&Language{Code: "de-AT", Fallbacks: "de-DE, en-US"}).Validate())
+	assert.ErrorIs(t, (&Language{Code: "de-AT", Fallbacks: "de'DE"}).Validate(), ErrInvalidLanguage)
+	assert.ErrorIs(t, (&Language{Code: "de-AT", Direction: "ttb"}).Validate(), ErrInvalidDirection)
+}
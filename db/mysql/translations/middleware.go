@@ -13,23 +13,12 @@ type contextKey string
 const LANG_KEY contextKey = "language"
 const fiberCtxKey contextKey = "fiberCtx"
 
+// TranslationMiddleware negotiates the language of the request from the Accept-Language header (see Negotiate)
+// and puts it to the locals and the user context.
 func TranslationMiddleware(c *fiber.Ctx) error {
-	lang := c.Get("Accept-Language")
-
-	langCodes, err := ListCodes(db.DB())
-	if err != nil {
-		lang = DEFAULT_LANG_CODE
-	} else {
-		found := false
-		for _, langCode := range langCodes {
-			if lang == langCode {
-				found = true
-				break
-			}
-		}
-		if !found {
-			lang = DEFAULT_LANG_CODE
-		}
+	lang := DefaultLanguage()
+	if langCodes, err := ListCodes(db.DB()); err == nil {
+		lang = Negotiate(c.Get("Accept-Language"), langCodes)
 	}
 
 	c.Locals("lang", lang)
@@ -48,6 +37,19 @@ func GetFiberCtx(ctx context.Context) (*fiber.Ctx, error) {
 	return fiberCtx, nil
 }
 
+// LanguageFromContext returns the language of the context set by TranslationMiddleware, false if there is none
+func LanguageFromContext(ctx context.Context) (string, bool) {
+	if lang, ok := ctx.Value(LANG_KEY).(string); ok && lang != "" {
+		return lang, true
+	}
+	if fiberCtx, err := GetFiberCtx(ctx); err == nil {
+		if lang, ok := fiberCtx.Locals("lang").(string); ok && lang != "" {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
 // GetLanguage now directly checks the context first before falling back to Fiber context
 func GetLanguage(ctx context.Context) string {
 	if lang, ok := ctx.Value(LANG_KEY).(string); ok && lang != "" {
@@ -55,11 +57,11 @@ func GetLanguage(ctx context.Context) string {
 	}
 	fiberCtx, err := GetFiberCtx(ctx)
 	if err != nil {
-		return DEFAULT_LANG_CODE
+		return DefaultLanguage()
 	}
 	lang, ok := fiberCtx.Locals("lang").(string)
 	if !ok || lang == "" {
-		return DEFAULT_LANG_CODE
+		return DefaultLanguage()
 	}
 	return lang
 }
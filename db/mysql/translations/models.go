@@ -6,6 +6,7 @@ import (
 	"errors"
 )
 
+// DEFAULT_LANG_CODE is the built-in default language, see DefaultLanguage for the configured one
 const DEFAULT_LANG_CODE = "en-US"
 
 // Translations is a type alias for a map of string to string. It is used to store translations for specific fields in all languages.
@@ -26,6 +27,15 @@ func (t *Translations) Set(key, value string) {
 	t.data[key] = value
 }
 
+// Map returns a copy of the translations by the language codes.
+func (t *Translations) Map() map[string]string {
+	m := make(map[string]string, len(t.data))
+	for lang, text := range t.data {
+		m[lang] = text
+	}
+	return m
+}
+
 // MarshalJSON is a custom JSON marshaller for the Translations type.
 func (t *Translations) MarshalJSON() ([]byte, error) {
 	return json.Marshal(t.data)
@@ -54,15 +64,20 @@ func (t *Translations) Unmarshal(data []byte) error {
 
 // Scan implements the sql.Scanner interface for reading from the database.
 func (t *Translations) Scan(value any) error {
-	bytes, ok := value.([]byte)
-	if !ok {
-		return errors.New("unsupported type for Translations")
+	switch v := value.(type) {
+	case []byte:
+		return t.Unmarshal(v)
+	case string:
+		// e.g. sqlite
+		return t.Unmarshal([]byte(v))
 	}
-
-	return t.Unmarshal(bytes)
+	return errors.New("unsupported type for Translations")
 }
 
 // Value implements the driver.Valuer interface for writing to the database.
 func (t *Translations) Value() (driver.Value, error) {
+	if t == nil {
+		return nil, nil
+	}
 	return t.Marshal()
 }
@@ -0,0 +1,100 @@
+package translations
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ParseAcceptLanguage returns the language tags of the Accept-Language header ordered by their q-values.
+// Tags with q=0 or an invalid q-value are dropped, tags with the same q-value keep the header order.
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		params := strings.Split(part, ";")
+		tag := strings.TrimSpace(params[0])
+		if tag == "" {
+			continue
+		}
+		q := 1.0
+		valid := true
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") {
+				continue
+			}
+			value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err != nil || value < 0 || value > 1 {
+				valid = false
+				break
+			}
+			q = value
+		}
+		if valid && q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+	result := make([]string, len(tags))
+	for i, t := range tags {
+		result[i] = t.tag
+	}
+	return result
+}
+
+// Negotiate returns the code of the best language for the Accept-Language header, the default language (see DefaultLanguage) if none match.
+// Tags are matched in the order of their q-values, first exactly and then by their base language (de-AT → de-DE).
+// Base matches prefer the code of the base's own region (de → de-DE), then the default language, then the first code.
+func Negotiate(header string, codes []string) string {
+	defaultCode := DefaultLanguage()
+	for _, tag := range ParseAcceptLanguage(header) {
+		if tag == "*" {
+			return defaultCode
+		}
+		for _, code := range codes {
+			if strings.EqualFold(tag, code) {
+				return code
+			}
+		}
+		if code := matchBase(baseOf(tag), codes, defaultCode); code != "" {
+			return code
+		}
+	}
+	return defaultCode
+}
+
+func matchBase(base string, codes []string, defaultLang string) string {
+	var first, defaultCode string
+	for _, code := range codes {
+		if !strings.EqualFold(baseOf(code), base) {
+			continue
+		}
+		if strings.EqualFold(code, base+"-"+base) {
+			return code
+		}
+		if first == "" {
+			first = code
+		}
+		if code == defaultLang {
+			defaultCode = code
+		}
+	}
+	if defaultCode != "" {
+		return defaultCode
+	}
+	return first
+}
+
+// baseOf returns the base language of the tag (e.g. "de" for "de-AT")
+func baseOf(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
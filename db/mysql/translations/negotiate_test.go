@@ -0,0 +1,68 @@
+package translations
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"de-AT", "de", "en"}, ParseAcceptLanguage("de-AT,de;q=0.9,en;q=0.8"))
+	assert.Equal(t, []string{"tr", "en", "fr"}, ParseAcceptLanguage("en;q=0.5, tr, fr;q=0.5, it;q=0, es;q=x"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+}
+
+func TestNegotiate(t *testing.T) {
+	codes := []string{"en-US", "en-GB", "de-DE", "tr-TR"}
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"de-AT,de;q=0.9,en;q=0.8", "de-DE"},
+		{"en-gb", "en-GB"},
+		{"en", "en-US"},
+		{"fr-FR,tr;q=0.5", "tr-TR"},
+		{"fr-FR", DEFAULT_LANG_CODE},
+		{"*", DEFAULT_LANG_CODE},
+		{"", DEFAULT_LANG_CODE},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, Negotiate(tt.header, codes), tt.header)
+	}
+}
+
+func TestFallbacks(t *testing.T) {
+	DB := dbtest.Open(t)
+	assert.NoError(t, DB.Exec("CREATE TABLE `Language` (`code` TEXT, `fallbacks` TEXT)").Error)
+	assert.NoError(t, DB.Exec("INSERT INTO `Language` VALUES ('en-US', NULL), ('de-DE', NULL), ('de-AT', NULL), ('de-CH', 'de-AT,de-DE')").Error)
+	langCodesCACHE.Flush()
+	defer langCodesCACHE.Flush()
+
+	assert.Equal(t, []string{"de-AT", "de-DE", "de-CH", "en-US"}, Fallbacks(DB, "de-AT"))
+	assert.Equal(t, []string{"de-CH", "de-AT", "de-DE", "en-US"}, Fallbacks(DB, "de-CH"))
+	assert.Equal(t, []string{"en-US"}, Fallbacks(DB, "en-US"))
+	assert.Equal(t, []string{"tr-TR", "en-US"}, Fallbacks(DB, "tr-TR", "en-US", "tr-TR", "x'y"))
+	assert.Equal(t, []string{"all"}, Fallbacks(DB, "all"))
+
+	codes, err := ListCodes(DB)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en-US", "de-DE", "de-AT", "de-CH"}, codes)
+}
+
+type MockCountry struct {
+	ID   uint
+	Code string
+	Name *Translations
+}
+
+func TestTranslatedSelect(t *testing.T) {
+	selects := buildTranslatedSelectClause(reflect.TypeOf(MockCountry{}), []string{"Name"}, []string{"de-AT", "en-US"})
+	assert.Equal(t, []string{
+		"`ID`",
+		"`Code`",
+		"JSON_OBJECT('de-AT', COALESCE(JSON_UNQUOTE(JSON_EXTRACT(`Name`, '$.\"de-AT\"')), JSON_UNQUOTE(JSON_EXTRACT(`Name`, '$.\"en-US\"')))) AS `Name`",
+	}, selects)
+	assert.Equal(t, []string{"`ID`", "`Code`", "`Name`"}, buildTranslatedSelectClause(reflect.TypeOf(MockCountry{}), []string{"Name"}, []string{"all"}))
+}
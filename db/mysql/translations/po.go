@@ -0,0 +1,119 @@
+package translations
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writePO writes the units as gettext PO entries with the contexts "{Entity}/{ID}/{Field}"
+func writePO(w io.Writer, source string, target string, units []Unit) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "msgid \"\"\nmsgstr \"\"\n%s\n%s\n%s\n",
+		poQuote("Content-Type: text/plain; charset=UTF-8\n"),
+		poQuote("Language: "+target+"\n"),
+		poQuote("X-Source-Language: "+source+"\n"))
+	for _, u := range units {
+		fmt.Fprintf(bw, "\nmsgctxt %s\nmsgid %s\nmsgstr %s\n", poQuote(u.key()), poQuote(u.Source), poQuote(u.Target))
+	}
+	return bw.Flush()
+}
+
+func readPO(r io.Reader) (string, string, []Unit, error) {
+	var source, target string
+	var units []Unit
+	var entry map[string]string
+	var keyword string
+	flush := func() error {
+		if entry == nil {
+			return nil
+		}
+		ctx, hasCtx := entry["msgctxt"]
+		if !hasCtx && entry["msgid"] == "" {
+			// header
+			for _, line := range strings.Split(entry["msgstr"], "\n") {
+				name, value, _ := strings.Cut(line, ":")
+				switch strings.TrimSpace(name) {
+				case "Language":
+					target = strings.TrimSpace(value)
+				case "X-Source-Language":
+					source = strings.TrimSpace(value)
+				}
+			}
+		} else {
+			u, ok := parseKey(ctx)
+			if !ok {
+				return fmt.Errorf("%w: invalid msgctxt %q", ErrInvalidFile, ctx)
+			}
+			u.Source, u.Target = entry["msgid"], entry["msgstr"]
+			units = append(units, u)
+		}
+		entry = nil
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, `"`):
+			if entry == nil {
+				return "", "", nil, fmt.Errorf("%w: unexpected string at line %d", ErrInvalidFile, n)
+			}
+			s, err := strconv.Unquote(line)
+			if err != nil {
+				return "", "", nil, fmt.Errorf("%w: invalid string at line %d", ErrInvalidFile, n)
+			}
+			entry[keyword] += s
+			continue
+		}
+		name, value, _ := strings.Cut(line, " ")
+		if name == "msgstr[0]" {
+			name = "msgstr"
+		}
+		switch name {
+		case "msgctxt", "msgid", "msgstr":
+		default:
+			// plurals are not used by the exchange
+			keyword = "ignored"
+			continue
+		}
+		if name == "msgctxt" || (name == "msgid" && (entry == nil || hasKey(entry, "msgid"))) {
+			if err := flush(); err != nil {
+				return "", "", nil, err
+			}
+			entry = map[string]string{}
+		}
+		if entry == nil {
+			return "", "", nil, fmt.Errorf("%w: unexpected %s at line %d", ErrInvalidFile, name, n)
+		}
+		s, err := strconv.Unquote(strings.TrimSpace(value))
+		if err != nil {
+			return "", "", nil, fmt.Errorf("%w: invalid string at line %d", ErrInvalidFile, n)
+		}
+		keyword = name
+		entry[keyword] = s
+	}
+	if err := scanner.Err(); err != nil {
+		return "", "", nil, err
+	}
+	if err := flush(); err != nil {
+		return "", "", nil, err
+	}
+	return source, target, units, nil
+}
+
+func hasKey(m map[string]string, key string) bool {
+	_, ok := m[key]
+	return ok
+}
+
+// poQuote quotes the string with the C escapes of gettext
+func poQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+	return `"` + r.Replace(s) + `"`
+}
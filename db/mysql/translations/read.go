@@ -0,0 +1,34 @@
+package translations
+
+import (
+	"github.com/filllabs/sincap-common/db/mysql"
+	"github.com/filllabs/sincap-common/db/queryapi"
+	"gorm.io/gorm"
+)
+
+// Read reads the record with the given id like mysql.Read and projects its Translations fields and the ones of
+// its preloads to the languages the same way List does. lang is the fallback chain of the languages (see Fallbacks).
+// Records without Translations fields in themselves or their preloads are read by mysql.Read as is.
+func Read(DB *gorm.DB, record any, id any, lang []string, preloads ...string) error {
+	langs := Fallbacks(DB, lang...)
+	multiLangFields := findTranslationFields(record)
+	if len(langs) == 0 || !hasTranslations(record, multiLangFields, preloads) {
+		return mysql.Read(DB, record, id, preloads...)
+	}
+	entityType, _ := queryapi.GetTableName(record)
+	db := addPreloads(DB, preloads, langs, entityType)
+	if len(multiLangFields) > 0 {
+		db = db.Select(buildTranslatedSelectClause(entityType, multiLangFields, langs))
+	}
+	return mysql.Read(db, record, id)
+}
+
+// hasTranslations reports whether the record or any of the preloads has Translations fields
+func hasTranslations(record any, multiLangFields []string, preloads []string) bool {
+	if len(multiLangFields) > 0 {
+		return true
+	}
+	entityType, _ := queryapi.GetTableName(record)
+	nested, _ := findNestedTranslationFields(preloads, entityType)
+	return len(nested) > 0
+}
@@ -0,0 +1,35 @@
+package translations
+
+import (
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type MockCity struct {
+	ID        uint
+	Name      *Translations
+	CountryID uint
+	Country   *MockCountry
+}
+
+func TestRead(t *testing.T) {
+	DB := dbtest.Open(t).Session(&gorm.Session{DryRun: true})
+	var queries []string
+	assert.NoError(t, DB.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		queries = append(queries, tx.Statement.SQL.String())
+	}))
+
+	_ = Read(DB, &MockCity{}, 3, []string{"tr-TR", "en-US"}, "Country")
+	assert.NotEmpty(t, queries)
+	assert.Contains(t, queries[0], "JSON_OBJECT('tr-TR', COALESCE(JSON_UNQUOTE(JSON_EXTRACT(`Name`, '$.\"tr-TR\"')), JSON_UNQUOTE(JSON_EXTRACT(`Name`, '$.\"en-US\"')))) AS `Name`")
+	assert.Contains(t, queries[0], "`MockCity`.`ID` = ?")
+
+	// records without translations are read as is
+	queries = nil
+	_ = Read(DB, &MockUser{}, 3, []string{"tr-TR"})
+	assert.NotEmpty(t, queries)
+	assert.NotContains(t, queries[0], "JSON")
+}
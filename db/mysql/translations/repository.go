@@ -1,6 +1,8 @@
 package translations
 
 import (
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/patrickmn/go-cache"
@@ -9,16 +11,140 @@ import (
 
 var langCodesCACHE = cache.New(5*time.Minute, 10*time.Minute)
 
+// langCodePattern validates the codes which are written into the JSON paths
+var langCodePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// language is an enabled row of the Language table (see Language) with its fallbacks split
+type language struct {
+	Code      string
+	Fallbacks []string
+	Default   bool
+	Collation string
+}
+
 func ListCodes(db *gorm.DB) ([]string, error) {
-	value, found := langCodesCACHE.Get("langCodes")
-	if found {
-		return value.([]string), nil
-	}
-	var codes []string
-	err := db.Table("Language").Pluck("code", &codes).Error
+	languages, err := listLanguages(db)
 	if err != nil {
 		return nil, err
 	}
-	langCodesCACHE.Set("langCodes", codes, 5*time.Minute)
+	codes := make([]string, len(languages))
+	for i, l := range languages {
+		codes[i] = l.Code
+	}
 	return codes, nil
 }
+
+// listLanguages reads the enabled languages with their fallbacks, all columns except code are optional
+func listLanguages(db *gorm.DB) ([]language, error) {
+	value, found := langCodesCACHE.Get("languages")
+	if found {
+		return value.([]language), nil
+	}
+	var rows []map[string]any
+	if err := db.Table("Language").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	languages := make([]language, 0, len(rows))
+	for _, row := range rows {
+		code := text(rowValue(row, "Code"))
+		if code == "" {
+			continue
+		}
+		if enabled, ok := flag(rowValue(row, "Enabled")); ok && !enabled {
+			continue
+		}
+		l := language{Code: code}
+		l.Default, _ = flag(rowValue(row, "Default"))
+		l.Collation = text(rowValue(row, "Collation"))
+		for _, fallback := range strings.Split(text(rowValue(row, "Fallbacks")), ",") {
+			if fallback = strings.TrimSpace(fallback); fallback != "" {
+				l.Fallbacks = append(l.Fallbacks, fallback)
+			}
+		}
+		languages = append(languages, l)
+	}
+	langCodesCACHE.Set("languages", languages, 5*time.Minute)
+	return languages, nil
+}
+
+// Fallbacks returns the chain of the languages to read the translations from. A single language is followed by
+// its fallbacks on the Language table, or the languages with the same base (de-AT → de-DE) if it has none,
+// and the default language (see DefaultLanguage). Multiple languages are used as the chain itself. Invalid codes and duplicates are dropped.
+func Fallbacks(db *gorm.DB, lang ...string) []string {
+	chain := lang
+	if len(lang) == 1 && lang[0] != "" && lang[0] != "all" {
+		chain = append([]string{lang[0]}, implicitFallbacks(db, lang[0])...)
+		chain = append(chain, DefaultLanguage())
+	}
+	result := make([]string, 0, len(chain))
+	seen := map[string]bool{}
+	for _, code := range chain {
+		if seen[code] || !langCodePattern.MatchString(code) {
+			continue
+		}
+		seen[code] = true
+		result = append(result, code)
+	}
+	return result
+}
+
+func implicitFallbacks(db *gorm.DB, code string) []string {
+	if db == nil {
+		return nil
+	}
+	languages, err := listLanguages(db)
+	if err != nil {
+		return nil
+	}
+	var sameBase []string
+	for _, l := range languages {
+		if strings.EqualFold(l.Code, code) {
+			if len(l.Fallbacks) > 0 {
+				return l.Fallbacks
+			}
+			continue
+		}
+		if strings.EqualFold(baseOf(l.Code), baseOf(code)) {
+			sameBase = append(sameBase, l.Code)
+		}
+	}
+	return sameBase
+}
+
+func text(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	}
+	return ""
+}
+
+// flag reads the boolean columns which are numbers on MySQL and SQLite, false if the column is missing or NULL
+func flag(value any) (bool, bool) {
+	switch v := value.(type) {
+	case bool:
+		return v, true
+	case int64:
+		return v != 0, true
+	case float64:
+		return v != 0, true
+	case []byte:
+		return len(v) > 0 && string(v) != "0", true
+	}
+	return false, false
+}
+
+// rowValue returns the column of the row case insensitively like MySQL
+func rowValue(row map[string]any, name string) any {
+	if value, ok := row[name]; ok {
+		return value
+	}
+	for column, value := range row {
+		if strings.EqualFold(column, name) {
+			return value
+		}
+	}
+	return nil
+}
@@ -18,8 +18,17 @@ import (
 )
 
 // List retrieves records from the database based on query parameters with enhanced support for translations
+// lang is the fallback chain of the languages, translations of the first language having one are selected.
+// A single language is expanded with its fallbacks (see Fallbacks).
+// Translations fields of the embedded structs and the translations paths of the types.JSON fields are translated
+// too, the paths are filtered, sorted and searched as <Field>.<path> (e.g. Attributes.seo.title). Translatable fields
+// are filtered and sorted by the names of their struct fields or by their columns.
 func List(DB *gorm.DB, records any, query *qapi.Query, lang []string) (int, error) {
-	langCode := lang[0]
+	langs := Fallbacks(DB, lang...)
+	langCode := ""
+	if len(langs) > 0 {
+		langCode = langs[0]
+	}
 
 	value := reflect.ValueOf(records)
 	if value.Kind() != reflect.Pointer {
@@ -41,12 +50,26 @@ func List(DB *gorm.DB, records any, query *qapi.Query, lang []string) (int, erro
 	// Check if we need to handle translations
 	useTranslations := len(langCode) > 0 && langCode != ""
 	multiLangFields := findTranslationFields(records)
+	query = resolveTranslationNames(query, translationFieldsOf(records))
+
+	// Sort and search in the collation of the language
+	collation := ""
+	if useTranslations {
+		collation = Collation(DB, getLanguagePath(langs)[0])
+	}
+
+	// Filter the records missing translations (e.g. Name.@missing=ar-SA)
+	query, missing, err := splitMissingFilters(query, tableName, multiLangFields)
+	if err != nil {
+		return 0, err
+	}
+	for _, condition := range missing {
+		db = db.Where(condition)
+	}
 
 	// Build query with or without translations
-	var err error
 	if useTranslations && (len(multiLangFields) > 0 || len(query.Preloads) > 0) {
-		path := getLanguagePath(langCode)
-		db, err = generateTranslatedDB(db, query, path, entityType, multiLangFields, tableName)
+		db, err = generateTranslatedDB(db, query, getLanguagePath(langs), collation, entityType, multiLangFields, tableName)
 	} else {
 		db, err = queryapi.GenerateDB(query, db, records)
 	}
@@ -57,7 +80,7 @@ func List(DB *gorm.DB, records any, query *qapi.Query, lang []string) (int, erro
 
 	// Add Q parameter search support
 	if len(query.Q) > 0 && useTranslations && len(multiLangFields) > 0 {
-		db = addQSearch(db, query.Q, langCode, multiLangFields)
+		db = addQSearch(db, query.Q, getLanguagePath(langs), collation, multiLangFields)
 	} else if len(query.Q) > 0 {
 		where, values, err := q2Sql(query.Q, entityType, tableName)
 		if err != nil {
@@ -73,10 +96,10 @@ func List(DB *gorm.DB, records any, query *qapi.Query, lang []string) (int, erro
 	}
 
 	// Add preloads with enhanced translation support
-	db = addPreloads(db, query.Preloads, langCode, entityType)
+	db = addPreloads(db, query.Preloads, langs, entityType)
 
 	// Build optimized select clause for specific fields or translations
-	db = buildOptimizedSelectClause(db, query, langCode, entityType, multiLangFields)
+	db = buildOptimizedSelectClause(db, query, langs, entityType, multiLangFields)
 
 	// Execute the query
 	result := db.Find(records)
@@ -91,16 +114,15 @@ func List(DB *gorm.DB, records any, query *qapi.Query, lang []string) (int, erro
 }
 
 // addQSearch performs a search across all translation fields for the given query string
-func addQSearch(db *gorm.DB, query string, langCode string, multiLangFields []string) *gorm.DB {
+func addQSearch(db *gorm.DB, query string, langs []string, collation string, multiLangFields []string) *gorm.DB {
 	var conditions []string
 	var values []interface{}
 
 	// Search in translation fields
 	for _, field := range multiLangFields {
-		jsonPath := fmt.Sprintf("$.\"%s\"", langCode)
+		column, path := splitTranslationField(field)
 		conditions = append(conditions,
-			fmt.Sprintf("LOWER(JSON_UNQUOTE(JSON_EXTRACT(`%s`, '%s'))) LIKE LOWER(?)",
-				field, jsonPath))
+			collate(fmt.Sprintf("LOWER(%s)", translatedPath("`"+column+"`", path, langs)), collation)+" LIKE LOWER(?)")
 		values = append(values, "%"+query+"%")
 	}
 
@@ -126,27 +148,27 @@ func handlePagination(db *gorm.DB, calculateCount bool, query *qapi.Query) (int,
 }
 
 // generateTranslatedDB handles the complex logic for queries with translations
-func generateTranslatedDB(db *gorm.DB, query *qapi.Query, langCode string,
+func generateTranslatedDB(db *gorm.DB, query *qapi.Query, langs []string, collation string,
 	entityType reflect.Type, multiLangFields []string, tableName string) (*gorm.DB, error) {
 
 	// Find translation fields in preloaded models
 	nestedMultiLangFields, m2mFields := findNestedTranslationFields(query.Preloads, entityType)
 
 	// Handle sorting with translations
-	db = handleTranslatedSorting(db, query, langCode, multiLangFields, nestedMultiLangFields, tableName, entityType)
+	db = handleTranslatedSorting(db, query, langs, collation, multiLangFields, nestedMultiLangFields, tableName, entityType)
 
 	// Handle one-to-many relationship filters
-	db = handleTranslatedOneToManyFilter(db, query, entityType)
+	db = handleTranslatedOneToManyFilter(db, query, entityType, multiLangFields)
 
 	// Handle filters with translations
-	db = handleTranslatedFilters(db, query, langCode, entityType, multiLangFields,
+	db = handleTranslatedFilters(db, query, langs, collation, entityType, multiLangFields,
 		nestedMultiLangFields, m2mFields)
 
 	return db, nil
 }
 
 // handleTranslatedSorting applies sorting with translation field awareness
-func handleTranslatedSorting(db *gorm.DB, query *qapi.Query, langCode string,
+func handleTranslatedSorting(db *gorm.DB, query *qapi.Query, langs []string, collation string,
 	multiLangFields []string, nestedMultiLangFields map[string][]string,
 	tableName string, entityType reflect.Type) *gorm.DB {
 
@@ -157,7 +179,7 @@ func handleTranslatedSorting(db *gorm.DB, query *qapi.Query, langCode string,
 	for _, sortClause := range query.Sort {
 		handled := false
 
-		if strings.Contains(sortClause, ".") {
+		if strings.Contains(sortClause, ".") && !contains(multiLangFields, strings.Split(sortClause, " ")[0]) {
 			// Handle sorting on related model fields
 			parts := strings.SplitN(sortClause, ".", 2)
 			relation := parts[0]
@@ -171,11 +193,11 @@ func handleTranslatedSorting(db *gorm.DB, query *qapi.Query, langCode string,
 			if fields, exists := nestedMultiLangFields[relation]; exists {
 				for _, multiLangField := range fields {
 					if fieldName == multiLangField {
-						jsonPath := fmt.Sprintf("$.\"%s\"", langCode)
+						column, path := splitTranslationField(fieldName)
 						db = db.Joins(fmt.Sprintf("JOIN %s ON %s.ID = %sID",
 							relation, relation, strings.ToLower(relation))).
-							Order(fmt.Sprintf("LOWER(JSON_UNQUOTE(JSON_EXTRACT(%s.%s, '%s'))) %s",
-								relation, fieldName, jsonPath, sortDirection))
+							Order(collate(fmt.Sprintf("LOWER(%s)", translatedPath(relation+"."+column, path, langs)), collation) +
+								" " + sortDirection)
 						handled = true
 						break
 					}
@@ -194,9 +216,9 @@ func handleTranslatedSorting(db *gorm.DB, query *qapi.Query, langCode string,
 			for _, tf := range multiLangFields {
 				if field == tf {
 					isTranslationField = true
-					jsonPath := fmt.Sprintf("$.\"%s\"", langCode)
-					db = db.Order(fmt.Sprintf("LOWER(JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))) %s",
-						field, jsonPath, sortDirection))
+					column, path := splitTranslationField(field)
+					db = db.Order(collate(fmt.Sprintf("LOWER(%s)", translatedPath(column, path, langs)), collation) +
+						" " + sortDirection)
 					handled = true
 					break
 				}
@@ -246,7 +268,7 @@ func handleJsonFieldSorting(db *gorm.DB, sortField string, tableName string, ent
 }
 
 // handleTranslatedFilters applies filters with translation field awareness
-func handleTranslatedFilters(db *gorm.DB, query *qapi.Query, langCode string,
+func handleTranslatedFilters(db *gorm.DB, query *qapi.Query, langs []string, collation string,
 	entityType reflect.Type, multiLangFields []string,
 	_ map[string][]string, m2mFields map[string]string) *gorm.DB {
 
@@ -256,14 +278,15 @@ func handleTranslatedFilters(db *gorm.DB, query *qapi.Query, langCode string,
 
 	for _, v := range query.Filter {
 		// Skip filters that contain a dot, as these are handled by handleTranslatedOneToManyFilter
-		if strings.Contains(v.Name, ".") {
+		// unless they are paths of the JSON fields
+		if strings.Contains(v.Name, ".") && !contains(multiLangFields, v.Name) {
 			continue
 		}
 
 		handled := false
 
 		// Check if this is a polymorphic relationship
-		db, handled = handlePolymorphicTranslationFilter(db, entityType, v, langCode)
+		db, handled = handlePolymorphicTranslationFilter(db, entityType, v, langs)
 		if handled {
 			continue
 		}
@@ -292,10 +315,10 @@ func handleTranslatedFilters(db *gorm.DB, query *qapi.Query, langCode string,
 		if !handled {
 			for _, multiLangField := range multiLangFields {
 				if v.Name == multiLangField {
-					jsonPath := fmt.Sprintf("$.\"%s\"", langCode)
 					// For translation fields, we typically use LIKE operations
-					db = db.Where("LOWER(JSON_UNQUOTE(JSON_EXTRACT("+v.Name+", ?))) LIKE LOWER(?)",
-						jsonPath, "%"+v.Value+"%")
+					column, path := splitTranslationField(v.Name)
+					db = db.Where(collate("LOWER("+translatedPath(column, path, langs)+")", collation)+" LIKE LOWER(?)",
+						"%"+v.Value+"%")
 					handled = true
 					break
 				}
@@ -449,7 +472,7 @@ func convertUnixTimestampToDatetime(timestamp string) string {
 
 // handlePolymorphicTranslationFilter handles filtering for polymorphic relationships
 func handlePolymorphicTranslationFilter(db *gorm.DB, entityType reflect.Type,
-	filter qapi.Filter, langCode string) (*gorm.DB, bool) {
+	filter qapi.Filter, langs []string) (*gorm.DB, bool) {
 
 	if !strings.Contains(filter.Name, ".") {
 		return db, false
@@ -476,10 +499,10 @@ func handlePolymorphicTranslationFilter(db *gorm.DB, entityType reflect.Type,
 		if len(relatedMultiLangFields) > 0 {
 			for _, multiLangField := range relatedMultiLangFields {
 				if parts[1] == multiLangField {
-					jsonPath := fmt.Sprintf("$.\"%s\"", langCode)
-					db = db.Where(fmt.Sprintf("ID IN (SELECT %s FROM %s WHERE LOWER(JSON_UNQUOTE(JSON_EXTRACT(%s, ?))) LIKE LOWER(?))",
-						polyID, relatedTable, multiLangField),
-						jsonPath, "%"+filter.Value+"%")
+					column, path := splitTranslationField(multiLangField)
+					db = db.Where(fmt.Sprintf("ID IN (SELECT %s FROM %s WHERE LOWER(%s) LIKE LOWER(?))",
+						polyID, relatedTable, translatedPath(column, path, langs)),
+						"%"+filter.Value+"%")
 					return db, true
 				}
 			}
@@ -490,12 +513,12 @@ func handlePolymorphicTranslationFilter(db *gorm.DB, entityType reflect.Type,
 }
 
 // buildOptimizedSelectClause creates an optimized SELECT clause for the query
-func buildOptimizedSelectClause(db *gorm.DB, query *qapi.Query, langCode string,
+func buildOptimizedSelectClause(db *gorm.DB, query *qapi.Query, langs []string,
 	entityType reflect.Type, multiLangFields []string) *gorm.DB {
 
 	// If specific fields are requested
 	if len(query.Fields) > 0 {
-		if langCode != "" && len(multiLangFields) > 0 {
+		if len(langs) > 0 && len(multiLangFields) > 0 {
 			// Only translate fields that are in both query.Fields and multiLangFields
 			var translatedFields []string
 			requestedFields := make(map[string]bool)
@@ -504,30 +527,12 @@ func buildOptimizedSelectClause(db *gorm.DB, query *qapi.Query, langCode string,
 				requestedFields[field] = true
 			}
 
-			for i := 0; i < entityType.NumField(); i++ {
-				field := entityType.Field(i)
-				columnName := getColumnName(field)
-
-				if !requestedFields[columnName] {
+			for _, column := range modelColumns(entityType) {
+				if !requestedFields[column.Name] {
 					continue
 				}
-
-				isMultiLang := false
-				for _, mlField := range multiLangFields {
-					if field.Name == mlField {
-						isMultiLang = true
-						break
-					}
-				}
-
-				if isMultiLang {
-					jsonPath := fmt.Sprintf("$.\"%s\"", langCode)
-					translatedFields = append(translatedFields,
-						fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(`%s`, '%s')) AS `%s`",
-							columnName, jsonPath, columnName))
-				} else {
-					translatedFields = append(translatedFields, fmt.Sprintf("`%s`", columnName))
-				}
+				translatedFields = append(translatedFields,
+					translatedColumn(column.Name, multiLangFields, getLanguagePath(langs), false))
 			}
 
 			if len(translatedFields) > 0 {
@@ -538,8 +543,8 @@ func buildOptimizedSelectClause(db *gorm.DB, query *qapi.Query, langCode string,
 	}
 
 	// If no specific fields, use original translation logic
-	if langCode != "" && len(multiLangFields) > 0 {
-		selectClause := buildTranslatedSelectClause(entityType, multiLangFields, langCode)
+	if len(langs) > 0 && len(multiLangFields) > 0 {
+		selectClause := buildTranslatedSelectClause(entityType, multiLangFields, langs)
 		if len(selectClause) > 0 {
 			return db.Select(strings.Join(selectClause, ", "))
 		}
@@ -564,7 +569,8 @@ func getColumnName(field reflect.StructField) string {
 }
 
 // addPreloads adds all preload statements to the query with enhanced translation support
-func addPreloads(db *gorm.DB, preloads []string, langCode string, entityType reflect.Type) *gorm.DB {
+func addPreloads(db *gorm.DB, preloads []string, langs []string, entityType reflect.Type) *gorm.DB {
+	all := isAll(langs)
 	for _, preload := range preloads {
 		if strings.Contains(preload, ".") {
 			// Handle chained preloads
@@ -586,10 +592,10 @@ func addPreloads(db *gorm.DB, preloads []string, langCode string, entityType ref
 						secondLevelMultiLangFields := findTranslationFields(secondRelatedModel)
 
 						// Handle translation fields for both levels
-						if len(firstLevelMultiLangFields) > 0 && langCode != "all" {
-							firstLevelSelects := buildTranslatedSelectClause(relatedType, firstLevelMultiLangFields, langCode)
-							if len(secondLevelMultiLangFields) > 0 && langCode != "all" {
-								secondLevelSelects := buildTranslatedSelectClause(secondRelatedType, secondLevelMultiLangFields, langCode)
+						if len(firstLevelMultiLangFields) > 0 && !all {
+							firstLevelSelects := buildTranslatedSelectClause(relatedType, firstLevelMultiLangFields, langs)
+							if len(secondLevelMultiLangFields) > 0 && !all {
+								secondLevelSelects := buildTranslatedSelectClause(secondRelatedType, secondLevelMultiLangFields, langs)
 								db = db.Preload(firstLevel, func(tx *gorm.DB) *gorm.DB {
 									return tx.Select(firstLevelSelects).Preload(secondLevel, func(tx2 *gorm.DB) *gorm.DB {
 										return tx2.Select(secondLevelSelects)
@@ -600,8 +606,8 @@ func addPreloads(db *gorm.DB, preloads []string, langCode string, entityType ref
 									return tx.Select(firstLevelSelects).Preload(secondLevel)
 								})
 							}
-						} else if len(secondLevelMultiLangFields) > 0 && langCode != "all" {
-							secondLevelSelects := buildTranslatedSelectClause(secondRelatedType, secondLevelMultiLangFields, langCode)
+						} else if len(secondLevelMultiLangFields) > 0 && !all {
+							secondLevelSelects := buildTranslatedSelectClause(secondRelatedType, secondLevelMultiLangFields, langs)
 							db = db.Preload(preload, func(tx *gorm.DB) *gorm.DB {
 								return tx.Select(secondLevelSelects)
 							})
@@ -622,8 +628,8 @@ func addPreloads(db *gorm.DB, preloads []string, langCode string, entityType ref
 				relatedModel := reflect.New(relatedType).Interface()
 				nestedMultiLangFields := findTranslationFields(relatedModel)
 
-				if len(nestedMultiLangFields) > 0 && langCode != "all" {
-					translatedSelects := buildTranslatedSelectClause(relatedType, nestedMultiLangFields, langCode)
+				if len(nestedMultiLangFields) > 0 && !all {
+					translatedSelects := buildTranslatedSelectClause(relatedType, nestedMultiLangFields, langs)
 					db = db.Preload(preload, func(tx *gorm.DB) *gorm.DB {
 						return tx.Select(translatedSelects)
 					})
@@ -717,39 +723,16 @@ func findNestedTranslationFields(preloads []string, entityType reflect.Type) (
 }
 
 // Build SELECT clause while ignoring unwanted GORM fields
-func buildTranslatedSelectClause(entityType reflect.Type, multiLangFields []string, langCode string) []string {
+func buildTranslatedSelectClause(entityType reflect.Type, multiLangFields []string, langs []string) []string {
 	var selectClause []string
 
-	for i := 0; i < entityType.NumField(); i++ {
-		field := entityType.Field(i)
-
-		// Skip fields that should not be included in the query
-		if shouldSkipField(field) {
-			continue
-		}
-
-		// Determine the actual DB column name
-		columnName := getColumnName(field)
-
-		// Check if it's a multi-language field
-		isMultiLang := false
-		for _, multiLangField := range multiLangFields {
-			if field.Name == multiLangField {
-				isMultiLang = true
-				break
-			}
-		}
-
-		if isMultiLang {
-			if langCode == "all" {
-				selectClause = append(selectClause, fmt.Sprintf("`%s`", columnName))
-			} else {
-				selectClause = append(selectClause,
-					fmt.Sprintf("JSON_OBJECT('%s', JSON_UNQUOTE(JSON_EXTRACT(`%s`, '$.\"%s\"'))) AS `%s`",
-						langCode, columnName, langCode, columnName))
-			}
+	// Columns of the embedded structs are flattened, fields to skip are left out
+	for _, column := range modelColumns(entityType) {
+		if isAll(langs) {
+			selectClause = append(selectClause, fmt.Sprintf("`%s`", column.Name))
 		} else {
-			selectClause = append(selectClause, fmt.Sprintf("`%s`", columnName))
+			// the value is keyed by the requested language even if it is read from a fallback
+			selectClause = append(selectClause, translatedColumn(column.Name, multiLangFields, langs, true))
 		}
 	}
 
@@ -776,36 +759,6 @@ func shouldSkipField(field reflect.StructField) bool {
 	return false
 }
 
-func findTranslationFields(record any) []string {
-	recordType := reflect.TypeOf(record)
-	if recordType.Kind() == reflect.Ptr {
-		recordType = recordType.Elem()
-	}
-
-	// Handle slice types to get the element type
-	if recordType.Kind() == reflect.Slice {
-		recordType = recordType.Elem()
-		if recordType.Kind() == reflect.Ptr {
-			recordType = recordType.Elem()
-		}
-	}
-
-	if recordType.Kind() != reflect.Struct {
-		return nil
-	}
-
-	translationFields := []string{}
-	for i := 0; i < recordType.NumField(); i++ {
-		field := recordType.Field(i)
-
-		translationsType := reflect.TypeOf((*Translations)(nil))
-		if field.Type.AssignableTo(translationsType) {
-			translationFields = append(translationFields, field.Name)
-		}
-	}
-	return translationFields
-}
-
 func getRelatedModelType(t reflect.Type) reflect.Type {
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
@@ -819,11 +772,34 @@ func getRelatedModelType(t reflect.Type) reflect.Type {
 	return t
 }
 
-func getLanguagePath(langCode string) string {
-	if langCode == "all" {
-		return DEFAULT_LANG_CODE
+// getLanguagePath returns the languages to read the texts for filters and sorts, "all" reads the default language
+func getLanguagePath(langs []string) []string {
+	if isAll(langs) {
+		return []string{DefaultLanguage()}
+	}
+	return langs
+}
+
+// isAll reports whether all languages are requested
+func isAll(langs []string) bool {
+	return len(langs) > 0 && langs[0] == "all"
+}
+
+// translatedText returns the SQL of the text of the column in the first language of the chain having a translation
+func translatedText(column string, langs []string) string {
+	return translatedPath(column, "", langs)
+}
+
+// translatedPath returns the SQL of the text of the translations at the path of the JSON column like translatedText
+func translatedPath(column string, path string, langs []string) string {
+	values := make([]string, len(langs))
+	for i, lang := range langs {
+		values[i] = fmt.Sprintf("JSON_UNQUOTE(JSON_EXTRACT(%s, '%s'))", column, jsonPath(path, lang))
+	}
+	if len(values) == 1 {
+		return values[0]
 	}
-	return langCode
+	return "COALESCE(" + strings.Join(values, ", ") + ")"
 }
 
 func q2Sql(q string, typ reflect.Type, tableName string) (string, []interface{}, error) {
@@ -937,9 +913,9 @@ func getQapiQPrefix(f *reflect.StructField) (string, bool) {
 }
 
 // Add this function to handle one-to-many relationships properly
-func handleTranslatedOneToManyFilter(db *gorm.DB, query *qapi.Query, entityType reflect.Type) *gorm.DB {
+func handleTranslatedOneToManyFilter(db *gorm.DB, query *qapi.Query, entityType reflect.Type, multiLangFields []string) *gorm.DB {
 	for _, v := range query.Filter {
-		if strings.Contains(v.Name, ".") {
+		if strings.Contains(v.Name, ".") && !contains(multiLangFields, v.Name) {
 			parts := strings.SplitN(v.Name, ".", 2)
 			relation := parts[0]
 			fieldName := parts[1]
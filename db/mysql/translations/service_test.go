@@ -72,7 +72,7 @@ func TestAddPreloads(t *testing.T) {
 			// Test that the function doesn't panic
 			assert.NotPanics(t, func() {
 				if db != nil {
-					addPreloads(db, tc.preloads, langCode, entityType)
+					addPreloads(db, tc.preloads, []string{langCode}, entityType)
 				}
 			})
 		})
@@ -0,0 +1,132 @@
+package translations
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/filllabs/sincap-common/db/mysql"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidLanguage is returned for the language codes which can't be used in the JSON paths
+var ErrInvalidLanguage = errors.New("translations: invalid language code")
+
+// Update updates the record like mysql.Update, but single language values of the Translations fields are merged
+// into the current translations instead of replacing them. Single language values are
+//
+//	map[string]any{"Name": map[string]any{"de-DE": "Titel"}} // sets de-DE only
+//	map[string]any{"Name": map[string]any{"de-DE": nil}}     // removes de-DE
+//	map[string]any{"Name": "Titel"}                          // sets the language of the context (see GetLanguage)
+//
+// Values with multiple languages replace the whole translations. Fields are named by their struct fields or their columns.
+func Update(DB *gorm.DB, model any, fieldsParams ...map[string]any) error {
+	if len(fieldsParams) != 1 || fieldsParams[0] == nil {
+		return mysql.Update(DB, model, fieldsParams...)
+	}
+	multiLangFields := translationColumns(translationFieldsOf(model))
+	if len(multiLangFields) == 0 {
+		return mysql.Update(DB, model, fieldsParams...)
+	}
+	fields := make(map[string]any, len(fieldsParams[0]))
+	for name, value := range fieldsParams[0] {
+		fields[name] = value
+		columnName, ok := translationColumn(multiLangFields, name)
+		if !ok {
+			continue
+		}
+		lang, text, ok := singleLanguage(DB, value)
+		if !ok {
+			continue
+		}
+		if !langCodePattern.MatchString(lang) {
+			return ErrInvalidLanguage
+		}
+		column := safeMySQLNaming(columnName)
+		path := fmt.Sprintf("$.\"%s\"", lang)
+		if text == nil {
+			fields[name] = gorm.Expr("JSON_REMOVE(COALESCE("+column+", JSON_OBJECT()), ?)", path)
+		} else {
+			fields[name] = gorm.Expr("JSON_SET(COALESCE("+column+", JSON_OBJECT()), ?, ?)", path, *text)
+		}
+	}
+	return mysql.Update(DB, model, fields)
+}
+
+// RemoveLanguage removes the translations of the language from the given Translations fields (names of the struct
+// fields or their columns) of the record, from all of them if no fields are given
+func RemoveLanguage(DB *gorm.DB, model any, lang string, fields ...string) error {
+	if !langCodePattern.MatchString(lang) {
+		return ErrInvalidLanguage
+	}
+	multiLangFields := translationColumns(translationFieldsOf(model))
+	if len(fields) == 0 {
+		for _, field := range multiLangFields {
+			fields = append(fields, field.Name)
+		}
+	}
+	if len(fields) == 0 {
+		return nil
+	}
+	updates := make(map[string]any, len(fields))
+	for _, name := range fields {
+		if _, ok := translationColumn(multiLangFields, name); !ok {
+			return fmt.Errorf("%s is not a Translations field", name)
+		}
+		updates[name] = map[string]any{lang: nil}
+	}
+	return Update(DB, model, updates)
+}
+
+// singleLanguage returns the language and the text of the value if it has a single language, nil text means remove
+func singleLanguage(DB *gorm.DB, value any) (string, *string, bool) {
+	var data map[string]any
+	switch v := value.(type) {
+	case string:
+		lang := DefaultLanguage()
+		if DB.Statement != nil && DB.Statement.Context != nil {
+			lang = GetLanguage(DB.Statement.Context)
+		}
+		return lang, &v, true
+	case map[string]any:
+		data = v
+	case map[string]string:
+		data = make(map[string]any, len(v))
+		for k, text := range v {
+			data[k] = text
+		}
+	case *Translations:
+		if v == nil {
+			return "", nil, false
+		}
+		data = make(map[string]any, len(v.data))
+		for k, text := range v.data {
+			data[k] = text
+		}
+	default:
+		return "", nil, false
+	}
+	if len(data) != 1 {
+		return "", nil, false
+	}
+	for lang, text := range data {
+		if text == nil {
+			return lang, nil, true
+		}
+		if reflect.TypeOf(text).Kind() != reflect.String {
+			return "", nil, false
+		}
+		s := reflect.ValueOf(text).String()
+		return lang, &s, true
+	}
+	return "", nil, false
+}
+
+func contains(arr []string, s string) bool {
+	for _, a := range arr {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
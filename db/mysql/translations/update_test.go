@@ -0,0 +1,41 @@
+package translations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestUpdate(t *testing.T) {
+	DB := dbtest.Open(t).Session(&gorm.Session{DryRun: true})
+	var sql string
+	var vars []any
+	assert.NoError(t, DB.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		sql, vars = tx.Statement.SQL.String(), tx.Statement.Vars
+	}))
+
+	assert.NoError(t, Update(DB, &MockCountry{ID: 1}, map[string]any{"Name": map[string]any{"de-DE": "Deutschland"}, "Code": "DE"}))
+	assert.Contains(t, sql, "`Name`=JSON_SET(COALESCE(`Name`, JSON_OBJECT()), ?, ?)")
+	assert.Contains(t, vars, `$."de-DE"`)
+	assert.Contains(t, vars, "Deutschland")
+
+	ctx := context.WithValue(context.Background(), LANG_KEY, "tr-TR")
+	assert.NoError(t, Update(DB.WithContext(ctx), &MockCountry{ID: 1}, map[string]any{"Name": "Almanya"}))
+	assert.Contains(t, vars, `$."tr-TR"`)
+	assert.Contains(t, vars, "Almanya")
+
+	// multiple languages replace the translations
+	assert.NoError(t, Update(DB, &MockCountry{ID: 1}, map[string]any{"Name": map[string]any{"de-DE": "Deutschland", "en-US": "Germany"}}))
+	assert.NotContains(t, sql, "JSON_SET")
+
+	assert.NoError(t, RemoveLanguage(DB, &MockCountry{ID: 1}, "de-DE"))
+	assert.Contains(t, sql, "`Name`=JSON_REMOVE(COALESCE(`Name`, JSON_OBJECT()), ?)")
+	assert.Contains(t, vars, `$."de-DE"`)
+
+	assert.ErrorIs(t, RemoveLanguage(DB, &MockCountry{ID: 1}, "de'DE"), ErrInvalidLanguage)
+	assert.ErrorIs(t, Update(DB, &MockCountry{ID: 1}, map[string]any{"Name": map[string]any{"x\"": "y"}}), ErrInvalidLanguage)
+	assert.Error(t, RemoveLanguage(DB, &MockCountry{ID: 1}, "de-DE", "Code"))
+}
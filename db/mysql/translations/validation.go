@@ -0,0 +1,32 @@
+package translations
+
+import (
+	"context"
+
+	"github.com/filllabs/sincap-common/db"
+	"github.com/filllabs/sincap-common/validator"
+)
+
+func init() {
+	validator.Languages = validationLanguages{}
+	validator.RegisterTranslated(Translations{})
+}
+
+// validationLanguages are the languages of the translations tag of the validator, * requires the enabled languages
+// of the default connection
+type validationLanguages struct{}
+
+func (validationLanguages) Language(ctx context.Context) string {
+	return GetLanguage(ctx)
+}
+
+func (validationLanguages) DefaultLanguage() string {
+	return DefaultLanguage()
+}
+
+func (validationLanguages) Enabled() ([]string, error) {
+	if !db.Has("default") {
+		return nil, nil
+	}
+	return ListCodes(db.DB())
+}
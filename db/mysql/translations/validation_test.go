@@ -0,0 +1,24 @@
+package translations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filllabs/sincap-common/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidation(t *testing.T) {
+	type product struct {
+		Name *Translations `validate:"translations=default tr-TR:2-5"`
+	}
+	name := &Translations{}
+	name.Set(DefaultLanguage(), "Pen")
+	assert.Error(t, validator.Validate.Struct(product{Name: name}))
+	name.Set("tr-TR", "Kalem")
+	assert.NoError(t, validator.Validate.Struct(product{Name: name}))
+
+	ctx := context.WithValue(context.Background(), LANG_KEY, "tr-TR")
+	assert.Error(t, validator.Validate.VarCtx(ctx, "Kurşun kalem", "translations=tr-TR:2-5"))
+	assert.NoError(t, validator.Validate.VarCtx(ctx, "Kalem", "translations=tr-TR:2-5"))
+}
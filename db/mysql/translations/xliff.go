@@ -0,0 +1,86 @@
+package translations
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// xliffDocument is the subset of XLIFF 2.0 used by the exchange.
+// Each entity is a file and each unit is named "{ID}/{Field}".
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID    string      `xml:"id,attr"`
+	Units []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID       string         `xml:"id,attr"`
+	Name     string         `xml:"name,attr"`
+	Segments []xliffSegment `xml:"segment"`
+}
+
+type xliffSegment struct {
+	Source string `xml:"source"`
+	Target string `xml:"target"`
+}
+
+func writeXLIFF(w io.Writer, source string, target string, units []Unit) error {
+	doc := xliffDocument{Version: "2.0", SrcLang: source, TrgLang: target}
+	for i, u := range units {
+		if len(doc.Files) == 0 || doc.Files[len(doc.Files)-1].ID != u.Entity {
+			doc.Files = append(doc.Files, xliffFile{ID: u.Entity})
+		}
+		file := &doc.Files[len(doc.Files)-1]
+		file.Units = append(file.Units, xliffUnit{
+			ID:       fmt.Sprintf("u%d", i+1),
+			Name:     u.ID + "/" + u.Field,
+			Segments: []xliffSegment{{Source: u.Source, Target: u.Target}},
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func readXLIFF(r io.Reader) (string, string, []Unit, error) {
+	var doc xliffDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return "", "", nil, fmt.Errorf("%w: %v", ErrInvalidFile, err)
+	}
+	if !strings.HasPrefix(doc.Version, "2.") {
+		return "", "", nil, fmt.Errorf("%w: unsupported xliff version %q", ErrInvalidFile, doc.Version)
+	}
+	var units []Unit
+	for _, file := range doc.Files {
+		for _, xu := range file.Units {
+			u, ok := parseKey(file.ID + "/" + xu.Name)
+			if !ok {
+				return "", "", nil, fmt.Errorf("%w: invalid unit name %q", ErrInvalidFile, xu.Name)
+			}
+			var src, trg strings.Builder
+			for _, segment := range xu.Segments {
+				src.WriteString(segment.Source)
+				trg.WriteString(segment.Target)
+			}
+			u.Source, u.Target = src.String(), trg.String()
+			units = append(units, u)
+		}
+	}
+	return doc.SrcLang, doc.TrgLang, units, nil
+}
@@ -0,0 +1,221 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/filllabs/sincap-common/backoff"
+	"github.com/filllabs/sincap-common/logging"
+	"github.com/filllabs/sincap-common/server/graceful"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// errNoHandler is recorded for the messages of the topics without a handler
+var errNoHandler = errors.New("outbox: no handler for the topic")
+
+// Dispatcher polls the due messages and delivers them to the handlers of their topics.
+// Multiple dispatchers (instances) may run at the same time, each message is held by one of them for the Lease.
+type Dispatcher struct {
+	db       *gorm.DB
+	handlers map[string]Handler
+	mu       sync.RWMutex
+	stop     chan struct{}
+	done     chan struct{}
+
+	// Interval is the wait between the polls when there are no due messages. Default is 1s.
+	Interval time.Duration
+	// BatchSize is the maximum number of messages delivered per poll. Default is 100.
+	BatchSize int
+	// MaxAttempts is the number of deliveries before the message is dead. Default is 10.
+	MaxAttempts int
+	// BaseDelay is the upper bound of the first retry wait, doubled on every retry. Default is 1s.
+	BaseDelay time.Duration
+	// MaxDelay is the upper bound of the retry waits. Default is 1h.
+	MaxDelay time.Duration
+	// Lease is the time a message is held by a dispatcher while delivering, it is retried after it if the dispatcher dies. Default is 1m.
+	Lease time.Duration
+}
+
+// NewDispatcher returns a dispatcher with the default settings
+func NewDispatcher(DB *gorm.DB) *Dispatcher {
+	return &Dispatcher{
+		db:          DB,
+		handlers:    map[string]Handler{},
+		Interval:    time.Second,
+		BatchSize:   100,
+		MaxAttempts: 10,
+		BaseDelay:   time.Second,
+		MaxDelay:    time.Hour,
+		Lease:       time.Minute,
+	}
+}
+
+// Handle registers the handler of the topic
+func (d *Dispatcher) Handle(topic string, handler Handler) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.handlers[topic] = handler
+}
+
+// Start starts polling in the background. It is registered to graceful.WG until Stop.
+func (d *Dispatcher) Start() {
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	graceful.WG.Addl("Outbox", 1)
+	go d.run()
+}
+
+// Stop stops polling after the current batch and waits for it
+func (d *Dispatcher) Stop() {
+	if d.stop == nil {
+		return
+	}
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+}
+
+func (d *Dispatcher) run() {
+	defer graceful.WG.Donel("Outbox")
+	defer close(d.done)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-d.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	for {
+		count, err := d.Dispatch(ctx)
+		if err != nil && ctx.Err() == nil {
+			logging.Logger.Named("Outbox").Error("Dispatch failed", zap.Error(err))
+		}
+		// continue immediately if the batch was full
+		if err == nil && count >= d.BatchSize {
+			select {
+			case <-d.stop:
+				return
+			default:
+				continue
+			}
+		}
+		select {
+		case <-d.stop:
+			return
+		case <-time.After(d.Interval):
+		}
+	}
+}
+
+// Dispatch delivers a batch of the due messages once and returns the number of the claimed messages.
+func (d *Dispatcher) Dispatch(ctx context.Context) (int, error) {
+	var due []Message
+	now := time.Now()
+	err := d.db.WithContext(ctx).
+		Where("Status = ? AND NextAttemptAt <= ?", StatusPending, now).
+		Order("NextAttemptAt, ID").
+		Limit(d.BatchSize).
+		Find(&due).Error
+	if err != nil {
+		return 0, err
+	}
+	claimed := 0
+	for _, msg := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := d.claim(ctx, &msg, now)
+		if err != nil {
+			return claimed, err
+		}
+		if !ok {
+			continue
+		}
+		claimed++
+		d.deliver(ctx, msg)
+	}
+	return claimed, nil
+}
+
+// claim holds the message by pushing its NextAttemptAt forward, false if another dispatcher holds it
+func (d *Dispatcher) claim(ctx context.Context, msg *Message, now time.Time) (bool, error) {
+	until := now.Add(d.Lease)
+	result := d.db.WithContext(ctx).Model(&Message{}).
+		Where("ID = ? AND Status = ? AND NextAttemptAt = ?", msg.ID, StatusPending, msg.NextAttemptAt).
+		Update("NextAttemptAt", until)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	msg.NextAttemptAt = until
+	return result.RowsAffected == 1, nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, msg Message) {
+	d.mu.RLock()
+	handler, ok := d.handlers[msg.Topic]
+	d.mu.RUnlock()
+	err := errNoHandler
+	if ok {
+		err = d.call(ctx, handler, msg)
+	}
+	// updates are not cancelled by the stop, the message is already delivered or failed
+	db := d.db.WithContext(context.Background()).Model(&Message{}).Where("ID = ?", msg.ID)
+	if err == nil {
+		now := time.Now()
+		if err := db.Updates(map[string]any{"Status": StatusSent, "SentAt": &now, "Attempts": msg.Attempts + 1, "LastError": ""}).Error; err != nil {
+			logging.Logger.Named("Outbox").Error("Can't mark as sent", zap.Uint("id", msg.ID), zap.Error(err))
+		}
+		return
+	}
+	attempts := msg.Attempts + 1
+	fields := map[string]any{"Attempts": attempts, "LastError": truncate(err.Error(), 1024)}
+	if attempts >= d.MaxAttempts {
+		fields["Status"] = StatusDead
+		logging.Logger.Named("Outbox").Error("Message is dead", zap.Uint("id", msg.ID), zap.String("topic", msg.Topic), zap.Int("attempts", attempts), zap.Error(err))
+	} else {
+		fields["NextAttemptAt"] = time.Now().Add(d.delay(attempts))
+		logging.Logger.Named("Outbox").Warn("Delivery failed", zap.Uint("id", msg.ID), zap.String("topic", msg.Topic), zap.Int("attempts", attempts), zap.Error(err))
+	}
+	if err := db.Updates(fields).Error; err != nil {
+		logging.Logger.Named("Outbox").Error("Can't mark as failed", zap.Uint("id", msg.ID), zap.Error(err))
+	}
+}
+
+func (d *Dispatcher) call(ctx context.Context, handler Handler, msg Message) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("outbox: handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, msg)
+}
+
+// delay returns the jittered wait before the given retry (starting from 1)
+func (d *Dispatcher) delay(retry int) time.Duration {
+	// at least the half of the ceiling, so retries do not hammer the handler
+	half := backoff.Ceiling(d.BaseDelay, d.MaxDelay, retry) / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// Retry makes the dead messages pending again, all of them if no ids are given
+func (d *Dispatcher) Retry(ids ...uint) error {
+	db := d.db.Model(&Message{}).Where("Status = ?", StatusDead)
+	if len(ids) > 0 {
+		db = db.Where("ID IN ?", ids)
+	}
+	return db.Updates(map[string]any{"Status": StatusPending, "Attempts": 0, "NextAttemptAt": time.Now()}).Error
+}
+
+func truncate(s string, max int) string {
+	if len(s) > max {
+		return s[:max]
+	}
+	return s
+}
@@ -0,0 +1,70 @@
+// Package outbox implements the transactional outbox. Messages are written with the same transaction of the change
+// (see Add) and delivered to the handlers of their topics by a Dispatcher running in the background.
+//
+//	db.DB().AutoMigrate(&outbox.Message{})
+//	d := outbox.NewDispatcher(db.DB())
+//	d.Handle("Order.created", sendMail)
+//	d.Handle("Price.updated", outbox.Writer(ws.BroadcastWriter{Socket: m}))
+//	d.Start()
+//	server.Start(conf, r, func() { d.Stop() })
+//
+// Delivery is at least once, handlers must be idempotent. Failed messages are retried with backoff and marked
+// as dead after MaxAttempts.
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/filllabs/sincap-common/db/types"
+	"gorm.io/gorm"
+)
+
+// Statuses of the messages
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusDead    = "dead"
+)
+
+// Message is a single outbox entry
+type Message struct {
+	ID        uint      `gorm:"primary_key"`
+	CreatedAt time.Time `gorm:"index"`
+	Topic     string    `gorm:"size:128;index"`
+	Payload   types.JSON
+	Status    string `gorm:"size:16;index:idx_outbox_due"`
+	// NextAttemptAt is the time of the next delivery, it is also pushed forward while a dispatcher holds the message
+	NextAttemptAt time.Time `gorm:"index:idx_outbox_due"`
+	Attempts      int
+	LastError     string `gorm:"size:1024"`
+	SentAt        *time.Time
+}
+
+// TableName returns the table name of the messages
+func (Message) TableName() string {
+	return "OutboxMessage"
+}
+
+// Handler delivers the message. Returning an error schedules a retry.
+type Handler func(ctx context.Context, msg Message) error
+
+// Add writes a message with the JSON of the payload to the outbox. Pass the transaction of the change as DB.
+func Add(DB *gorm.DB, topic string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	return DB.Create(&Message{CreatedAt: now, Topic: topic, Payload: data, Status: StatusPending, NextAttemptAt: now}).Error
+}
+
+// Writer returns a handler which writes the payloads to the writer (e.g. ws.BroadcastWriter)
+func Writer(w io.Writer) Handler {
+	return func(ctx context.Context, msg Message) error {
+		_, err := w.Write(msg.Payload)
+		return err
+	}
+}
@@ -0,0 +1,136 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestDispatch(t *testing.T) {
+	DB := dbtest.Open(t, &Message{})
+	failure := errors.New("rollback")
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		assert.NoError(t, Add(tx, "Order.created", map[string]any{"ID": 1}))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.NoError(t, DB.Transaction(func(tx *gorm.DB) error {
+		return Add(tx, "Order.created", map[string]any{"ID": 2})
+	}))
+
+	d := NewDispatcher(DB)
+	var got []string
+	d.Handle("Order.created", func(ctx context.Context, msg Message) error {
+		got = append(got, string(msg.Payload))
+		return nil
+	})
+	count, err := d.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, []string{`{"ID":2}`}, got)
+
+	var msg Message
+	assert.NoError(t, DB.First(&msg).Error)
+	assert.Equal(t, StatusSent, msg.Status)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.NotNil(t, msg.SentAt)
+
+	// sent messages are not delivered again
+	count, err = d.Dispatch(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
+
+func TestDispatchRetry(t *testing.T) {
+	DB := dbtest.Open(t, &Message{})
+	assert.NoError(t, Add(DB, "Mail.send", "hello"))
+	assert.NoError(t, Add(DB, "Unknown", "?"))
+
+	d := NewDispatcher(DB)
+	d.MaxAttempts = 2
+	d.BaseDelay = 0
+	calls := 0
+	d.Handle("Mail.send", func(ctx context.Context, msg Message) error {
+		calls++
+		if calls == 1 {
+			return errors.New("smtp down")
+		}
+		panic("boom")
+	})
+	ctx := context.Background()
+	count, err := d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	var msgs []Message
+	assert.NoError(t, DB.Order("ID").Find(&msgs).Error)
+	assert.Equal(t, StatusPending, msgs[0].Status)
+	assert.Equal(t, "smtp down", msgs[0].LastError)
+	assert.Equal(t, errNoHandler.Error(), msgs[1].LastError)
+
+	count, err = d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.NoError(t, DB.Order("ID").Find(&msgs).Error)
+	assert.Equal(t, StatusDead, msgs[0].Status)
+	assert.Contains(t, msgs[0].LastError, "boom")
+	assert.Equal(t, StatusDead, msgs[1].Status)
+
+	// dead messages are not delivered until retried
+	count, err = d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	d.Handle("Mail.send", func(ctx context.Context, msg Message) error { return nil })
+	assert.NoError(t, d.Retry(msgs[0].ID))
+	count, err = d.Dispatch(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.NoError(t, DB.Order("ID").Find(&msgs).Error)
+	assert.Equal(t, StatusSent, msgs[0].Status)
+	assert.Equal(t, StatusDead, msgs[1].Status)
+}
+
+func TestDispatchClaim(t *testing.T) {
+	DB := dbtest.Open(t, &Message{})
+	assert.NoError(t, Add(DB, "Order.created", 1))
+	d := NewDispatcher(DB)
+	var msg Message
+	assert.NoError(t, DB.First(&msg).Error)
+
+	ok, err := d.claim(context.Background(), &msg, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	// another dispatcher can't claim the held message
+	stale := msg
+	stale.NextAttemptAt = stale.NextAttemptAt.Add(-d.Lease)
+	ok, err = d.claim(context.Background(), &stale, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestStartStop(t *testing.T) {
+	DB := dbtest.Open(t, &Message{})
+	d := NewDispatcher(DB)
+	d.Interval = 10 * time.Millisecond
+	delivered := make(chan string, 1)
+	d.Handle("Order.created", func(ctx context.Context, msg Message) error {
+		delivered <- string(msg.Payload)
+		return nil
+	})
+	d.Start()
+	assert.NoError(t, Add(DB, "Order.created", 7))
+	select {
+	case payload := <-delivered:
+		assert.Equal(t, "7", payload)
+	case <-time.After(5 * time.Second):
+		t.Fatal("message is not delivered")
+	}
+	d.Stop()
+	d.Stop()
+}
@@ -0,0 +1,89 @@
+// Package ownership provides a gorm plugin which restricts all operations on util.OwnedModel entities to their owners.
+// The owner is read from the claims carried by the statement context (see claims.WithDecrypted and claims.Middleware).
+//
+//	db.DB().Use(ownership.Plugin{AdminRoles: []string{"admin"}})
+//	db.DB().WithContext(claims.WithDecrypted(ctx, c)).Find(&notes) // only the notes of c.UserID
+//
+// Operations without claims at the context (migrations, background jobs etc.) are not scoped unless Strict is set.
+package ownership
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/db/scoping"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/reflection"
+	"gorm.io/gorm"
+)
+
+// ErrNoClaims is returned at Strict mode if an owned entity is accessed without claims
+var ErrNoClaims = errors.New("ownership: no claims found at context")
+
+// ErrNotOwner is returned if a save tries to overwrite a record of another owner
+var ErrNotOwner = errors.New("ownership: record belongs to another owner")
+
+const ownerField = "OwnerID"
+
+var ownedModelType = reflect.TypeOf(util.OwnedModel{})
+
+// ownedTypes caches the result of IsOwned per model type
+var ownedTypes sync.Map
+
+// Plugin scopes reads, updates and deletes of util.OwnedModel entities with OwnerID and fills OwnerID on create.
+type Plugin struct {
+	// AdminRoles holds role names (claims.RoleName) which bypass ownership checks
+	AdminRoles []string
+	// Strict makes operations on owned entities fail with ErrNoClaims if there are no claims at the context
+	Strict bool
+}
+
+// Name returns the name of the plugin
+func (p Plugin) Name() string {
+	return "sincap:ownership"
+}
+
+// Initialize registers ownership callbacks to the given db
+func (p Plugin) Initialize(db *gorm.DB) error {
+	return scoping.Column{
+		Name:     p.Name(),
+		Field:    ownerField,
+		Matches:  IsOwned,
+		Value:    p.owner,
+		ErrOther: ErrNotOwner,
+	}.Register(db)
+}
+
+// owner returns the owner of the statement from its claims, admins bypass the ownership checks.
+func (p Plugin) owner(db *gorm.DB) (any, bool, bool) {
+	c, ok := claims.DecryptedFromContext(db.Statement.Context)
+	if !ok {
+		if p.Strict {
+			db.AddError(ErrNoClaims)
+		}
+		return nil, false, false
+	}
+	return c.UserID, p.isAdmin(c), true
+}
+
+func (p Plugin) isAdmin(c *claims.DecryptedClaims) bool {
+	for _, role := range p.AdminRoles {
+		if role == c.RoleName {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOwned checks if the given type embeds util.OwnedModel
+func IsOwned(typ reflect.Type) bool {
+	typ = reflection.ExtractRealTypeField(typ)
+	if owned, ok := ownedTypes.Load(typ); ok {
+		return owned.(bool)
+	}
+	owned := reflection.Embeds(typ, ownedModelType)
+	ownedTypes.Store(typ, owned)
+	return owned
+}
@@ -0,0 +1,123 @@
+package ownership
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Note struct {
+	util.Model
+	util.OwnedModel
+	Text string
+}
+
+type Tag struct {
+	ID   uint
+	Text string
+}
+
+func openDB(t *testing.T, p Plugin) *gorm.DB {
+	DB := dbtest.Open(t, &Note{}, &Tag{})
+	assert.NoError(t, DB.Create(&[]Note{
+		{OwnedModel: util.OwnedModel{OwnerID: 1}, Text: "first"},
+		{OwnedModel: util.OwnedModel{OwnerID: 1}, Text: "second"},
+		{OwnedModel: util.OwnedModel{OwnerID: 2}, Text: "third"},
+	}).Error)
+	assert.NoError(t, DB.Use(p))
+	return DB
+}
+
+func userCtx(id uint, role string) context.Context {
+	return claims.WithDecrypted(context.Background(), &claims.DecryptedClaims{UserID: id, RoleName: role})
+}
+
+func TestIsOwned(t *testing.T) {
+	assert.True(t, IsOwned(reflect.TypeOf(Note{})))
+	assert.True(t, IsOwned(reflect.TypeOf(&[]*Note{})))
+	assert.False(t, IsOwned(reflect.TypeOf(Tag{})))
+}
+
+func TestPluginRead(t *testing.T) {
+	DB := openDB(t, Plugin{AdminRoles: []string{"admin"}})
+
+	var notes []Note
+	assert.NoError(t, DB.WithContext(userCtx(1, "user")).Find(&notes).Error)
+	assert.Len(t, notes, 2)
+
+	var note Note
+	assert.ErrorIs(t, DB.WithContext(userCtx(1, "user")).First(&note, 3).Error, gorm.ErrRecordNotFound)
+
+	var count int64
+	assert.NoError(t, DB.WithContext(userCtx(2, "user")).Model(&Note{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	notes = nil
+	assert.NoError(t, DB.WithContext(userCtx(5, "admin")).Find(&notes).Error)
+	assert.Len(t, notes, 3)
+
+	notes = nil
+	assert.NoError(t, DB.Find(&notes).Error)
+	assert.Len(t, notes, 3)
+}
+
+func TestPluginUpdateDelete(t *testing.T) {
+	DB := openDB(t, Plugin{})
+	ctx := userCtx(2, "user")
+
+	result := DB.WithContext(ctx).Model(&Note{Model: util.Model{ID: 1}}).Updates(map[string]any{"Text": "changed"})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	result = DB.WithContext(ctx).Model(&Note{Model: util.Model{ID: 3}}).Updates(map[string]any{"Text": "changed", "OwnerID": 1})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+	var note Note
+	assert.NoError(t, DB.First(&note, 3).Error)
+	assert.Equal(t, uint(2), note.OwnerID)
+
+	result = DB.WithContext(ctx).Model(&Note{Model: util.Model{ID: 3}}).Omit("Text").Updates(map[string]any{"Text": "omitted"})
+	assert.NoError(t, result.Error)
+	assert.NoError(t, DB.First(&note, 3).Error)
+	assert.Equal(t, "changed", note.Text)
+
+	result = DB.WithContext(ctx).Delete(&Note{}, 1)
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	// the owner condition must not pass the missing where check of GORM
+	assert.ErrorIs(t, DB.WithContext(ctx).Model(&Note{}).Updates(map[string]any{"Text": "all"}).Error, gorm.ErrMissingWhereClause)
+	assert.ErrorIs(t, DB.WithContext(ctx).Delete(&Note{}).Error, gorm.ErrMissingWhereClause)
+	result = DB.WithContext(ctx).Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&Note{}).Updates(map[string]any{"Text": "all"})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+}
+
+func TestPluginCreate(t *testing.T) {
+	DB := openDB(t, Plugin{AdminRoles: []string{"admin"}})
+
+	note := Note{Text: "mine", OwnedModel: util.OwnedModel{OwnerID: 1}}
+	assert.NoError(t, DB.WithContext(userCtx(2, "user")).Create(&note).Error)
+	assert.Equal(t, uint(2), note.OwnerID)
+
+	note = Note{Text: "for someone", OwnedModel: util.OwnedModel{OwnerID: 1}}
+	assert.NoError(t, DB.WithContext(userCtx(5, "admin")).Create(&note).Error)
+	assert.Equal(t, uint(1), note.OwnerID)
+
+	stolen := Note{Model: util.Model{ID: 1}, Text: "stolen"}
+	assert.ErrorIs(t, DB.WithContext(userCtx(2, "user")).Save(&stolen).Error, ErrNotOwner)
+}
+
+func TestPluginStrict(t *testing.T) {
+	DB := openDB(t, Plugin{Strict: true})
+	var notes []Note
+	assert.ErrorIs(t, DB.Find(&notes).Error, ErrNoClaims)
+	var tags []Tag
+	assert.NoError(t, DB.Find(&tags).Error)
+}
@@ -0,0 +1,382 @@
+package queryapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filllabs/sincap-common/db/types"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/filllabs/sincap-common/reflection"
+)
+
+// Match reports whether the record (a struct or a pointer to struct) satisfies the filters and _q of the query
+// the same way GenerateDB does in SQL. Inner field filters match if any of the related records match.
+// Strings are compared case insensitively like the default MySQL collations.
+func Match(q *qapi.Query, record reflect.Value) (bool, error) {
+	record = reflect.Indirect(record)
+	if q == nil {
+		return true, nil
+	}
+	for _, filter := range q.Filter {
+		ok, err := matchFilter(record, filter)
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	if len(q.Q) > 0 {
+		return matchQ(record, q.Q), nil
+	}
+	return true, nil
+}
+
+// SortRecords sorts the records (structs or pointers to structs) by the sort parameters of the query (e.g. "Name desc").
+// The sort is stable and the unknown fields are ignored like GenerateDB. NULL values come first in ascending order.
+func SortRecords(sorts []string, records []reflect.Value) {
+	if len(sorts) == 0 {
+		return
+	}
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := reflect.Indirect(records[i]), reflect.Indirect(records[j])
+		for _, s := range sorts {
+			values := strings.Split(s, " ")
+			names := strings.Split(values[0], ".")
+			c := compareValues(sortValue(a, names), sortValue(b, names))
+			if c == 0 {
+				continue
+			}
+			if len(values) > 1 && values[1] == qapi.DSC.String() {
+				return c > 0
+			}
+			return c < 0
+		}
+		return false
+	})
+}
+
+func matchFilter(record reflect.Value, filter qapi.Filter) (bool, error) {
+	names := strings.Split(filter.Name, ".")
+	field, isFieldFound := record.Type().FieldByName(names[0])
+	if !isFieldFound {
+		return false, fmt.Errorf("Can't find field for %s", filter.Name)
+	}
+	if len(names) > 1 && reflection.DepointerField(field.Type) == jsonType {
+		// json paths are always compared with LIKE
+		value, ok := jsonValue(record.FieldByIndex(field.Index), names[1:])
+		return ok && like(filter.Value, fmt.Sprint(value)), nil
+	}
+	leaves, err := leafValues(record, names, filter.Name)
+	if err != nil {
+		return false, err
+	}
+	for _, leaf := range leaves {
+		ok, err := compareFilter(leaf, filter)
+		if err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
+
+// leafValues returns the values of the dotted field path, walking into all elements of the slices
+func leafValues(v reflect.Value, names []string, path string) ([]reflect.Value, error) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if len(names) == 0 {
+				return []reflect.Value{v}, nil
+			}
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if len(names) == 0 {
+		return []reflect.Value{v}, nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var leaves []reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			l, err := leafValues(v.Index(i), names, path)
+			if err != nil {
+				return nil, err
+			}
+			leaves = append(leaves, l...)
+		}
+		return leaves, nil
+	case reflect.Struct:
+		field := v.FieldByName(names[0])
+		if !field.IsValid() {
+			return nil, fmt.Errorf("Can't find struct: %s field: %s", v.Type().Name(), path)
+		}
+		return leafValues(field, names[1:], path)
+	}
+	return nil, fmt.Errorf("%s is not struct field in %s", path, v.Type().Name())
+}
+
+func compareFilter(leaf reflect.Value, filter qapi.Filter) (bool, error) {
+	isNil := leaf.Kind() == reflect.Ptr && leaf.IsNil()
+	switch {
+	case filter.Operation == qapi.EQ && isNull(filter.Value):
+		return isNil, nil
+	case filter.Operation == qapi.NEQ && isNull(filter.Value):
+		return !isNil, nil
+	case isNil:
+		// comparisons with NULL are never true
+		return false, nil
+	}
+	leaf = reflect.Indirect(leaf)
+	switch filter.Operation {
+	case qapi.LK:
+		return like(filter.Value, fmt.Sprint(plain(leaf))), nil
+	case qapi.IN, qapi.IN_ALT:
+		sep := "|"
+		if filter.Operation == qapi.IN_ALT {
+			sep = "*"
+		}
+		for _, value := range strings.Split(filter.Value, sep) {
+			c, ok, err := compareTo(leaf, filter, value)
+			if err != nil {
+				return false, err
+			}
+			if ok && c == 0 {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	c, ok, err := compareTo(leaf, filter, filter.Value)
+	if err != nil || !ok {
+		return false, err
+	}
+	switch filter.Operation {
+	case qapi.EQ:
+		return c == 0, nil
+	case qapi.NEQ:
+		return c != 0, nil
+	case qapi.LT:
+		return c < 0, nil
+	case qapi.LTE:
+		return c <= 0, nil
+	case qapi.GT:
+		return c > 0, nil
+	case qapi.GTE:
+		return c >= 0, nil
+	}
+	return false, qapi.ErrInvalidOp
+}
+
+// compareTo compares the leaf with the value converted like the SQL parameters, false if the value can't be converted
+func compareTo(leaf reflect.Value, filter qapi.Filter, value string) (int, bool, error) {
+	values, err := util.ConvertValue(filter, leaf.Type(), leaf.Kind(), nil, value)
+	if err != nil || len(values) == 0 {
+		return 0, false, err
+	}
+	return compareValues(plain(leaf), values[0]), true, nil
+}
+
+// plain returns the value as one of string, int64, uint64, float64, bool or time.Time where possible
+func plain(v reflect.Value) any {
+	if !v.IsValid() {
+		return nil
+	}
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Bool:
+		return v.Bool()
+	}
+	if t, ok := v.Interface().(time.Time); ok {
+		return t
+	}
+	if j, ok := v.Interface().(types.JSON); ok {
+		return string(j)
+	}
+	return v.Interface()
+}
+
+// compareValues compares the plain values, nil is less than everything
+func compareValues(a any, b any) int {
+	if a == nil || b == nil {
+		switch {
+		case a == nil && b == nil:
+			return 0
+		case a == nil:
+			return -1
+		}
+		return 1
+	}
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	switch x := a.(type) {
+	case bool:
+		if y, ok := b.(bool); ok {
+			if x == y {
+				return 0
+			} else if !x {
+				return -1
+			}
+			return 1
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			switch {
+			case x.Before(y):
+				return -1
+			case x.After(y):
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(fmt.Sprint(a)), strings.ToLower(fmt.Sprint(b)))
+}
+
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+func sortValue(record reflect.Value, names []string) any {
+	field, isFieldFound := record.Type().FieldByName(names[0])
+	if !isFieldFound {
+		return nil
+	}
+	if len(names) > 1 && reflection.DepointerField(field.Type) == jsonType {
+		value, _ := jsonValue(record.FieldByIndex(field.Index), names[1:])
+		return value
+	}
+	leaves, err := leafValues(record, names, strings.Join(names, "."))
+	if err != nil || len(leaves) == 0 {
+		return nil
+	}
+	return plain(leaves[0])
+}
+
+// jsonValue returns the value at the path of the json field
+func jsonValue(field reflect.Value, path []string) (any, bool) {
+	field = reflect.Indirect(field)
+	if !field.IsValid() {
+		return nil, false
+	}
+	var value any
+	if err := json.Unmarshal(field.Interface().(types.JSON), &value); err != nil {
+		return nil, false
+	}
+	for _, name := range path {
+		m, ok := value.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if value, ok = m[name]; !ok {
+			return nil, false
+		}
+	}
+	if value == nil {
+		return nil, false
+	}
+	return value, true
+}
+
+// matchQ matches the q with the fields tagged with qapi q patterns, nested structs are searched recursively
+func matchQ(record reflect.Value, q string) bool {
+	for _, field := range *getQapiFields(record.Type()) {
+		value := record.FieldByIndex(field.Field.Index)
+		if field.Typ.Kind() != reflect.Struct {
+			if v := plain(value); v != nil && like(strings.Replace(field.Tag, "*", q, 1), fmt.Sprint(v)) {
+				return true
+			}
+			continue
+		}
+		for _, related := range structsOf(value) {
+			if matchQ(related, q) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// structsOf returns the struct values of the pointer, struct or slice value
+func structsOf(v reflect.Value) []reflect.Value {
+	v = reflect.Indirect(v)
+	switch v.Kind() {
+	case reflect.Struct:
+		return []reflect.Value{v}
+	case reflect.Slice, reflect.Array:
+		var structs []reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			structs = append(structs, structsOf(v.Index(i))...)
+		}
+		return structs
+	}
+	return nil
+}
+
+// likePatterns caches the compiled LIKE patterns
+var likePatterns sync.Map
+
+// like matches the value with the SQL LIKE pattern case insensitively
+func like(pattern string, value string) bool {
+	cached, ok := likePatterns.Load(pattern)
+	re, _ := cached.(*regexp.Regexp)
+	if !ok {
+		var sb strings.Builder
+		sb.WriteString("(?is)^")
+		escaped := false
+		for _, ch := range pattern {
+			switch {
+			case escaped:
+				sb.WriteString(regexp.QuoteMeta(string(ch)))
+				escaped = false
+			case ch == '\\':
+				escaped = true
+			case ch == '%':
+				sb.WriteString(".*")
+			case ch == '_':
+				sb.WriteString(".")
+			default:
+				sb.WriteString(regexp.QuoteMeta(string(ch)))
+			}
+		}
+		sb.WriteString("$")
+		re = regexp.MustCompile(sb.String())
+		likePatterns.Store(pattern, re)
+	}
+	return re.MatchString(value)
+}
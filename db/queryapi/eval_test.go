@@ -0,0 +1,59 @@
+package queryapi
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatch(t *testing.T) {
+	sample := Sample{ID: 3, Name: "Seray", InnerF: &Inner1{Name: "Ankara", Inner2P: &Inner2{Name: "Kizilay", Age: 7}}}
+	tests := []struct {
+		name    string
+		query   qapi.Query
+		want    bool
+		wantErr bool
+	}{
+		{"EQ", qapi.Query{Filter: []qapi.Filter{{Name: "Name", Operation: qapi.EQ, Value: "seray"}}}, true, false},
+		{"NEQ", qapi.Query{Filter: []qapi.Filter{{Name: "Name", Operation: qapi.NEQ, Value: "Seray"}}}, false, false},
+		{"GT", qapi.Query{Filter: []qapi.Filter{{Name: "ID", Operation: qapi.GT, Value: "2"}}}, true, false},
+		{"LTE", qapi.Query{Filter: []qapi.Filter{{Name: "ID", Operation: qapi.LTE, Value: "2"}}}, false, false},
+		{"LK", qapi.Query{Filter: []qapi.Filter{{Name: "Name", Operation: qapi.LK, Value: "%ra_"}}}, true, false},
+		{"IN", qapi.Query{Filter: []qapi.Filter{{Name: "ID", Operation: qapi.IN, Value: "1|3"}}}, true, false},
+		{"IN_ALT", qapi.Query{Filter: []qapi.Filter{{Name: "ID", Operation: qapi.IN_ALT, Value: "1*2"}}}, false, false},
+		{"NULL", qapi.Query{Filter: []qapi.Filter{{Name: "InnerF", Operation: qapi.NEQ, Value: "NULL"}}}, true, false},
+		{"Inner", qapi.Query{Filter: []qapi.Filter{{Name: "InnerF.Inner2P.Age", Operation: qapi.GTE, Value: "7"}}}, true, false},
+		{"AND", qapi.Query{Filter: []qapi.Filter{{Name: "ID", Operation: qapi.EQ, Value: "3"}, {Name: "Name", Operation: qapi.EQ, Value: "x"}}}, false, false},
+		{"Q", qapi.Query{Q: "eRa"}, true, false},
+		{"QInner", qapi.Query{Q: "Kizil"}, true, false},
+		{"QMiss", qapi.Query{Q: "Ankaraa"}, false, false},
+		{"Unknown", qapi.Query{Filter: []qapi.Filter{{Name: "Surname", Operation: qapi.EQ, Value: "x"}}}, false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Match(&tt.query, reflect.ValueOf(&sample))
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestSortRecords(t *testing.T) {
+	records := []reflect.Value{
+		reflect.ValueOf(Inner2{ID: 1, Name: "b", Age: 3}),
+		reflect.ValueOf(Inner2{ID: 2, Name: "A", Age: 3}),
+		reflect.ValueOf(Inner2{ID: 3, Name: "c", Age: 1}),
+	}
+	SortRecords([]string{"Age desc", "Name asc"}, records)
+	var ids []uint
+	for _, r := range records {
+		ids = append(ids, r.Interface().(Inner2).ID)
+	}
+	assert.Equal(t, []uint{2, 1, 3}, ids)
+}
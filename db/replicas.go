@@ -0,0 +1,239 @@
+package db
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/filllabs/sincap-common/locals"
+	"github.com/filllabs/sincap-common/logging"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+type stickyKey string
+
+const stickyCtxKey stickyKey = "primarySticky"
+
+// StickyLocalsKey is the fiber locals key which holds the primary stickiness of the request.
+// It is read from c.Context() as well as c.UserContext(), see locals.Value.
+const StickyLocalsKey = "primarySticky"
+
+const defaultHealthCheckInterval = 10 * time.Second
+
+// resolvers holds the replica resolvers of the connections by name
+var resolvers = map[string]*replicaResolver{}
+
+// Sticky marks the request as written, after the first write all reads of the request go to the primary.
+type Sticky struct {
+	written int32
+	forced  bool
+}
+
+// WithSticky returns a copy of the context which routes the reads to the primary after the first write.
+func WithSticky(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyCtxKey, &Sticky{})
+}
+
+// WithStickyOf returns a copy of the context which carries the given stickiness.
+// It is useful for sharing the stickiness of a request with the contexts derived from it.
+func WithStickyOf(ctx context.Context, s *Sticky) context.Context {
+	return context.WithValue(ctx, stickyCtxKey, s)
+}
+
+// WithPrimary returns a copy of the context which routes all reads to the primary
+func WithPrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyCtxKey, &Sticky{forced: true})
+}
+
+// StickyFromContext returns the stickiness put by WithSticky, WithPrimary or the sticky middleware if any
+func StickyFromContext(ctx context.Context) (*Sticky, bool) {
+	return locals.Value[*Sticky](ctx, stickyCtxKey, StickyLocalsKey)
+}
+
+// replica is a read only connection with its health state
+type replica struct {
+	conn    *gorm.DB
+	pool    gorm.ConnPool
+	healthy int32
+}
+
+// replicaResolver is a gorm plugin which routes reads to the healthy replicas and writes to the primary.
+// Reads inside transactions, locking reads (FOR UPDATE) and reads after a write of a sticky context
+// (see WithSticky) always go to the primary.
+type replicaResolver struct {
+	name     string
+	primary  gorm.ConnPool
+	replicas []*replica
+	next     uint64
+	interval time.Duration
+	stop     chan struct{}
+	once     sync.Once
+}
+
+// Name returns the name of the plugin
+func (r *replicaResolver) Name() string {
+	return "sincap:replicas"
+}
+
+// Initialize registers routing callbacks to the given db
+func (r *replicaResolver) Initialize(db *gorm.DB) error {
+	r.primary = db.ConnPool
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("sincap:replicas:query", r.route); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("sincap:replicas:restore_query", r.restore); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register("sincap:replicas:row", r.route); err != nil {
+		return err
+	}
+	if err := cb.Row().After("gorm:row").Register("sincap:replicas:restore_row", r.restore); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("sincap:replicas:create", r.written); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("sincap:replicas:update", r.written); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("sincap:replicas:delete", r.written); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("sincap:replicas:raw", r.written)
+}
+
+// route switches the connection of the statement to a replica if possible
+func (r *replicaResolver) route(db *gorm.DB) {
+	stmt := db.Statement
+	// transactions and prepared statements have their own pools
+	if stmt.ConnPool != r.primary {
+		return
+	}
+	if _, locking := stmt.Clauses["FOR"]; locking {
+		return
+	}
+	// tenants with dedicated connections have no replicas
+	if tenantID, ok := TenantFromContext(stmt.Context); ok && HasTenant(r.name, tenantID) {
+		return
+	}
+	if s, ok := StickyFromContext(stmt.Context); ok && (s.forced || atomic.LoadInt32(&s.written) == 1) {
+		return
+	}
+	if rep := r.pick(); rep != nil {
+		stmt.ConnPool = rep.pool
+	}
+}
+
+// restore switches the connection back to the primary so the statement can be reused for writes
+func (r *replicaResolver) restore(db *gorm.DB) {
+	for _, rep := range r.replicas {
+		if db.Statement.ConnPool == rep.pool {
+			db.Statement.ConnPool = r.primary
+			return
+		}
+	}
+}
+
+func (r *replicaResolver) written(db *gorm.DB) {
+	if s, ok := StickyFromContext(db.Statement.Context); ok && db.Error == nil {
+		atomic.StoreInt32(&s.written, 1)
+	}
+}
+
+// pick returns the next healthy replica with round robin, nil if all are unhealthy
+func (r *replicaResolver) pick() *replica {
+	count := uint64(len(r.replicas))
+	for i := uint64(0); i < count; i++ {
+		rep := r.replicas[(atomic.AddUint64(&r.next, 1)-1)%count]
+		if atomic.LoadInt32(&rep.healthy) == 1 {
+			return rep
+		}
+	}
+	return nil
+}
+
+// check pings all replicas and updates their health
+func (r *replicaResolver) check() {
+	for i, rep := range r.replicas {
+		sdb, err := rep.conn.DB()
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+			err = sdb.PingContext(ctx)
+			cancel()
+		}
+		healthy := int32(0)
+		if err == nil {
+			healthy = 1
+		}
+		if atomic.SwapInt32(&rep.healthy, healthy) != healthy {
+			if healthy == 1 {
+				logging.Logger.Named("DB").Info("Replica is healthy", zap.String("name", r.name), zap.Int("replica", i))
+			} else {
+				logging.Logger.Named("DB").Warn("Replica is unhealthy", zap.String("name", r.name), zap.Int("replica", i), zap.Error(err))
+			}
+		}
+	}
+}
+
+// watch checks the health of the replicas periodically until close
+func (r *replicaResolver) watch() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			r.check()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// close stops the health checks and closes the replica connections
+func (r *replicaResolver) close() {
+	r.once.Do(func() {
+		close(r.stop)
+		for _, rep := range r.replicas {
+			if err := Close(rep.conn); err != nil {
+				logging.Logger.Named("DB").Error("Can't close replica connection", zap.String("name", r.name), zap.Error(err))
+			}
+		}
+	})
+}
+
+// configureReplicas opens the replicas of the config and registers the resolver to the primary connection
+func configureReplicas(name string, primary *gorm.DB, conf Config) {
+	if len(conf.Replicas) == 0 {
+		return
+	}
+	r := &replicaResolver{
+		name:     name,
+		interval: defaultHealthCheckInterval,
+		stop:     make(chan struct{}),
+	}
+	if conf.ReplicaHealthCheck > 0 {
+		r.interval = time.Duration(conf.ReplicaHealthCheck) * time.Second
+	}
+	for i, dsn := range conf.Replicas {
+		// replicas do not stop the start up, they are used after the first successful health check
+		conn, err := connect(dsn, conf, true)
+		if err != nil {
+			logging.Logger.Named("DB").Error("Can't open replica connection", zap.String("name", name), zap.Int("replica", i), zap.Error(err))
+			continue
+		}
+		r.replicas = append(r.replicas, &replica{conn: conn, pool: conn.ConnPool})
+	}
+	if len(r.replicas) == 0 {
+		return
+	}
+	if err := primary.Use(r); err != nil {
+		logging.Logger.Fatal("DB Could not register replicas.", zap.String("name", name), zap.Error(err))
+	}
+	r.check()
+	resolvers[name] = r
+	go r.watch()
+	logging.Logger.Info("DB replicas initialized", zap.String("name", name), zap.Int("count", len(r.replicas)))
+}
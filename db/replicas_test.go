@@ -0,0 +1,60 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+type Item struct {
+	ID   uint
+	Name string
+}
+
+func openSqlite(t *testing.T, name string) *gorm.DB {
+	conn, err := gorm.Open(sqlite.Open("file:"+name+"?mode=memory&cache=shared"), &gorm.Config{NamingStrategy: AsIsNamingStrategy()})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %s", err)
+	}
+	assert.NoError(t, conn.AutoMigrate(&Item{}))
+	return conn
+}
+
+func TestReplicaResolver(t *testing.T) {
+	primary := openSqlite(t, t.Name()+"primary")
+	replicaDB := openSqlite(t, t.Name()+"replica")
+	assert.NoError(t, replicaDB.Create(&Item{Name: "replica"}).Error)
+
+	rep := &replica{conn: replicaDB, pool: replicaDB.ConnPool, healthy: 1}
+	r := &replicaResolver{name: "test", replicas: []*replica{rep}}
+	assert.NoError(t, primary.Use(r))
+	assert.NoError(t, primary.Create(&Item{Name: "primary"}).Error)
+
+	read := func(DB *gorm.DB) string {
+		var item Item
+		assert.NoError(t, DB.First(&item).Error)
+		return item.Name
+	}
+
+	assert.Equal(t, "replica", read(primary))
+	assert.Equal(t, "primary", read(primary.WithContext(WithPrimary(context.Background()))))
+
+	// sticky context reads from the replica until the first write
+	ctx := WithSticky(context.Background())
+	assert.Equal(t, "replica", read(primary.WithContext(ctx)))
+	assert.NoError(t, primary.WithContext(ctx).Create(&Item{Name: "other"}).Error)
+	assert.Equal(t, "primary", read(primary.WithContext(ctx)))
+
+	// transactions stay on the primary
+	assert.NoError(t, primary.Transaction(func(tx *gorm.DB) error {
+		assert.Equal(t, "primary", read(tx))
+		return nil
+	}))
+
+	// unhealthy replicas are skipped
+	rep.healthy = 0
+	assert.Equal(t, "primary", read(primary))
+}
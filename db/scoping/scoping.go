@@ -0,0 +1,207 @@
+// Package scoping provides the gorm callbacks which bind entities to a column value read from the statement context.
+// Reads, updates and deletes are scoped with the value, creates fill it and updates can't change it.
+// It is the base of the ownership (OwnerID of the claims) and tenancy (TenantID of the context) plugins.
+//
+//	func (p Plugin) Initialize(db *gorm.DB) error {
+//		return scoping.Column{Name: "app:region", Field: "RegionID", Matches: isRegional, Value: p.region}.Register(db)
+//	}
+package scoping
+
+import (
+	"context"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// Column scopes the entities which have the Field by the value of the statement context.
+type Column struct {
+	// Name prefixes the names of the callbacks, e.g. sincap:ownership registers sincap:ownership:query etc.
+	Name string
+	// Field is the name of the scoped field, e.g. OwnerID
+	Field string
+	// Matches reports whether the model type is scoped
+	Matches func(reflect.Type) bool
+	// Value returns the value of the statement. ok false leaves the statement as it is, Value may add an error to the
+	// statement (e.g. for strict modes). bypass skips the conditions and keeps the values given on create (e.g. admins).
+	Value func(db *gorm.DB) (value any, bypass bool, ok bool)
+	// ErrOther is returned if a save tries to overwrite a record with another value
+	ErrOther error
+}
+
+// Register registers the callbacks of the column to the given db
+func (c Column) Register(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register(c.Name+":query", c.scope); err != nil {
+		return err
+	}
+	if err := cb.Row().Before("gorm:row").Register(c.Name+":row", c.scope); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register(c.Name+":update", c.update); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register(c.Name+":delete", c.delete); err != nil {
+		return err
+	}
+	return cb.Create().Before("gorm:create").Register(c.Name+":create", c.create)
+}
+
+// value returns the value and the field of the statement if the column must be applied
+func (c Column) value(db *gorm.DB) (any, *schema.Field, bool, bool) {
+	stmt := db.Statement
+	if stmt.Schema == nil || !c.Matches(stmt.Schema.ModelType) {
+		return nil, nil, false, false
+	}
+	field := stmt.Schema.LookUpField(c.Field)
+	if field == nil {
+		return nil, nil, false, false
+	}
+	value, bypass, ok := c.Value(db)
+	return value, field, bypass, ok
+}
+
+// scope adds the condition of the column to the statement
+func (c Column) scope(db *gorm.DB) {
+	value, field, bypass, ok := c.value(db)
+	if !ok || bypass {
+		return
+	}
+	where(db, field, value)
+}
+
+// delete scopes the statement like scope, deletes without conditions are rejected like GORM does
+func (c Column) delete(db *gorm.DB) {
+	value, field, bypass, ok := c.value(db)
+	if !ok || bypass {
+		return
+	}
+	if missingWhere(db) {
+		db.AddError(gorm.ErrMissingWhereClause)
+		return
+	}
+	where(db, field, value)
+}
+
+// missingWhere reports whether the statement has neither conditions nor primary keys. GORM rejects such updates
+// and deletes unless AllowGlobalUpdate is set, the condition of the column must not make them pass its check.
+func missingWhere(db *gorm.DB) bool {
+	stmt := db.Statement
+	if stmt.AllowGlobalUpdate {
+		return false
+	}
+	if c, ok := stmt.Clauses["WHERE"]; ok {
+		if where, ok := c.Expression.(clause.Where); ok && len(where.Exprs) > 0 {
+			return false
+		}
+	}
+	return !hasPrimaryKey(stmt, stmt.ReflectValue) && (stmt.Model == nil || !hasPrimaryKey(stmt, reflect.ValueOf(stmt.Model)))
+}
+
+// hasPrimaryKey reports whether any record of the value has a non-zero primary key
+func hasPrimaryKey(stmt *gorm.Statement, rv reflect.Value) bool {
+	rv = reflect.Indirect(rv)
+	if !rv.IsValid() || len(stmt.Schema.PrimaryFields) == 0 {
+		return false
+	}
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem() != stmt.Schema.ModelType && rv.Type().Elem() != reflect.PointerTo(stmt.Schema.ModelType) {
+			return false
+		}
+	case reflect.Struct:
+		if rv.Type() != stmt.Schema.ModelType {
+			return false
+		}
+	default:
+		return false
+	}
+	_, values := schema.GetIdentityFieldValuesMap(stmt.Context, rv, stmt.Schema.PrimaryFields)
+	return len(values) > 0
+}
+
+func where(db *gorm.DB, field *schema.Field, value any) {
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: value},
+	}})
+}
+
+// update scopes the statement and prevents moving the records to other values. Updates without conditions are
+// rejected like GORM does.
+func (c Column) update(db *gorm.DB) {
+	value, field, bypass, ok := c.value(db)
+	if !ok || bypass {
+		return
+	}
+	if missingWhere(db) {
+		db.AddError(gorm.ErrMissingWhereClause)
+		return
+	}
+	// keep the omits of the caller, Statement.Omit replaces them
+	db.Statement.Omits = append(db.Statement.Omits, field.DBName)
+	where(db, field, value)
+}
+
+// create fills the field of the records. Bypassed statements may create records for others so it is only filled if empty.
+func (c Column) create(db *gorm.DB) {
+	value, field, bypass, ok := c.value(db)
+	if !ok {
+		return
+	}
+	stmt := db.Statement
+	rv := reflect.Indirect(stmt.ReflectValue)
+	var records []reflect.Value
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			records = append(records, reflect.Indirect(rv.Index(i)))
+		}
+	case reflect.Struct:
+		records = append(records, rv)
+	}
+	for _, record := range records {
+		if _, isZero := field.ValueOf(stmt.Context, record); bypass && !isZero {
+			continue
+		}
+		if err := field.Set(stmt.Context, record, value); err != nil {
+			db.AddError(err)
+			return
+		}
+	}
+	// Save falls back to an upsert if nothing is updated. Do not let it overwrite the records of others.
+	if _, upsert := stmt.Clauses["ON CONFLICT"]; upsert && !bypass {
+		c.checkUpsert(db, records, field, value)
+	}
+}
+
+func (c Column) checkUpsert(db *gorm.DB, records []reflect.Value, field *schema.Field, value any) {
+	stmt := db.Statement
+	pk := stmt.Schema.PrioritizedPrimaryField
+	if pk == nil {
+		return
+	}
+	var ids []any
+	for _, record := range records {
+		if id, isZero := pk.ValueOf(stmt.Context, record); !isZero {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return
+	}
+	var count int64
+	err := db.Session(&gorm.Session{NewDB: true, Context: context.Background()}).
+		Table(stmt.Table).
+		Where(clause.IN{Column: clause.Column{Name: pk.DBName}, Values: ids}).
+		Where(clause.Neq{Column: clause.Column{Name: field.DBName}, Value: value}).
+		Count(&count).Error
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if count > 0 {
+		db.AddError(c.ErrOther)
+	}
+}
@@ -0,0 +1,77 @@
+package tenancy
+
+import (
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/db"
+	"github.com/filllabs/sincap-common/messages"
+	"github.com/gofiber/fiber/v2"
+)
+
+// Resolver reads the tenant id from the request. Returns false if it can't find any.
+type Resolver func(ctx *fiber.Ctx) (string, bool)
+
+// FromSubdomain resolves the tenant from the first subdomain (tenant.example.com).
+// offset is the number of the domain parts to skip from the right, default is 2.
+func FromSubdomain(offset ...int) Resolver {
+	return func(ctx *fiber.Ctx) (string, bool) {
+		subdomains := ctx.Subdomains(offset...)
+		if len(subdomains) == 0 || len(subdomains[0]) == 0 {
+			return "", false
+		}
+		return subdomains[0], true
+	}
+}
+
+// FromHeader resolves the tenant from the header with the given name
+func FromHeader(name string) Resolver {
+	return func(ctx *fiber.Ctx) (string, bool) {
+		tenantID := ctx.Get(name)
+		return tenantID, len(tenantID) > 0
+	}
+}
+
+// FromClaim resolves the tenant from the extra claim with the given key.
+// Claims are read from the given locals key which is filled by the authentication middleware.
+func FromClaim(localsKey string, extraKey string) Resolver {
+	return func(ctx *fiber.Ctx) (string, bool) {
+		c, ok := ctx.Locals(localsKey).(*claims.DecryptedClaims)
+		if !ok || c == nil {
+			return "", false
+		}
+		val, found := c.GetExtra(extraKey)
+		if !found {
+			return "", false
+		}
+		tenantID, ok := val.(string)
+		return tenantID, ok && len(tenantID) > 0
+	}
+}
+
+// Middleware resolves the tenant of the request with the first successful resolver
+// and puts it to the locals (db.TenantLocalsKey) and to the user context.
+// Responds 400 if none of the resolvers finds a tenant.
+func Middleware(resolvers ...Resolver) func(ctx *fiber.Ctx) error {
+	return func(ctx *fiber.Ctx) error {
+		for _, resolve := range resolvers {
+			if tenantID, ok := resolve(ctx); ok {
+				ctx.Locals(db.TenantLocalsKey, tenantID)
+				ctx.SetUserContext(db.WithTenant(ctx.UserContext(), tenantID))
+				return ctx.Next()
+			}
+		}
+		return ctx.Status(fiber.StatusBadRequest).JSON(map[string]string{"error": messages.Localize(ctx.UserContext(), messages.KeyTenantNotFound, nil)})
+	}
+}
+
+// DBMiddleware puts the connection of the request tenant to the locals with the given key (database per tenant mode).
+// Must be added after Middleware. Responds 404 if the tenant has no connection defined at db.Config.Tenants.
+func DBMiddleware(dbCtxKey string, name string) func(ctx *fiber.Ctx) error {
+	return func(ctx *fiber.Ctx) error {
+		tenantID, ok := ctx.Locals(db.TenantLocalsKey).(string)
+		if !ok || !db.HasTenant(name, tenantID) {
+			return ctx.Status(fiber.StatusNotFound).JSON(map[string]string{"error": messages.Localize(ctx.UserContext(), messages.KeyTenantNotFound, nil)})
+		}
+		ctx.Locals(dbCtxKey, db.GetTenant(name, tenantID))
+		return ctx.Next()
+	}
+}
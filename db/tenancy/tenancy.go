@@ -0,0 +1,83 @@
+// Package tenancy provides multi tenant support with two modes.
+//
+// Column mode: models embed util.TenantModel and Plugin scopes all queries with the TenantID of the context
+// and fills it on create.
+//
+//	db.DB().Use(tenancy.Plugin{})
+//	app.Use(tenancy.Middleware(tenancy.FromHeader("X-Tenant")))
+//
+// Database mode: each tenant has its own connection defined at db.Config.Tenants. db.Get(name) runs the statements
+// with a tenant at the context on its connection and DBMiddleware puts the connection to the locals for the services.
+//
+//	app.Use(tenancy.Middleware(tenancy.FromSubdomain()), tenancy.DBMiddleware("db", "default"))
+package tenancy
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/filllabs/sincap-common/db"
+	"github.com/filllabs/sincap-common/db/scoping"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/reflection"
+	"gorm.io/gorm"
+)
+
+// ErrNoTenant is returned at Strict mode if a tenant entity is accessed without a tenant at the context
+var ErrNoTenant = errors.New("tenancy: no tenant found at context")
+
+// ErrOtherTenant is returned if a save tries to overwrite a record of another tenant
+var ErrOtherTenant = errors.New("tenancy: record belongs to another tenant")
+
+const tenantField = "TenantID"
+
+var tenantModelType = reflect.TypeOf(util.TenantModel{})
+
+// tenantTypes caches the result of IsTenant per model type
+var tenantTypes sync.Map
+
+// Plugin scopes reads, updates and deletes of util.TenantModel entities with TenantID and fills TenantID on create.
+type Plugin struct {
+	// Strict makes operations on tenant entities fail with ErrNoTenant if there is no tenant at the context
+	Strict bool
+}
+
+// Name returns the name of the plugin
+func (p Plugin) Name() string {
+	return "sincap:tenancy"
+}
+
+// Initialize registers tenancy callbacks to the given db
+func (p Plugin) Initialize(db *gorm.DB) error {
+	return scoping.Column{
+		Name:     p.Name(),
+		Field:    tenantField,
+		Matches:  IsTenant,
+		Value:    p.tenant,
+		ErrOther: ErrOtherTenant,
+	}.Register(db)
+}
+
+// tenant returns the tenant of the statement context
+func (p Plugin) tenant(DB *gorm.DB) (any, bool, bool) {
+	tenantID, ok := db.TenantFromContext(DB.Statement.Context)
+	if !ok {
+		if p.Strict {
+			DB.AddError(ErrNoTenant)
+		}
+		return nil, false, false
+	}
+	return tenantID, false, true
+}
+
+// IsTenant checks if the given type embeds util.TenantModel
+func IsTenant(typ reflect.Type) bool {
+	typ = reflection.ExtractRealTypeField(typ)
+	if tenant, ok := tenantTypes.Load(typ); ok {
+		return tenant.(bool)
+	}
+	tenant := reflection.Embeds(typ, tenantModelType)
+	tenantTypes.Store(typ, tenant)
+	return tenant
+}
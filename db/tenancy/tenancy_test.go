@@ -0,0 +1,111 @@
+package tenancy
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/filllabs/sincap-common/db"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/gofiber/fiber/v2"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Product struct {
+	util.Model
+	util.TenantModel
+	Name string
+}
+
+func openDB(t *testing.T) *gorm.DB {
+	DB := dbtest.Open(t, &Product{})
+	assert.NoError(t, DB.Create(&[]Product{
+		{TenantModel: util.TenantModel{TenantID: "acme"}, Name: "anvil"},
+		{TenantModel: util.TenantModel{TenantID: "acme"}, Name: "rocket"},
+		{TenantModel: util.TenantModel{TenantID: "globex"}, Name: "doomsday device"},
+	}).Error)
+	assert.NoError(t, DB.Use(Plugin{}))
+	return DB
+}
+
+func TestPlugin(t *testing.T) {
+	DB := openDB(t)
+	acme := db.WithTenant(context.Background(), "acme")
+
+	var products []Product
+	assert.NoError(t, DB.WithContext(acme).Find(&products).Error)
+	assert.Len(t, products, 2)
+
+	result := DB.WithContext(acme).Model(&Product{Model: util.Model{ID: 3}}).Updates(map[string]any{"Name": "changed"})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+
+	result = DB.WithContext(acme).Model(&Product{Model: util.Model{ID: 1}}).Omit("Name").Updates(map[string]any{"Name": "omitted", "TenantID": "globex"})
+	assert.NoError(t, result.Error)
+	var anvil Product
+	assert.NoError(t, DB.First(&anvil, 1).Error)
+	assert.Equal(t, "anvil", anvil.Name)
+	assert.Equal(t, "acme", anvil.TenantID)
+
+	product := Product{Name: "glue", TenantModel: util.TenantModel{TenantID: "globex"}}
+	assert.NoError(t, DB.WithContext(acme).Create(&product).Error)
+	assert.Equal(t, "acme", product.TenantID)
+
+	stolen := Product{Model: util.Model{ID: 3}, Name: "stolen"}
+	assert.ErrorIs(t, DB.WithContext(acme).Save(&stolen).Error, ErrOtherTenant)
+
+	products = nil
+	assert.NoError(t, DB.Find(&products).Error)
+	assert.Len(t, products, 4)
+}
+
+func TestPluginMissingWhere(t *testing.T) {
+	DB := openDB(t)
+	acme := db.WithTenant(context.Background(), "acme")
+
+	assert.ErrorIs(t, DB.WithContext(acme).Delete(&Product{}).Error, gorm.ErrMissingWhereClause)
+	assert.ErrorIs(t, DB.WithContext(acme).Model(&Product{}).Update("Name", "all").Error, gorm.ErrMissingWhereClause)
+	var count int64
+	assert.NoError(t, DB.Model(&Product{}).Count(&count).Error)
+	assert.Equal(t, int64(3), count)
+
+	result := DB.WithContext(acme).Where("Name = ?", "anvil").Delete(&Product{})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+	result = DB.WithContext(acme).Delete(&Product{Model: util.Model{ID: 2}})
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(1), result.RowsAffected)
+	result = DB.WithContext(acme).Session(&gorm.Session{AllowGlobalUpdate: true}).Model(&Product{}).Update("Name", "all")
+	assert.NoError(t, result.Error)
+	assert.Equal(t, int64(0), result.RowsAffected)
+}
+
+func TestMiddleware(t *testing.T) {
+	app := fiber.New()
+	app.Use(Middleware(FromHeader("X-Tenant"), FromSubdomain()))
+	app.Get("/", func(ctx *fiber.Ctx) error {
+		tenantID, _ := db.TenantFromContext(ctx.UserContext())
+		return ctx.SendString(tenantID)
+	})
+
+	req := httptest.NewRequest("GET", "http://acme.example.com/", nil)
+	resp, err := app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+	body := make([]byte, 4)
+	resp.Body.Read(body)
+	assert.Equal(t, "acme", string(body))
+
+	req = httptest.NewRequest("GET", "http://example.com/", nil)
+	req.Header.Set("X-Tenant", "globex")
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	req = httptest.NewRequest("GET", "http://example.com/", nil)
+	resp, err = app.Test(req)
+	assert.NoError(t, err)
+	assert.Equal(t, 400, resp.StatusCode)
+}
@@ -0,0 +1,118 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/filllabs/sincap-common/locals"
+	"gorm.io/gorm"
+)
+
+type contextKey string
+
+const tenantCtxKey contextKey = "tenant"
+
+// TenantLocalsKey is the fiber locals key which holds the tenant id of the request.
+// It is read from c.Context() as well as c.UserContext(), see locals.Value.
+const TenantLocalsKey = "tenant"
+
+// WithTenant returns a copy of the context which carries the given tenant id
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantCtxKey, tenantID)
+}
+
+// TenantFromContext returns the tenant id put by WithTenant or the tenancy middleware if any
+func TenantFromContext(ctx context.Context) (string, bool) {
+	return locals.Value[string](ctx, tenantCtxKey, TenantLocalsKey)
+}
+
+// HasTenant checks if a dedicated connection is configured for the given tenant
+func HasTenant(name string, tenantID string) bool {
+	_, ok := db[tenantConnectionName(name, tenantID)]
+	return ok
+}
+
+// GetTenant returns the connection of the tenant defined at Config.Tenants.
+// If there is no dedicated connection for the tenant, the shared connection with the given name is returned.
+// Get(name) routes to the same connection by the tenant of the statement context, GetTenant also works without one.
+func GetTenant(name string, tenantID string) *gorm.DB {
+	if conn, ok := db[tenantConnectionName(name, tenantID)]; ok {
+		return conn
+	}
+	return Get(name)
+}
+
+// GetContext returns the connection with the given name for the tenant at the context.
+func GetContext(ctx context.Context, name string) *gorm.DB {
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		return GetTenant(name, tenantID)
+	}
+	return Get(name)
+}
+
+func tenantConnectionName(name string, tenantID string) string {
+	return name + "/" + tenantID
+}
+
+// tenantPool routes the statements of a shared connection to the connection of the tenant at the statement context.
+// Statements without a tenant or of the tenants without a dedicated connection run on the shared pool.
+type tenantPool struct {
+	name   string
+	shared gorm.ConnPool
+}
+
+// routeTenants makes Get(name) run the statements with a tenant at the context (see WithTenant) on the connections
+// of Config.Tenants. Callbacks of the shared connection still apply, only the pool is switched.
+func routeTenants(name string, conn *gorm.DB) {
+	pool := &tenantPool{name: name, shared: conn.ConnPool}
+	conn.ConnPool = pool
+	conn.Statement.ConnPool = pool
+}
+
+// pool returns the pool of the tenant at the context, the shared one if there is none
+func (p *tenantPool) pool(ctx context.Context) gorm.ConnPool {
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		if conn, ok := db[tenantConnectionName(p.name, tenantID)]; ok {
+			return conn.ConnPool
+		}
+	}
+	return p.shared
+}
+
+func (p *tenantPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return p.pool(ctx).PrepareContext(ctx, query)
+}
+
+func (p *tenantPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.pool(ctx).ExecContext(ctx, query, args...)
+}
+
+func (p *tenantPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return p.pool(ctx).QueryContext(ctx, query, args...)
+}
+
+func (p *tenantPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return p.pool(ctx).QueryRowContext(ctx, query, args...)
+}
+
+// BeginTx starts the transaction on the pool of the tenant, the statements of the transaction use it directly
+func (p *tenantPool) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	switch beginner := p.pool(ctx).(type) {
+	case gorm.TxBeginner:
+		return beginner.BeginTx(ctx, opts)
+	case gorm.ConnPoolBeginner:
+		return beginner.BeginTx(ctx, opts)
+	}
+	return nil, gorm.ErrInvalidTransaction
+}
+
+// GetDBConn returns the shared sql.DB so pool settings, Ping and Close work on the shared connection
+func (p *tenantPool) GetDBConn() (*sql.DB, error) {
+	if connector, ok := p.shared.(gorm.GetDBConnector); ok {
+		return connector.GetDBConn()
+	}
+	if sdb, ok := p.shared.(*sql.DB); ok {
+		return sdb, nil
+	}
+	return nil, gorm.ErrInvalidDB
+}
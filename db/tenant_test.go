@@ -0,0 +1,39 @@
+package db
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestRouteTenants(t *testing.T) {
+	shared := openSqlite(t, t.Name()+"shared")
+	acmeDB := openSqlite(t, t.Name()+"acme")
+	db["tenants"] = shared
+	db["tenants/acme"] = acmeDB
+	defer delete(db, "tenants")
+	defer delete(db, "tenants/acme")
+	routeTenants("tenants", shared)
+
+	acme := WithTenant(context.Background(), "acme")
+	globex := WithTenant(context.Background(), "globex")
+	assert.NoError(t, Get("tenants").WithContext(acme).Create(&Item{Name: "anvil"}).Error)
+	assert.NoError(t, Get("tenants").WithContext(acme).Transaction(func(tx *gorm.DB) error {
+		return tx.Create(&Item{Name: "rocket"}).Error
+	}))
+	assert.NoError(t, Get("tenants").WithContext(globex).Create(&Item{Name: "shared"}).Error)
+
+	var count int64
+	assert.NoError(t, acmeDB.Model(&Item{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	assert.NoError(t, Get("tenants").WithContext(acme).Model(&Item{}).Count(&count).Error)
+	assert.Equal(t, int64(2), count)
+	assert.NoError(t, Get("tenants").Model(&Item{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+
+	sdb, err := Get("tenants").DB()
+	assert.NoError(t, err)
+	assert.NoError(t, sdb.Ping())
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const timeoutKey = "sincap:timeout"
+
+// timeout holds the original context and the cancel func of the statement
+type timeout struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// statementTimeout is a gorm plugin which cancels the statements running longer than the timeout.
+// Row callbacks are not limited since the returned rows are read after the callbacks.
+type statementTimeout struct {
+	timeout time.Duration
+}
+
+// Name returns the name of the plugin
+func (p *statementTimeout) Name() string {
+	return "sincap:timeout"
+}
+
+// Initialize registers timeout callbacks to the given db
+func (p *statementTimeout) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	if err := cb.Query().Before("gorm:query").Register("sincap:timeout:query", p.start); err != nil {
+		return err
+	}
+	if err := cb.Query().After("gorm:query").Register("sincap:timeout:end_query", p.end); err != nil {
+		return err
+	}
+	if err := cb.Create().Before("gorm:create").Register("sincap:timeout:create", p.start); err != nil {
+		return err
+	}
+	if err := cb.Create().After("gorm:create").Register("sincap:timeout:end_create", p.end); err != nil {
+		return err
+	}
+	if err := cb.Update().Before("gorm:update").Register("sincap:timeout:update", p.start); err != nil {
+		return err
+	}
+	if err := cb.Update().After("gorm:update").Register("sincap:timeout:end_update", p.end); err != nil {
+		return err
+	}
+	if err := cb.Delete().Before("gorm:delete").Register("sincap:timeout:delete", p.start); err != nil {
+		return err
+	}
+	if err := cb.Delete().After("gorm:delete").Register("sincap:timeout:end_delete", p.end); err != nil {
+		return err
+	}
+	if err := cb.Raw().Before("gorm:raw").Register("sincap:timeout:raw", p.start); err != nil {
+		return err
+	}
+	return cb.Raw().After("gorm:raw").Register("sincap:timeout:end_raw", p.end)
+}
+
+// start replaces the context of the statement with a timeout context unless it already has an earlier deadline
+func (p *statementTimeout) start(db *gorm.DB) {
+	stmt := db.Statement
+	ctx := stmt.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= p.timeout {
+		return
+	}
+	limited, cancel := context.WithTimeout(ctx, p.timeout)
+	db.InstanceSet(timeoutKey, &timeout{ctx: stmt.Context, cancel: cancel})
+	stmt.Context = limited
+}
+
+// end cancels the timeout context and restores the original one
+func (p *statementTimeout) end(db *gorm.DB) {
+	value, _ := db.InstanceGet(timeoutKey)
+	t, ok := value.(*timeout)
+	if !ok || t == nil {
+		return
+	}
+	t.cancel()
+	db.Statement.Context = t.ctx
+	db.InstanceSet(timeoutKey, nil)
+}
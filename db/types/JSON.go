@@ -25,11 +25,14 @@ func (j *JSON) Scan(value interface{}) error {
 		*j = nil
 		return nil
 	}
-	s, ok := value.([]byte)
-	if !ok {
+	switch s := value.(type) {
+	case []byte:
+		*j = append((*j)[0:0], s...)
+	case string:
+		*j = append((*j)[0:0], s...)
+	default:
 		return errors.New("invalid scan source")
 	}
-	*j = append((*j)[0:0], s...)
 	return nil
 }
 
@@ -42,6 +42,8 @@ func DropRelationTables(DB *gorm.DB, models ...interface{}) {
 
 // AutoMigrate migrates all models defined
 // It is for creating tables,relations and indexdes.
+//
+// Deprecated: use versioned migrations of the migrate package. migrate.Generate creates the migration from the models.
 func AutoMigrate(command string, dbconfig db.Config, DB *gorm.DB, models ...interface{}) {
 	logging.Logger.Info("AutoMigrating all tables")
 	migCmds := dbconfig.AutoMigrate
@@ -31,3 +31,8 @@ type PolymorphicModel struct {
 type OwnedModel struct {
 	OwnerID uint `gorm:"index;not null"`
 }
+
+// TenantModel helps to make your model having a tenant restriction
+type TenantModel struct {
+	TenantID string `gorm:"index;not null;size:64"`
+}
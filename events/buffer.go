@@ -0,0 +1,67 @@
+package events
+
+import (
+	"context"
+	"sync"
+)
+
+type bufferKey string
+
+const bufferCtxKey bufferKey = "eventBuffer"
+
+type buffered struct {
+	bus   *Bus
+	event Event
+}
+
+// buffer holds the events published in a transaction
+type buffer struct {
+	mu     sync.Mutex
+	events []buffered
+	parent *buffer
+}
+
+func (b *buffer) add(bus *Bus, e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.events = append(b.events, buffered{bus: bus, event: e})
+}
+
+func (b *buffer) take() []buffered {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	events := b.events
+	b.events = nil
+	return events
+}
+
+// Buffer returns a copy of the context which holds the events published with it until Flush or Discard.
+// Buffers may be nested, flushing an inner buffer moves its events to the outer one.
+func Buffer(ctx context.Context) context.Context {
+	parent, _ := ctx.Value(bufferCtxKey).(*buffer)
+	return context.WithValue(ctx, bufferCtxKey, &buffer{parent: parent})
+}
+
+// Flush delivers the events held by the buffer of the context, usually after commit
+func Flush(ctx context.Context) {
+	b, ok := ctx.Value(bufferCtxKey).(*buffer)
+	if !ok || b == nil {
+		return
+	}
+	// events published by the subscribers are not buffered
+	unbuffered := context.WithValue(ctx, bufferCtxKey, (*buffer)(nil))
+	for _, item := range b.take() {
+		if b.parent != nil {
+			b.parent.add(item.bus, item.event)
+			continue
+		}
+		item.bus.deliver(unbuffered, item.event)
+	}
+}
+
+// Discard drops the events held by the buffer of the context, usually after rollback
+func Discard(ctx context.Context) {
+	if b, ok := ctx.Value(bufferCtxKey).(*buffer); ok && b != nil {
+		b.take()
+	}
+}
@@ -0,0 +1,246 @@
+// Package events provides an in-process event bus for entity changes. GormService publishes Created, Updated
+// and Deleted events when a bus is set (see GormService.WithEvents).
+//
+//	events.Default.Subscribe("Country", func(ctx context.Context, e events.Event) error { ... })
+//	events.Default.SubscribeAsync("", indexer) // all entities
+//
+// Sync subscribers run in the publishing goroutine in subscription order, async subscribers run in their own
+// goroutine in publishing order. Events published inside a buffered context (see Buffer) are held until Flush,
+// so subscribers only see committed changes.
+package events
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/filllabs/sincap-common/logging"
+	"go.uber.org/zap"
+)
+
+// Type is the type of the change
+type Type string
+
+const (
+	// Created is published after a record is created
+	Created Type = "created"
+	// Updated is published after a record is updated
+	Updated Type = "updated"
+	// Deleted is published after a record is deleted
+	Deleted Type = "deleted"
+)
+
+// asyncQueueSize is the buffer size of the async subscribers, publishing blocks when it is full
+const asyncQueueSize = 256
+
+// Event is a change of an entity record
+type Event struct {
+	Type Type
+	// Entity is the table name of the record
+	Entity string
+	// ID holds the primary key values joined with "," (empty for bulk updates)
+	ID string
+	// Before is a copy of the record before the change (nil for creates)
+	Before any
+	// After is the record after the change (nil for deletes)
+	After     any
+	ActorID   uint
+	ActorName string
+	Time      time.Time
+}
+
+// Handler handles the events. Errors are logged.
+type Handler func(ctx context.Context, e Event) error
+
+type subscriber struct {
+	id      uint64
+	entity  string
+	handler Handler
+	queue   chan delivery
+	// mu guards the queue against the sends after close, senders hold the read lock
+	mu     sync.RWMutex
+	closed bool
+}
+
+type delivery struct {
+	ctx   context.Context
+	event Event
+}
+
+// Bus delivers the published events to the subscribers
+type Bus struct {
+	mu     sync.RWMutex
+	subs   []*subscriber
+	nextID uint64
+	wg     sync.WaitGroup
+	closed bool
+}
+
+// Default is the bus of the application
+var Default = NewBus()
+
+// NewBus returns an empty bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler which is called synchronously for the events of the entity, empty entity means all.
+// Returns the function which unsubscribes the handler.
+func (b *Bus) Subscribe(entity string, handler Handler) func() {
+	return b.subscribe(&subscriber{entity: entity, handler: handler})
+}
+
+// SubscribeAsync registers a handler which is called in its own goroutine for the events of the entity, empty entity means all.
+// The context of the delivered events is detached from the cancellation of the publisher.
+// Returns the function which unsubscribes the handler after delivering the queued events.
+func (b *Bus) SubscribeAsync(entity string, handler Handler) func() {
+	s := &subscriber{entity: entity, handler: handler, queue: make(chan delivery, asyncQueueSize)}
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+		for d := range s.queue {
+			s.call(d.ctx, d.event)
+		}
+	}()
+	return b.subscribe(s)
+}
+
+func (b *Bus) subscribe(s *subscriber) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.nextID++
+	s.id = b.nextID
+	b.subs = append(b.subs, s)
+	var once sync.Once
+	return func() {
+		once.Do(func() { b.unsubscribe(s.id) })
+	}
+}
+
+func (b *Bus) unsubscribe(id uint64) {
+	b.mu.Lock()
+	var removed *subscriber
+	for i, s := range b.subs {
+		if s.id == id {
+			b.subs = append(b.subs[:i:i], b.subs[i+1:]...)
+			removed = s
+			break
+		}
+	}
+	b.mu.Unlock()
+	// a publisher may be waiting for the queue, close it without blocking the bus
+	if removed != nil {
+		removed.close()
+	}
+}
+
+// HasSubscribers reports whether any handler is subscribed to the entity
+func (b *Bus) HasSubscribers(entity string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, s := range b.subs {
+		if s.entity == "" || s.entity == entity {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish delivers the event to the subscribers of its entity. If the context is buffered (see Buffer)
+// the event is held until Flush.
+func (b *Bus) Publish(ctx context.Context, e Event) {
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	if buf, ok := ctx.Value(bufferCtxKey).(*buffer); ok && buf != nil {
+		buf.add(b, e)
+		return
+	}
+	b.deliver(ctx, e)
+}
+
+// deliver sends the event to the matching subscribers after releasing the lock of the bus, so the handlers may
+// publish and subscribe while a publisher waits for a full queue
+func (b *Bus) deliver(ctx context.Context, e Event) {
+	b.mu.RLock()
+	if b.closed {
+		b.mu.RUnlock()
+		return
+	}
+	var async, direct []*subscriber
+	for _, s := range b.subs {
+		if s.entity != "" && s.entity != e.Entity {
+			continue
+		}
+		if s.queue != nil {
+			async = append(async, s)
+		} else {
+			direct = append(direct, s)
+		}
+	}
+	b.mu.RUnlock()
+	for _, s := range async {
+		s.send(delivery{ctx: detached{ctx}, event: e})
+	}
+	for _, s := range direct {
+		s.call(ctx, e)
+	}
+}
+
+// Close unsubscribes all handlers and waits until the async handlers deliver their queued events.
+// Events published after Close are dropped.
+func (b *Bus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := b.subs
+	b.subs = nil
+	b.mu.Unlock()
+	for _, s := range subs {
+		s.close()
+	}
+	b.wg.Wait()
+}
+
+// send queues the delivery of an async subscriber, it is dropped if the subscriber is closed
+func (s *subscriber) send(d delivery) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if !s.closed {
+		s.queue <- d
+	}
+}
+
+// close closes the queue of an async subscriber once, the queued deliveries are still handled
+func (s *subscriber) close() {
+	if s.queue == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.queue)
+	}
+}
+
+func (s *subscriber) call(ctx context.Context, e Event) {
+	defer func() {
+		if r := recover(); r != nil {
+			logging.Logger.Named("Events").Error("Handler panicked", zap.String("entity", e.Entity), zap.String("type", string(e.Type)), zap.String("panic", fmt.Sprint(r)))
+		}
+	}()
+	if err := s.handler(ctx, e); err != nil {
+		logging.Logger.Named("Events").Error("Handler failed", zap.String("entity", e.Entity), zap.String("type", string(e.Type)), zap.String("id", e.ID), zap.Error(err))
+	}
+}
+
+// detached keeps the values of the context without its deadline and cancellation
+type detached struct {
+	parent context.Context
+}
+
+func (d detached) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (d detached) Done() <-chan struct{}             { return nil }
+func (d detached) Err() error                        { return nil }
+func (d detached) Value(key interface{}) interface{} { return d.parent.Value(key) }
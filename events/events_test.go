@@ -0,0 +1,118 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBus(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	unsubscribe := bus.Subscribe("Country", func(ctx context.Context, e Event) error {
+		got = append(got, string(e.Type)+":"+e.ID)
+		return nil
+	})
+	bus.Subscribe("", func(ctx context.Context, e Event) error {
+		panic("recovered")
+	})
+	bus.Subscribe("", func(ctx context.Context, e Event) error {
+		return errors.New("logged")
+	})
+	var (
+		mu    sync.Mutex
+		async []string
+	)
+	bus.SubscribeAsync("", func(ctx context.Context, e Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		async = append(async, e.Entity+":"+e.ID)
+		return nil
+	})
+	assert.True(t, bus.HasSubscribers("Currency"))
+
+	ctx := context.Background()
+	bus.Publish(ctx, Event{Type: Created, Entity: "Country", ID: "1"})
+	bus.Publish(ctx, Event{Type: Created, Entity: "Currency", ID: "2"})
+	unsubscribe()
+	bus.Publish(ctx, Event{Type: Deleted, Entity: "Country", ID: "1"})
+	bus.Close()
+
+	assert.Equal(t, []string{"created:1"}, got)
+	assert.Equal(t, []string{"Country:1", "Currency:2", "Country:1"}, async)
+
+	// closed bus drops the events
+	bus.Publish(ctx, Event{Type: Created, Entity: "Country", ID: "3"})
+	assert.Len(t, async, 3)
+}
+
+func TestBuffer(t *testing.T) {
+	bus := NewBus()
+	var got []string
+	bus.Subscribe("", func(ctx context.Context, e Event) error {
+		got = append(got, e.ID)
+		// events published by the subscribers are delivered immediately
+		if e.ID == "1" {
+			bus.Publish(ctx, Event{Entity: "Log", ID: "log"})
+		}
+		return nil
+	})
+
+	ctx := Buffer(context.Background())
+	bus.Publish(ctx, Event{Entity: "Country", ID: "1"})
+	inner := Buffer(ctx)
+	bus.Publish(inner, Event{Entity: "Country", ID: "2"})
+	Flush(inner)
+	assert.Empty(t, got)
+	Flush(ctx)
+	assert.Equal(t, []string{"1", "log", "2"}, got)
+
+	got = nil
+	ctx = Buffer(context.Background())
+	bus.Publish(ctx, Event{Entity: "Country", ID: "3"})
+	Discard(ctx)
+	Flush(ctx)
+	assert.Empty(t, got)
+}
+
+func TestBusFullQueue(t *testing.T) {
+	bus := NewBus()
+	release := make(chan struct{})
+	var once sync.Once
+	bus.SubscribeAsync("Country", func(ctx context.Context, e Event) error {
+		once.Do(func() { <-release })
+		// handlers publish while a publisher waits for the full queue and a subscriber for the lock
+		bus.Publish(ctx, Event{Entity: "Log", ID: e.ID})
+		return nil
+	})
+
+	ctx := context.Background()
+	for i := 0; i <= asyncQueueSize; i++ {
+		bus.Publish(ctx, Event{Entity: "Country"})
+	}
+	go bus.Publish(ctx, Event{Entity: "Country"})
+	time.Sleep(10 * time.Millisecond)
+	subscribed := make(chan struct{})
+	go func() {
+		bus.Subscribe("Log", func(ctx context.Context, e Event) error { return nil })
+		close(subscribed)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+
+	done := make(chan struct{})
+	go func() {
+		<-subscribed
+		bus.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("bus is deadlocked")
+	}
+}
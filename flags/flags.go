@@ -22,3 +22,11 @@ func Parse(fArr ...string) (*flag.FlagSet, error) {
 
 // Defaults are the information needed for config and commanf flags.
 var Defaults = []string{"config", "config.json", "Location of the config file.", "command", "server", "Command for the executable."}
+
+// Migrate is the flag for the migration command. It is read when the command is "migrate".
+// Possible values are "up", "down", "status" and "to {version}".
+var Migrate = []string{"migrate", "up", "Migration command (up, down, status or to {version})."}
+
+// Translations is the flag for the translation exchange command. It is read when the command is "translations".
+// Possible values are "export {format} {source} {target} {file}" and "import {format} {file} [dry-run]".
+var Translations = []string{"translations", "", "Translation exchange command (export {format} {source} {target} {file} or import {format} {file} [dry-run])."}
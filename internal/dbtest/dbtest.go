@@ -0,0 +1,26 @@
+// Package dbtest opens the databases of the tests
+package dbtest
+
+import (
+	"testing"
+
+	"github.com/filllabs/sincap-common/db"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Open opens a sqlite memory database named after the test with the naming strategy of the db package
+// and migrates the given models. Databases of the same test share their data.
+func Open(t testing.TB, models ...interface{}) *gorm.DB {
+	t.Helper()
+	DB, err := gorm.Open(sqlite.Open("file:"+t.Name()+"?mode=memory&cache=shared"), &gorm.Config{NamingStrategy: db.AsIsNamingStrategy()})
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %s", err)
+	}
+	if len(models) > 0 {
+		if err := DB.AutoMigrate(models...); err != nil {
+			t.Fatalf("failed to migrate: %s", err)
+		}
+	}
+	return DB
+}
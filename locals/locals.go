@@ -0,0 +1,24 @@
+// Package locals reads request scoped values from contexts filled by context helpers or by fiber middlewares.
+//
+// Since fiber's request context (c.Context()) resolves string keys from the locals of the request, a value stored
+// with c.Locals(key, v) is also returned by c.Context().Value(key). Packages keep a private key for their With*
+// helpers and an exported string key for the locals, Value reads both so c.Context() works as well as c.UserContext().
+//
+//	const LocalsKey = "tenant"
+//	tenantID, ok := locals.Value[string](ctx, tenantCtxKey, LocalsKey)
+package locals
+
+import "context"
+
+// Value returns the value stored with ctxKey or the locals key. Zero values (e.g. nil pointers, "") are missing.
+func Value[T comparable](ctx context.Context, ctxKey any, localsKey string) (T, bool) {
+	var zero T
+	if ctx == nil {
+		return zero, false
+	}
+	if v, ok := ctx.Value(ctxKey).(T); ok && v != zero {
+		return v, true
+	}
+	v, ok := ctx.Value(localsKey).(T)
+	return v, ok && v != zero
+}
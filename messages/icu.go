@@ -0,0 +1,223 @@
+package messages
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales"
+	"github.com/go-playground/locales/ar"
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/es"
+	"github.com/go-playground/locales/fa"
+	"github.com/go-playground/locales/fr"
+	"github.com/go-playground/locales/id"
+	"github.com/go-playground/locales/it"
+	"github.com/go-playground/locales/ja"
+	"github.com/go-playground/locales/nl"
+	"github.com/go-playground/locales/pl"
+	"github.com/go-playground/locales/pt"
+	"github.com/go-playground/locales/ru"
+	"github.com/go-playground/locales/tr"
+	"github.com/go-playground/locales/zh"
+)
+
+// plurals are the CLDR plural rules by the base languages, others use the English rules
+var plurals = map[string]locales.Translator{}
+
+func init() {
+	for _, l := range []locales.Translator{ar.New(), de.New(), en.New(), es.New(), fa.New(), fr.New(), id.New(),
+		it.New(), ja.New(), nl.New(), pl.New(), pt.New(), ru.New(), tr.New(), zh.New()} {
+		plurals[l.Locale()] = l
+	}
+}
+
+// Format formats the ICU message pattern with the args in the language. Supported arguments are
+//
+//	{name}                                              the value of the arg
+//	{count, plural, =0 {no items} one {# item} other {# items}}   # is the number
+//	{gender, select, female {her} male {his} other {their}}
+//
+// Apostrophes quote the special characters ('{' is a brace) and two apostrophes are one like ICU. Missing args are written as is.
+func Format(lang string, pattern string, args map[string]any) string {
+	var sb strings.Builder
+	formatTo(&sb, lang, pattern, args, "")
+	return sb.String()
+}
+
+// formatTo writes the formatted pattern, hash is the number of the enclosing plural
+func formatTo(sb *strings.Builder, lang string, pattern string, args map[string]any, hash string) {
+	for i := 0; i < len(pattern); i++ {
+		ch := pattern[i]
+		switch {
+		case ch == '\'':
+			i = quoted(sb, pattern, i)
+		case ch == '#' && hash != "":
+			sb.WriteString(hash)
+		case ch == '{':
+			end := closing(pattern, i)
+			if end < 0 {
+				sb.WriteString(pattern[i:])
+				return
+			}
+			argument(sb, lang, pattern[i:end+1], args)
+			i = end
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+}
+
+// quoted writes the quoted text starting at i and returns its last index
+func quoted(sb *strings.Builder, pattern string, i int) int {
+	if i+1 < len(pattern) && pattern[i+1] == '\'' {
+		sb.WriteByte('\'')
+		return i + 1
+	}
+	if i+1 >= len(pattern) || strings.IndexByte("{}#|", pattern[i+1]) < 0 {
+		sb.WriteByte('\'')
+		return i
+	}
+	for j := i + 1; j < len(pattern); j++ {
+		if pattern[j] != '\'' {
+			sb.WriteByte(pattern[j])
+			continue
+		}
+		if j+1 < len(pattern) && pattern[j+1] == '\'' {
+			sb.WriteByte('\'')
+			j++
+			continue
+		}
+		return j
+	}
+	return len(pattern)
+}
+
+// closing returns the index of the brace closing the one at start, -1 if there is none
+func closing(pattern string, start int) int {
+	depth := 0
+	for i := start; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// argument writes the argument ({...}) with its value
+func argument(sb *strings.Builder, lang string, arg string, args map[string]any) {
+	parts := strings.SplitN(arg[1:len(arg)-1], ",", 3)
+	name := strings.TrimSpace(parts[0])
+	value, ok := args[name]
+	if !ok {
+		sb.WriteString(arg)
+		return
+	}
+	if len(parts) < 3 {
+		sb.WriteString(fmt.Sprint(value))
+		return
+	}
+	options := parseOptions(parts[2])
+	switch strings.TrimSpace(parts[1]) {
+	case "plural":
+		n, ok := number(value)
+		if !ok {
+			sb.WriteString(fmt.Sprint(value))
+			return
+		}
+		hash := strconv.FormatFloat(n, 'f', -1, 64)
+		message, found := options["="+hash]
+		if !found {
+			if message, found = options[pluralOf(lang, n, hash)]; !found {
+				message = options["other"]
+			}
+		}
+		formatTo(sb, lang, message, args, hash)
+	case "select":
+		message, found := options[fmt.Sprint(value)]
+		if !found {
+			message = options["other"]
+		}
+		formatTo(sb, lang, message, args, "")
+	default:
+		sb.WriteString(fmt.Sprint(value))
+	}
+}
+
+// parseOptions parses the "selector {message} ..." options of plural and select
+func parseOptions(s string) map[string]string {
+	options := map[string]string{}
+	for {
+		start := strings.IndexByte(s, '{')
+		if start < 0 {
+			return options
+		}
+		end := closing(s, start)
+		if end < 0 {
+			return options
+		}
+		options[strings.TrimSpace(s[:start])] = s[start+1 : end]
+		s = s[end+1:]
+	}
+}
+
+// pluralOf returns the CLDR plural category (zero, one, two, few, many or other) of the number in the language
+func pluralOf(lang string, n float64, formatted string) string {
+	l, ok := plurals[strings.ToLower(baseOf(lang))]
+	if !ok {
+		l = plurals["en"]
+	}
+	var v uint64
+	if i := strings.IndexByte(formatted, '.'); i >= 0 {
+		v = uint64(len(formatted) - i - 1)
+	}
+	return strings.ToLower(l.CardinalPluralRule(n, v).String())
+}
+
+func number(value any) (float64, bool) {
+	switch v := value.(type) {
+	case int:
+		return float64(v), true
+	case int8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(v, 64)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+// baseOf returns the base language of the tag (e.g. "de" for "de-AT")
+func baseOf(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		return tag[:i]
+	}
+	return tag
+}
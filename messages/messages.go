@@ -0,0 +1,167 @@
+// Package messages is the catalogue of the localized messages of the API. Messages are ICU patterns (see Format)
+// by language and key. Default holds the messages of the library in English and Turkish, applications add theirs.
+//
+//	messages.Default.Add("de-DE", map[string]string{messages.KeyRecordNotFound: "Datensatz nicht gefunden"})
+//	msg := messages.Localize(ctx, messages.KeyValidationFailed, map[string]any{"count": 2})
+//
+// The language of the context is the one set by translations.TranslationMiddleware.
+package messages
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+)
+
+// fallbackLang is the language of the messages when neither the requested nor the default language has them
+const fallbackLang = "en-US"
+
+// Keys of the messages produced by the library, they are named as <group>.<snake_case name> like the error codes
+const (
+	KeyInvalidRequest   = "request.invalid"
+	KeyForbiddenField   = "request.forbidden_field"
+	KeyValidationFailed = "validation.failed"
+	KeyTenantNotFound   = "tenant.not_found"
+	KeyRecordNotFound   = "error.not_found"
+	KeyForbidden        = "error.forbidden"
+	KeyDuplicate        = "error.duplicate"
+	KeyReferenced       = "error.referenced"
+	KeyInvalidReference = "error.invalid_reference"
+	KeyCheckViolation   = "error.check_violation"
+	KeyBusy             = "error.busy"
+	KeyTimeout          = "error.timeout"
+	KeyInternal         = "error.internal"
+)
+
+// validationKeyPrefix is the prefix of the keys of the validation tags (e.g. validation.iban)
+const validationKeyPrefix = "validation."
+
+// Catalogue holds the message patterns by language and key
+type Catalogue struct {
+	mu       sync.RWMutex
+	messages map[string]map[string]string
+}
+
+// NewCatalogue returns an empty catalogue
+func NewCatalogue() *Catalogue {
+	return &Catalogue{messages: map[string]map[string]string{}}
+}
+
+// Default is the catalogue of the application with the messages of the library
+var Default = NewCatalogue()
+
+func init() {
+	Default.Add("en-US", map[string]string{
+		KeyInvalidRequest:                    "invalid request",
+		KeyForbiddenField:                    "You cannot update {field} Field",
+		KeyValidationFailed:                  "{count, plural, one {# field is invalid} other {# fields are invalid}}",
+		KeyTenantNotFound:                    "tenant not found",
+		KeyRecordNotFound:                    "record not found",
+		KeyForbidden:                         "access denied",
+		KeyDuplicate:                         "record already exists",
+		KeyReferenced:                        "record is referenced by other records",
+		KeyInvalidReference:                  "referenced record does not exist",
+		KeyCheckViolation:                    "invalid value",
+		KeyBusy:                              "record is busy, try again",
+		KeyTimeout:                           "request timed out",
+		KeyInternal:                          "Internal Server Error",
+		validationKeyPrefix + "iban":         "{field} must be a valid IBAN",
+		validationKeyPrefix + "phone":        "{field} must be a valid phone number",
+		validationKeyPrefix + "plate":        "{field} must be a valid plate",
+		validationKeyPrefix + "translations": "{field} must be translated to the required languages within the length limits",
+	})
+	Default.Add("tr-TR", map[string]string{
+		KeyInvalidRequest:                    "geçersiz istek",
+		KeyForbiddenField:                    "{field} alanını güncelleyemezsiniz",
+		KeyValidationFailed:                  "{count} alan geçersiz",
+		KeyTenantNotFound:                    "kiracı bulunamadı",
+		KeyRecordNotFound:                    "kayıt bulunamadı",
+		KeyForbidden:                         "erişim reddedildi",
+		KeyDuplicate:                         "kayıt zaten mevcut",
+		KeyReferenced:                        "kayıt başka kayıtlar tarafından kullanılıyor",
+		KeyInvalidReference:                  "ilişkili kayıt bulunamadı",
+		KeyCheckViolation:                    "geçersiz değer",
+		KeyBusy:                              "kayıt meşgul, tekrar deneyin",
+		KeyTimeout:                           "istek zaman aşımına uğradı",
+		KeyInternal:                          "Sunucu Hatası",
+		validationKeyPrefix + "iban":         "{field} geçerli bir IBAN olmalıdır",
+		validationKeyPrefix + "phone":        "{field} geçerli bir telefon numarası olmalıdır",
+		validationKeyPrefix + "plate":        "{field} geçerli bir plaka olmalıdır",
+		validationKeyPrefix + "translations": "{field} gerekli dillere uzunluk sınırları içinde çevrilmelidir",
+	})
+}
+
+// Add adds the messages of the language, existing keys are replaced
+func (c *Catalogue) Add(lang string, messages map[string]string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	m, ok := c.messages[lang]
+	if !ok {
+		m = make(map[string]string, len(messages))
+		c.messages[lang] = m
+	}
+	for key, message := range messages {
+		m[key] = message
+	}
+}
+
+// Lookup returns the pattern of the key in the language or the nearest one: a language with the same base
+// (de-AT → de-DE), the default language (see translations.DefaultLanguage) and en-US.
+func (c *Catalogue) Lookup(lang string, key string) (string, string, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if message, ok := c.messages[lang][key]; ok {
+		return lang, message, true
+	}
+	base := baseOf(lang)
+	var sameBase string
+	for code, m := range c.messages {
+		if _, ok := m[key]; !ok || !strings.EqualFold(baseOf(code), base) {
+			continue
+		}
+		// prefer the base's own region (de → de-DE) and then the smallest code to be deterministic
+		if sameBase == "" || strings.EqualFold(code, base+"-"+base) || (!strings.EqualFold(sameBase, base+"-"+base) && code < sameBase) {
+			sameBase = code
+		}
+	}
+	if sameBase != "" {
+		return sameBase, c.messages[sameBase][key], true
+	}
+	for _, code := range []string{translations.DefaultLanguage(), fallbackLang} {
+		if message, ok := c.messages[code][key]; ok {
+			return code, message, true
+		}
+	}
+	return "", "", false
+}
+
+// Message formats the message of the key in the language (see Lookup). Returns the key if there is no message.
+func (c *Catalogue) Message(lang string, key string, args map[string]any) string {
+	code, pattern, ok := c.Lookup(lang, key)
+	if !ok {
+		return key
+	}
+	return Format(code, pattern, args)
+}
+
+// Message formats the message of the key in the language with the Default catalogue
+func Message(lang string, key string, args map[string]any) string {
+	return Default.Message(lang, key, args)
+}
+
+// Localize formats the message of the key in the language of the context with the Default catalogue
+func Localize(ctx context.Context, key string, args map[string]any) string {
+	return Default.Message(Language(ctx), key, args)
+}
+
+// Language returns the language of the context (see translations.TranslationMiddleware), the default language otherwise
+func Language(ctx context.Context) string {
+	if ctx != nil {
+		if lang, ok := translations.LanguageFromContext(ctx); ok {
+			return lang
+		}
+	}
+	return translations.DefaultLanguage()
+}
@@ -0,0 +1,69 @@
+package messages
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/validator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormat(t *testing.T) {
+	items := "{count, plural, =0 {no items} one {# item} other {# items}}"
+	assert.Equal(t, "no items", Format("en-US", items, map[string]any{"count": 0}))
+	assert.Equal(t, "1 item", Format("en-US", items, map[string]any{"count": 1}))
+	assert.Equal(t, "2.5 items", Format("en-US", items, map[string]any{"count": 2.5}))
+
+	files := "{n, plural, one {# файл} few {# файла} many {# файлов} other {# файла}}"
+	assert.Equal(t, "21 файл", Format("ru-RU", files, map[string]any{"n": 21}))
+	assert.Equal(t, "3 файла", Format("ru-RU", files, map[string]any{"n": 3}))
+	assert.Equal(t, "5 файлов", Format("ru", files, map[string]any{"n": 5}))
+
+	assert.Equal(t, "Ayşe updated her profile", Format("en-US", "{name} updated {gender, select, female {her} male {his} other {their}} profile",
+		map[string]any{"name": "Ayşe", "gender": "female"}))
+	assert.Equal(t, "{missing} stays", Format("en-US", "{missing} stays", nil))
+	assert.Equal(t, "it's {literal} #1", Format("en-US", "it''s '{literal}' #1", nil))
+	assert.Equal(t, "unclosed {x", Format("en-US", "unclosed {x", map[string]any{"x": 1}))
+}
+
+func TestCatalogue(t *testing.T) {
+	c := NewCatalogue()
+	c.Add("en-US", map[string]string{"hello": "Hello {name}", "bye": "Bye"})
+	c.Add("de-DE", map[string]string{"hello": "Hallo {name}"})
+	c.Add("de-CH", map[string]string{"hello": "Grüezi {name}"})
+
+	assert.Equal(t, "Grüezi Ayşe", c.Message("de-CH", "hello", map[string]any{"name": "Ayşe"}))
+	assert.Equal(t, "Hallo Ayşe", c.Message("de-AT", "hello", map[string]any{"name": "Ayşe"}))
+	assert.Equal(t, "Bye", c.Message("de-DE", "bye", nil))
+	assert.Equal(t, "unknown", c.Message("de-DE", "unknown", nil))
+
+	ctx := context.WithValue(context.Background(), translations.LANG_KEY, "tr-TR")
+	assert.Equal(t, "kayıt bulunamadı", Localize(ctx, KeyRecordNotFound, nil))
+	assert.Equal(t, "record not found", Localize(context.Background(), KeyRecordNotFound, nil))
+	assert.Equal(t, "1 field is invalid", Message("en-GB", KeyValidationFailed, map[string]any{"count": 1}))
+}
+
+type account struct {
+	Name string `validate:"required"`
+	IBAN string `validate:"iban"`
+	Age  int    `validate:"gte=18"`
+}
+
+func TestFieldErrors(t *testing.T) {
+	err := validator.Validate.Struct(account{IBAN: "x", Age: 3})
+	assert.Equal(t, map[string]string{
+		"Name": "Name zorunlu bir alandır",
+		"IBAN": "IBAN geçerli bir IBAN olmalıdır",
+		"Age":  "Age, 18 veya daha büyük olmalıdır",
+	}, FieldErrors("tr-TR", err))
+	assert.Equal(t, "Name is a required field", FieldErrors("en-US", err)["Name"])
+	assert.Equal(t, "IBAN must be a valid IBAN", FieldErrors("fr-FR", err)["IBAN"])
+	assert.Equal(t, "Name est un champ obligatoire", FieldErrors("fr-FR", err)["Name"])
+
+	results := validator.Validate.ValidateMap(map[string]any{"Name": ""}, map[string]any{"Name": "required"})
+	assert.Equal(t, map[string]string{"Name": "Name is a required field"}, MapErrors("en-US", results))
+
+	results = validator.Validate.ValidateMap(map[string]any{"Title": map[string]any{"de-DE": nil}}, map[string]any{"Title": "translations=de-DE"})
+	assert.Equal(t, map[string]string{"Title": "Title gerekli dillere uzunluk sınırları içinde çevrilmelidir"}, MapErrors("tr-TR", results))
+}
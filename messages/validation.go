@@ -0,0 +1,127 @@
+package messages
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/filllabs/sincap-common/logging"
+	"github.com/filllabs/sincap-common/validator"
+	"github.com/go-playground/locales"
+	ut "github.com/go-playground/universal-translator"
+	playground "github.com/go-playground/validator/v10"
+	entrans "github.com/go-playground/validator/v10/translations/en"
+	estrans "github.com/go-playground/validator/v10/translations/es"
+	fatrans "github.com/go-playground/validator/v10/translations/fa"
+	frtrans "github.com/go-playground/validator/v10/translations/fr"
+	idtrans "github.com/go-playground/validator/v10/translations/id"
+	jatrans "github.com/go-playground/validator/v10/translations/ja"
+	nltrans "github.com/go-playground/validator/v10/translations/nl"
+	pttrans "github.com/go-playground/validator/v10/translations/pt"
+	rutrans "github.com/go-playground/validator/v10/translations/ru"
+	trtrans "github.com/go-playground/validator/v10/translations/tr"
+	zhtrans "github.com/go-playground/validator/v10/translations/zh"
+	"go.uber.org/zap"
+)
+
+// validationTranslations are the translators of the validator by the base languages, English is the fallback
+var validationTranslations *ut.UniversalTranslator
+
+func init() {
+	registers := map[string]func(v *playground.Validate, trans ut.Translator) error{
+		"en": entrans.RegisterDefaultTranslations,
+		"es": estrans.RegisterDefaultTranslations,
+		"fa": fatrans.RegisterDefaultTranslations,
+		"fr": frtrans.RegisterDefaultTranslations,
+		"id": idtrans.RegisterDefaultTranslations,
+		"ja": jatrans.RegisterDefaultTranslations,
+		"nl": nltrans.RegisterDefaultTranslations,
+		"pt": pttrans.RegisterDefaultTranslations,
+		"ru": rutrans.RegisterDefaultTranslations,
+		"tr": trtrans.RegisterDefaultTranslations,
+		"zh": zhtrans.RegisterDefaultTranslations,
+	}
+	supported := make([]locales.Translator, 0, len(registers))
+	for code := range registers {
+		supported = append(supported, plurals[code])
+	}
+	validationTranslations = ut.New(plurals["en"], supported...)
+	for code, register := range registers {
+		trans, _ := validationTranslations.GetTranslator(code)
+		if err := register(validator.Validate, trans); err != nil {
+			logging.Logger.Named("Messages").Warn("Can't register validation translations", zap.String("lang", code), zap.Error(err))
+		}
+	}
+}
+
+// FieldErrors returns the localized messages of the validation errors by their fields (namespaces without the struct name).
+// Messages of the catalogue ("validation.{tag}" with {field} and {param}) have priority over the translations of the validator.
+// Errors other than validator.ValidationErrors are returned under the "" key.
+func FieldErrors(lang string, err error) map[string]string {
+	result := map[string]string{}
+	var validationErrs playground.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		if err != nil {
+			result[""] = err.Error()
+		}
+		return result
+	}
+	for _, fe := range validationErrs {
+		result[fieldOf(fe)] = FieldError(lang, fe)
+	}
+	return result
+}
+
+// MapErrors returns the localized messages of the results of validator.Validate.ValidateMap by their keys
+func MapErrors(lang string, results map[string]any) map[string]string {
+	result := make(map[string]string, len(results))
+	for key, value := range results {
+		switch v := value.(type) {
+		case playground.ValidationErrors:
+			if len(v) > 0 {
+				result[key] = fieldMessage(lang, v[0], key)
+			}
+		case map[string]any:
+			for inner, message := range MapErrors(lang, v) {
+				result[key+"."+inner] = message
+			}
+		case error:
+			result[key] = v.Error()
+		}
+	}
+	return result
+}
+
+// FieldError returns the localized message of the validation error
+func FieldError(lang string, fe playground.FieldError) string {
+	return fieldMessage(lang, fe, fe.Field())
+}
+
+// fieldMessage returns the localized message of the validation error, field is used for the errors without a field (map rules)
+func fieldMessage(lang string, fe playground.FieldError, field string) string {
+	code, pattern, ok := Default.Lookup(lang, validationKeyPrefix+fe.Tag())
+	if ok && strings.EqualFold(baseOf(code), baseOf(lang)) {
+		return Format(code, pattern, map[string]any{"field": field, "param": fe.Param()})
+	}
+	trans, found := validationTranslations.GetTranslator(strings.ToLower(baseOf(lang)))
+	if found {
+		if message := fe.Translate(trans); message != fe.Error() {
+			if fe.Field() == "" {
+				return field + " " + strings.TrimSpace(message)
+			}
+			return message
+		}
+	}
+	if ok {
+		return Format(code, pattern, map[string]any{"field": field, "param": fe.Param()})
+	}
+	return fe.Error()
+}
+
+// fieldOf returns the namespace of the field without the struct name
+func fieldOf(fe playground.FieldError) string {
+	ns := fe.Namespace()
+	if i := strings.IndexByte(ns, '.'); i >= 0 {
+		return ns[i+1:]
+	}
+	return ns
+}
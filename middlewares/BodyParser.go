@@ -1,8 +1,7 @@
 package middlewares
 
 import (
-	"fmt"
-
+	"github.com/filllabs/sincap-common/messages"
 	"github.com/gofiber/fiber/v2"
 )
 
@@ -26,7 +25,7 @@ func BodyParserMap(key string, forbiddenFields ...string) func(ctx *fiber.Ctx) e
 		}
 		for _, key := range forbiddenFields {
 			if _, ok := t[key]; ok {
-				return ctx.Status(422).JSON(map[string]string{"error": fmt.Sprintf("You cannot update %s Field", key)})
+				return ctx.Status(422).JSON(map[string]string{"error": messages.Localize(ctx.UserContext(), messages.KeyForbiddenField, map[string]any{"field": key})})
 			}
 		}
 		ctx.Locals(key, &t)
@@ -2,35 +2,34 @@ package middlewares
 
 import (
 	"reflect"
+	"strings"
 
-	"github.com/filllabs/sincap-common/logging"
+	"github.com/filllabs/sincap-common/db/mysql"
 	"github.com/gofiber/fiber/v2"
-	"go.uber.org/zap"
 	"gorm.io/gorm"
 )
 
+// PathParamID reads the record of the path param from the database and puts it to the locals with the contextKey.
+// Keys are parsed by the types of the primary fields, so integer, string and UUID keys are supported.
+// For composite primary keys paramKey lists the params of the primary fields in order joined with ","
+// (e.g. "tenant,code"), a single param holding the values joined with "," is also accepted.
 func PathParamID(contextKey string, in interface{}, paramKey string, db *gorm.DB) func(ctx *fiber.Ctx) error {
 	t := reflect.TypeOf(in)
+	paramKeys := strings.Split(paramKey, ",")
 	return func(ctx *fiber.Ctx) error {
-		// parse handles the mime type
-		id, err := ctx.ParamsInt(paramKey, 0)
-		if err != nil || id == 0 {
-			return fiber.NewError(fiber.StatusNotFound)
+		params := make([]string, len(paramKeys))
+		for i, key := range paramKeys {
+			params[i] = ctx.Params(key)
 		}
-
 		record := reflect.New(t).Interface()
-		if err := read(db.Unscoped(), record, id); err != nil {
+		id, err := mysql.ParseID(db, record, params...)
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound)
+		}
+		if err := mysql.Read(db.Unscoped().WithContext(ctx.UserContext()), record, id); err != nil {
 			return fiber.NewError(fiber.StatusNotFound)
 		}
 		ctx.Locals(contextKey, record)
 		return ctx.Next()
 	}
 }
-
-func read(db *gorm.DB, record interface{}, id int, preloads ...string) error {
-	result := db.First(record, id)
-	if result.Error != nil {
-		logging.Logger.Error("Read error", zap.Any("Model", reflect.TypeOf(record)), zap.Error(result.Error), zap.Int("id", id))
-	}
-	return result.Error
-}
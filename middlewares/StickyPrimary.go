@@ -0,0 +1,15 @@
+package middlewares
+
+import (
+	"github.com/filllabs/sincap-common/db"
+	"github.com/gofiber/fiber/v2"
+)
+
+// StickyPrimary routes all reads of the request to the primary after its first write,
+// so the request reads its own writes even if the replicas are lagging.
+func StickyPrimary(ctx *fiber.Ctx) error {
+	s := &db.Sticky{}
+	ctx.Locals(db.StickyLocalsKey, s)
+	ctx.SetUserContext(db.WithStickyOf(ctx.UserContext(), s))
+	return ctx.Next()
+}
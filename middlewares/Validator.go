@@ -1,46 +1,68 @@
 package middlewares
 
 import (
-	"fmt"
 	"reflect"
 
+	"github.com/filllabs/sincap-common/messages"
 	"github.com/filllabs/sincap-common/validator"
 	"github.com/gofiber/fiber/v2"
 )
 
+// Validator validates the struct at the locals with the key in the context of the request (e.g. the language of the
+// translations tag). Errors are responded as 422 with the messages in the language of the request
+// (see messages.FieldErrors): {"error": "2 fields are invalid", "fields": {"Name": "..."}}
+// Invalid translations tags of the struct are returned as errors (see validator.CheckTranslations).
 func Validator(key string) func(ctx *fiber.Ctx) error {
 	return func(ctx *fiber.Ctx) error {
 		r := ctx.Locals(key)
 		if r == nil {
-			return ctx.Status(400).JSON(map[string]string{"error": "invalid request"})
+			return invalidRequest(ctx)
 		}
-		if err := validator.Validate.Struct(r); err != nil {
-			// TODO: beautify error messages
-			return ctx.Status(422).JSON(map[string]string{"error": err.Error()})
+		if err := validator.CheckTranslations(r); err != nil {
+			return err
+		}
+		if err := validator.Validate.StructCtx(ctx.UserContext(), r); err != nil {
+			return validationFailed(ctx, messages.FieldErrors(messages.Language(ctx.UserContext()), err))
 		}
 		return ctx.Next()
 	}
 }
+
+// ValidatorMap validates the map at the locals with the key by the validate tags of the fields of t.
+// Single language values of the translations tag are merged, so only their lengths are checked (see translations.Update).
+// Errors are responded like Validator. It panics if the translations tags of t are invalid (see validator.CheckTranslations).
 func ValidatorMap(key string, t any) func(ctx *fiber.Ctx) error {
+	if err := validator.CheckTranslations(t); err != nil {
+		panic(err)
+	}
 	return func(ctx *fiber.Ctx) error {
 		r, ok := ctx.Locals(key).(*map[string]any)
 		if !ok {
-			return ctx.Status(400).JSON(map[string]string{"error": "invalid request"})
+			return invalidRequest(ctx)
 		}
 
 		if r == nil {
-			return ctx.Status(400).JSON(map[string]string{"error": "invalid request"})
+			return invalidRequest(ctx)
 		}
 		rules := mapRulesFromFields(t, *r)
-		if results := validator.Validate.ValidateMap(*r, rules); len(results) > 0 {
-			// TODO: beautify error messages
-
-			return ctx.Status(422).JSON(map[string]string{"error": fmt.Sprintf("%v", results)})
+		if results := validator.Validate.ValidateMapCtx(ctx.UserContext(), *r, rules); len(results) > 0 {
+			return validationFailed(ctx, messages.MapErrors(messages.Language(ctx.UserContext()), results))
 		}
 		return ctx.Next()
 	}
 }
 
+func invalidRequest(ctx *fiber.Ctx) error {
+	return ctx.Status(400).JSON(map[string]string{"error": messages.Localize(ctx.UserContext(), messages.KeyInvalidRequest, nil)})
+}
+
+func validationFailed(ctx *fiber.Ctx, fields map[string]string) error {
+	return ctx.Status(422).JSON(map[string]any{
+		"error":  messages.Localize(ctx.UserContext(), messages.KeyValidationFailed, map[string]any{"count": len(fields)}),
+		"fields": fields,
+	})
+}
+
 func mapRulesFromFields(t any, rec map[string]any) map[string]any {
 	//TODO: add caching
 	fields := reflect.VisibleFields(reflect.TypeOf(t))
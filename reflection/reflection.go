@@ -28,3 +28,22 @@ func DepointerInteface(t interface{}) interface{} {
 	}
 	return v.Interface()
 }
+
+// Embeds checks if the given struct type embeds the target type at any depth of its anonymous fields
+func Embeds(typ reflect.Type, target reflect.Type) bool {
+	typ = ExtractRealTypeField(typ)
+	if typ.Kind() != reflect.Struct {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if !f.Anonymous {
+			continue
+		}
+		ft := DepointerField(f.Type)
+		if ft == target || Embeds(ft, target) {
+			return true
+		}
+	}
+	return false
+}
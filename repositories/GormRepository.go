@@ -19,6 +19,11 @@ func (rep *GormRepository) List(db *gorm.DB, records any, query *qapi.Query, lan
 	return mysql.List(db, records, query)
 }
 
+// Stream walks all records matching the query in batches and calls fn for each batch
+func (rep *GormRepository) Stream(db *gorm.DB, records any, query *qapi.Query, batchSize int, fn func(batch any) error) error {
+	return mysql.Each(db, records, query, batchSize, fn)
+}
+
 // Read retrieves a single record by its ID with optional preloads
 func (rep *GormRepository) Read(db *gorm.DB, record any, id any, preloads ...string) error {
 	return mysql.Read(db, record, id, preloads...)
@@ -29,9 +34,9 @@ func (rep *GormRepository) Create(db *gorm.DB, record any) error {
 	return mysql.Create(db, record)
 }
 
-// Update handles both full and partial updates
+// Update handles both full and partial updates, single language values of the Translations fields are merged
 func (rep *GormRepository) Update(db *gorm.DB, record any, fieldParams ...map[string]any) error {
-	return mysql.Update(db, record, fieldParams...)
+	return translations.Update(db, record, fieldParams...)
 }
 
 // Delete handles both single and bulk deletions
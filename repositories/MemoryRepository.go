@@ -0,0 +1,604 @@
+package repositories
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/filllabs/sincap-common/db/queryapi"
+	"github.com/filllabs/sincap-common/db/types"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/filllabs/sincap-common/reflection"
+	"gorm.io/gorm"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var deletedAtType = reflect.TypeOf(gorm.DeletedAt{})
+var jsonType = reflect.TypeOf(types.JSON{})
+
+// MemoryRepository is a Repository keeping the records in memory for the unit tests of the services.
+// Filters, sorts, _q, fields and pagination of the queries are evaluated in Go with the same rules of queryapi.
+// The db parameters are ignored, so writes are not rolled back with the transactions.
+//
+//	repo := repositories.NewMemoryRepository()
+//	repo.Create(nil, &User{Name: "ayse"})
+//	s := services.NewGormService("db").WithRepository(repo)
+//
+// Records are stored as shallow copies by their table names, so records of the other types with the same table
+// name (smart selects) read the fields with the same names. Associations are stored as parts of the records and
+// preloads are ignored. Integer primary keys are auto incremented, CreatedAt, UpdatedAt and DeletedAt
+// are handled like GORM. Missing records return gorm.ErrRecordNotFound, duplicate keys gorm.ErrDuplicatedKey.
+type MemoryRepository struct {
+	mu     sync.RWMutex
+	tables map[string]*memoryTable
+}
+
+type memoryTable struct {
+	pks    [][]int
+	names  []string
+	rows   []reflect.Value
+	nextID uint64
+}
+
+// NewMemoryRepository returns an empty repository
+func NewMemoryRepository() *MemoryRepository {
+	return &MemoryRepository{tables: map[string]*memoryTable{}}
+}
+
+// Reset removes all records
+func (rep *MemoryRepository) Reset() {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	rep.tables = map[string]*memoryTable{}
+}
+
+// List retrieves a list of records based on the given query, languages are ignored
+func (rep *MemoryRepository) List(db *gorm.DB, records any, query *qapi.Query, lang ...string) (int, error) {
+	elem, err := sliceOf(records)
+	if err != nil {
+		return 0, err
+	}
+	if query == nil {
+		query = &qapi.Query{}
+	}
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+	matched, err := rep.match(records, query)
+	if err != nil {
+		return 0, err
+	}
+	queryapi.SortRecords(query.Sort, matched)
+	count := len(matched)
+	if offset := query.Offset; offset > 0 {
+		if offset > len(matched) {
+			offset = len(matched)
+		}
+		matched = matched[offset:]
+	}
+	if query.Limit > 0 && query.Limit < len(matched) {
+		matched = matched[:query.Limit]
+	}
+	fill(elem, matched, query.Fields)
+	if query.Offset > 0 || query.Limit > 0 {
+		return count, nil
+	}
+	return elem.Len(), nil
+}
+
+// Stream walks all records matching the query in batches ordered by the primary keys like mysql.Each
+func (rep *MemoryRepository) Stream(db *gorm.DB, records any, query *qapi.Query, batchSize int, fn func(batch any) error) error {
+	elem, err := sliceOf(records)
+	if err != nil {
+		return err
+	}
+	if batchSize <= 0 {
+		return fmt.Errorf("batchSize must be greater than 0")
+	}
+	if query == nil {
+		query = &qapi.Query{}
+	}
+	matched, err := rep.snapshot(records, &qapi.Query{Q: query.Q, Filter: query.Filter})
+	if err != nil {
+		return err
+	}
+	for start := 0; start < len(matched); start += batchSize {
+		end := start + batchSize
+		if end > len(matched) {
+			end = len(matched)
+		}
+		elem.Set(reflect.MakeSlice(elem.Type(), 0, batchSize))
+		fill(elem, matched[start:end], query.Fields)
+		if err := fn(records); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot returns copies of the matching records ordered by the primary keys, so fn of Stream may write to the
+// repository and the writes do not change the batches
+func (rep *MemoryRepository) snapshot(records any, query *qapi.Query) ([]reflect.Value, error) {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+	matched, err := rep.match(records, query)
+	if err != nil || len(matched) == 0 {
+		return nil, err
+	}
+	for i, row := range matched {
+		matched[i] = copyOf(row)
+	}
+	table := rep.table(records, false)
+	sort.SliceStable(matched, func(i, j int) bool {
+		return table.less(matched[i], matched[j])
+	})
+	return matched, nil
+}
+
+// Read retrieves a single record by its ID in the forms of mysql.Read, preloads are ignored
+func (rep *MemoryRepository) Read(db *gorm.DB, record any, id any, preloads ...string) error {
+	rep.mu.RLock()
+	defer rep.mu.RUnlock()
+	table := rep.table(record, false)
+	if table == nil {
+		return gorm.ErrRecordNotFound
+	}
+	key, ok := table.idKey(id)
+	if !ok {
+		return gorm.ErrRecordNotFound
+	}
+	for _, row := range table.rows {
+		if !isDeleted(row) && table.key(row) == key {
+			copyFields(reflect.Indirect(reflect.ValueOf(record)), row, nil)
+			return nil
+		}
+	}
+	return gorm.ErrRecordNotFound
+}
+
+// Create inserts a new record and sets its auto incremented primary key and timestamps
+func (rep *MemoryRepository) Create(db *gorm.DB, record any) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	table := rep.table(record, true)
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("record must be a pointer to struct")
+	}
+	return table.insert(rv)
+}
+
+// Update handles both full and partial updates like mysql.Update
+func (rep *MemoryRepository) Update(db *gorm.DB, record any, fieldParams ...map[string]any) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	table := rep.table(record, true)
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("record must be a pointer to struct")
+	}
+	now := time.Now()
+	if len(fieldParams) == 0 {
+		// save inserts the records without primary keys
+		if !table.hasKey(rv) {
+			return table.insert(rv)
+		}
+		setTime(rv, "UpdatedAt", now, true)
+		key := table.key(rv)
+		for i, row := range table.rows {
+			if table.key(row) == key {
+				table.rows[i] = copyOf(rv)
+				return nil
+			}
+		}
+		return table.insert(rv)
+	}
+	if len(fieldParams) > 1 || fieldParams[0] == nil {
+		return fmt.Errorf("update failed: invalid fields parameter")
+	}
+	rows, err := table.find(rv)
+	if err != nil {
+		return err
+	}
+	fields := fieldParams[0]
+	for _, row := range rows {
+		for name, value := range fields {
+			if err := setField(row, name, value); err != nil {
+				return err
+			}
+		}
+		setTime(row, "UpdatedAt", now, true)
+	}
+	if table.hasKey(rv) && len(rows) == 1 {
+		// GORM assigns the updated values to the model
+		copyFields(rv, rows[0], nil)
+	}
+	return nil
+}
+
+// Delete handles both single and bulk deletions, records with DeletedAt are soft deleted
+func (rep *MemoryRepository) Delete(db *gorm.DB, record any, ids ...any) error {
+	rep.mu.Lock()
+	defer rep.mu.Unlock()
+	table := rep.table(record, true)
+	var rows []reflect.Value
+	if len(ids) == 0 {
+		var err error
+		if rows, err = table.find(reflect.Indirect(reflect.ValueOf(record))); err != nil {
+			return err
+		}
+	} else {
+		keys := map[string]bool{}
+		for _, id := range ids {
+			// slices are lists of ids for the single primary keys like mysql.PrimaryKeys
+			values := []any{id}
+			if len(table.pks) == 1 {
+				values = flatten(id)
+			}
+			for _, value := range values {
+				if key, ok := table.idKey(value); ok {
+					keys[key] = true
+				}
+			}
+		}
+		for _, row := range table.rows {
+			if !isDeleted(row) && keys[table.key(row)] {
+				rows = append(rows, row)
+			}
+		}
+	}
+	now := time.Now()
+	for _, row := range rows {
+		if deletedAt := row.FieldByName("DeletedAt"); deletedAt.IsValid() && deletedAt.Type() == deletedAtType {
+			deletedAt.Set(reflect.ValueOf(gorm.DeletedAt{Time: now, Valid: true}))
+			continue
+		}
+		table.remove(row)
+	}
+	return nil
+}
+
+// table returns the table of the record, creates it if create is true
+func (rep *MemoryRepository) table(record any, create bool) *memoryTable {
+	typ, name := queryapi.GetTableName(record)
+	table, ok := rep.tables[name]
+	if !ok && create {
+		pks := primaryKeys(typ)
+		table = &memoryTable{pks: pks, names: make([]string, len(pks))}
+		for i, index := range pks {
+			table.names[i] = typ.FieldByIndex(index).Name
+		}
+		rep.tables[name] = table
+	}
+	return table
+}
+
+// match returns the live records matching the filters and _q of the query in insertion order
+func (rep *MemoryRepository) match(records any, query *qapi.Query) ([]reflect.Value, error) {
+	table := rep.table(records, false)
+	if table == nil {
+		return nil, nil
+	}
+	var matched []reflect.Value
+	for _, row := range table.rows {
+		if isDeleted(row) {
+			continue
+		}
+		ok, err := queryapi.Match(query, row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			matched = append(matched, row)
+		}
+	}
+	return matched, nil
+}
+
+func (table *memoryTable) insert(rv reflect.Value) error {
+	if len(table.pks) == 1 {
+		pk := rv.FieldByIndex(table.pks[0])
+		switch pk.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if pk.Int() == 0 {
+				table.nextID++
+				pk.SetInt(int64(table.nextID))
+			} else if uint64(pk.Int()) > table.nextID {
+				table.nextID = uint64(pk.Int())
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if pk.Uint() == 0 {
+				table.nextID++
+				pk.SetUint(table.nextID)
+			} else if pk.Uint() > table.nextID {
+				table.nextID = pk.Uint()
+			}
+		}
+	}
+	key := table.key(rv)
+	for _, row := range table.rows {
+		if table.key(row) == key {
+			return gorm.ErrDuplicatedKey
+		}
+	}
+	now := time.Now()
+	setTime(rv, "CreatedAt", now, false)
+	setTime(rv, "UpdatedAt", now, false)
+	table.rows = append(table.rows, copyOf(rv))
+	return nil
+}
+
+func (table *memoryTable) remove(row reflect.Value) {
+	key := table.key(row)
+	for i, r := range table.rows {
+		if table.key(r) == key {
+			table.rows = append(table.rows[:i:i], table.rows[i+1:]...)
+			return
+		}
+	}
+}
+
+// find returns the live rows of the primary key of the record or its non zero fields if it has no primary key
+func (table *memoryTable) find(rv reflect.Value) ([]reflect.Value, error) {
+	var rows []reflect.Value
+	if table.hasKey(rv) {
+		key := table.key(rv)
+		for _, row := range table.rows {
+			if !isDeleted(row) && table.key(row) == key {
+				rows = append(rows, row)
+			}
+		}
+		return rows, nil
+	}
+	var conditions []int
+	for i := 0; i < rv.NumField(); i++ {
+		if rv.Type().Field(i).IsExported() && !rv.Field(i).IsZero() {
+			conditions = append(conditions, i)
+		}
+	}
+	if len(conditions) == 0 {
+		return nil, gorm.ErrMissingWhereClause
+	}
+	for _, row := range table.rows {
+		if isDeleted(row) {
+			continue
+		}
+		matches := true
+		for _, i := range conditions {
+			field := row.FieldByName(rv.Type().Field(i).Name)
+			if !field.IsValid() || !reflect.DeepEqual(field.Interface(), rv.Field(i).Interface()) {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+// key joins the primary key values of the row with ","
+func (table *memoryTable) key(rv reflect.Value) string {
+	rv = reflect.Indirect(rv)
+	keys := make([]string, len(table.pks))
+	for i, index := range table.pks {
+		keys[i] = fmt.Sprint(rv.FieldByIndex(index).Interface())
+	}
+	return strings.Join(keys, ",")
+}
+
+// idKey returns the key (see key) of the id in the forms of mysql.PrimaryKey. Composite keys are given as
+// "a,b" strings, slices of the values or maps by the names of the primary key fields.
+func (table *memoryTable) idKey(id any) (string, bool) {
+	if len(table.pks) <= 1 {
+		return fmt.Sprint(id), true
+	}
+	var values []any
+	switch v := id.(type) {
+	case string:
+		return v, true
+	case map[string]any:
+		for _, name := range table.names {
+			value, ok := v[name]
+			if !ok {
+				return "", false
+			}
+			values = append(values, value)
+		}
+	default:
+		values = flatten(id)
+	}
+	if len(values) != len(table.pks) {
+		return "", false
+	}
+	keys := make([]string, len(values))
+	for i, value := range values {
+		keys[i] = fmt.Sprint(value)
+	}
+	return strings.Join(keys, ","), true
+}
+
+// flatten returns the elements of the slices except []byte, the value itself otherwise
+func flatten(value any) []any {
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Slice || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []any{value}
+	}
+	values := make([]any, rv.Len())
+	for i := range values {
+		values[i] = rv.Index(i).Interface()
+	}
+	return values
+}
+
+// less compares the rows by their primary keys in order, numbers by their values
+func (table *memoryTable) less(a reflect.Value, b reflect.Value) bool {
+	for _, index := range table.pks {
+		x, y := a.FieldByIndex(index), b.FieldByIndex(index)
+		switch x.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if x.Int() != y.Int() {
+				return x.Int() < y.Int()
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if x.Uint() != y.Uint() {
+				return x.Uint() < y.Uint()
+			}
+		default:
+			if xs, ys := fmt.Sprint(x.Interface()), fmt.Sprint(y.Interface()); xs != ys {
+				return xs < ys
+			}
+		}
+	}
+	return false
+}
+
+func (table *memoryTable) hasKey(rv reflect.Value) bool {
+	if len(table.pks) == 0 {
+		return false
+	}
+	for _, index := range table.pks {
+		if rv.FieldByIndex(index).IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// primaryKeys returns the indexes of the fields tagged as primary key, ID if there are none
+func primaryKeys(typ reflect.Type) [][]int {
+	var pks [][]int
+	for _, f := range reflect.VisibleFields(typ) {
+		tag := strings.ToLower(strings.ReplaceAll(f.Tag.Get("gorm"), "_", ""))
+		if strings.Contains(tag, "primarykey") {
+			pks = append(pks, f.Index)
+		}
+	}
+	if len(pks) == 0 {
+		if f, ok := typ.FieldByName("ID"); ok {
+			pks = append(pks, f.Index)
+		}
+	}
+	return pks
+}
+
+func isDeleted(row reflect.Value) bool {
+	deletedAt := row.FieldByName("DeletedAt")
+	return deletedAt.IsValid() && deletedAt.Type() == deletedAtType && deletedAt.Interface().(gorm.DeletedAt).Valid
+}
+
+// setTime sets the time field with the given name, only if it is zero unless force is true
+func setTime(rv reflect.Value, name string, now time.Time, force bool) {
+	field := rv.FieldByName(name)
+	if field.IsValid() && field.Type() == timeType && (force || field.IsZero()) {
+		field.Set(reflect.ValueOf(now))
+	}
+}
+
+// setField assigns the value to the field converting it like the sql driver
+func setField(row reflect.Value, name string, value any) error {
+	field := row.FieldByName(name)
+	if !field.IsValid() {
+		return fmt.Errorf("Can't find field for %s", name)
+	}
+	if value == nil {
+		field.Set(reflect.Zero(field.Type()))
+		return nil
+	}
+	if field.Type() == jsonType {
+		if j, ok := value.(types.JSON); ok {
+			field.Set(reflect.ValueOf(j))
+			return nil
+		}
+		data, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(types.JSON(data)))
+		return nil
+	}
+	v := reflect.ValueOf(value)
+	target := field.Type()
+	isPtr := target.Kind() == reflect.Ptr && v.Kind() != reflect.Ptr
+	if isPtr {
+		target = target.Elem()
+	}
+	if !v.Type().ConvertibleTo(target) {
+		return fmt.Errorf("can't assign %T to field %s", value, name)
+	}
+	v = v.Convert(target)
+	if isPtr {
+		p := reflect.New(target)
+		p.Elem().Set(v)
+		v = p
+	}
+	field.Set(v)
+	return nil
+}
+
+func sliceOf(records any) (reflect.Value, error) {
+	value := reflect.ValueOf(records)
+	if value.Kind() != reflect.Pointer {
+		return value, fmt.Errorf("records must be a pointer")
+	}
+	elem := value.Elem()
+	if elem.Kind() != reflect.Slice {
+		return elem, fmt.Errorf("records must be a pointer to slice")
+	}
+	return elem, nil
+}
+
+// fill appends copies of the rows to the slice, only the given fields if there are any
+func fill(elem reflect.Value, rows []reflect.Value, fields []string) {
+	elem.Set(reflect.MakeSlice(elem.Type(), 0, len(rows)))
+	itemType := elem.Type().Elem()
+	isPtr := itemType.Kind() == reflect.Ptr
+	structType := reflection.DepointerField(itemType)
+	for _, row := range rows {
+		item := reflect.New(structType)
+		copyFields(item.Elem(), row, fields)
+		if isPtr {
+			elem.Set(reflect.Append(elem, item))
+		} else {
+			elem.Set(reflect.Append(elem, item.Elem()))
+		}
+	}
+}
+
+// copyFields copies the fields of the row to the fields with the same names and types of the target
+func copyFields(target reflect.Value, row reflect.Value, fields []string) {
+	if target.Type() == row.Type() && len(fields) == 0 {
+		target.Set(row)
+		return
+	}
+	for i := 0; i < target.NumField(); i++ {
+		f := target.Type().Field(i)
+		if !f.IsExported() || (len(fields) > 0 && !contains(fields, f.Name)) {
+			continue
+		}
+		source := row.FieldByName(f.Name)
+		if source.IsValid() && source.Type().AssignableTo(f.Type) {
+			target.Field(i).Set(source)
+		}
+	}
+}
+
+// copyOf returns an addressable copy of the struct
+func copyOf(rv reflect.Value) reflect.Value {
+	copied := reflect.New(rv.Type()).Elem()
+	copied.Set(rv)
+	return copied
+}
+
+func contains(arr []string, s string) bool {
+	for _, a := range arr {
+		if a == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,153 @@
+package repositories
+
+import (
+	"testing"
+	"time"
+
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+type Book struct {
+	ID        uint
+	Title     string `qapi:"q:%*%"`
+	Pages     int
+	Author    *string
+	CreatedAt time.Time
+	DeletedAt gorm.DeletedAt
+}
+
+type BookTitle struct {
+	ID    uint
+	Title string
+}
+
+func (BookTitle) TableName() string {
+	return "Book"
+}
+
+func TestMemoryRepository(t *testing.T) {
+	rep := NewMemoryRepository()
+	author := "Orhan"
+	for _, b := range []Book{{Title: "Kar", Pages: 436, Author: &author}, {Title: "Benim Adım Kırmızı", Pages: 472}, {Title: "Masumiyet Müzesi", Pages: 592}} {
+		assert.NoError(t, rep.Create(nil, &b))
+	}
+	assert.ErrorIs(t, rep.Create(nil, &Book{ID: 1}), gorm.ErrDuplicatedKey)
+
+	var book Book
+	assert.NoError(t, rep.Read(nil, &book, 2))
+	assert.Equal(t, "Benim Adım Kırmızı", book.Title)
+	assert.False(t, book.CreatedAt.IsZero())
+	assert.ErrorIs(t, rep.Read(nil, &book, 9), gorm.ErrRecordNotFound)
+
+	// smart select reads the same table
+	var title BookTitle
+	assert.NoError(t, rep.Read(nil, &title, 1))
+	assert.Equal(t, "Kar", title.Title)
+
+	var books []Book
+	count, err := rep.List(nil, &books, &qapi.Query{Filter: []qapi.Filter{{Name: "Pages", Operation: qapi.GT, Value: "450"}}, Sort: []string{"Pages desc"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, "Masumiyet Müzesi", books[0].Title)
+
+	count, err = rep.List(nil, &books, &qapi.Query{Filter: []qapi.Filter{{Name: "Author", Operation: qapi.EQ, Value: "NULL"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	count, err = rep.List(nil, &books, &qapi.Query{Q: "kırmızı"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	// count is the total while paginating
+	var page []*Book
+	count, err = rep.List(nil, &page, &qapi.Query{Offset: 1, Limit: 1, Sort: []string{"ID asc"}, Fields: []string{"ID"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, count)
+	assert.Len(t, page, 1)
+	assert.Equal(t, uint(2), page[0].ID)
+	assert.Empty(t, page[0].Title)
+
+	_, err = rep.List(nil, &books, &qapi.Query{Filter: []qapi.Filter{{Name: "Price", Operation: qapi.EQ, Value: "1"}}})
+	assert.Error(t, err)
+
+	// partial and full updates
+	assert.NoError(t, rep.Update(nil, &Book{ID: 1}, map[string]any{"Pages": 440, "Author": "Ferit"}))
+	assert.NoError(t, rep.Read(nil, &book, 1))
+	assert.Equal(t, 440, book.Pages)
+	assert.Equal(t, "Ferit", *book.Author)
+	book.Title = "Snow"
+	assert.NoError(t, rep.Update(nil, &book))
+	assert.NoError(t, rep.Read(nil, &book, 1))
+	assert.Equal(t, "Snow", book.Title)
+	assert.ErrorIs(t, rep.Update(nil, &Book{}, map[string]any{"Pages": 1}), gorm.ErrMissingWhereClause)
+
+	// deletes are soft
+	assert.NoError(t, rep.Delete(nil, &Book{}, 1, 2))
+	count, err = rep.List(nil, &books, &qapi.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.ErrorIs(t, rep.Read(nil, &book, 1), gorm.ErrRecordNotFound)
+
+	// stream walks in batches
+	assert.NoError(t, rep.Create(nil, &Book{Title: "Kara Kitap"}))
+	var batches []int
+	err = rep.Stream(nil, &books, nil, 1, func(batch any) error {
+		batches = append(batches, len(*batch.(*[]Book)))
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 1}, batches)
+	assert.Equal(t, "Kara Kitap", books[0].Title)
+	assert.Equal(t, uint(4), books[0].ID)
+}
+
+func TestMemoryRepositoryStreamOrder(t *testing.T) {
+	rep := NewMemoryRepository()
+	for _, id := range []uint{10, 2, 9} {
+		assert.NoError(t, rep.Create(nil, &Book{ID: id, Title: "book"}))
+	}
+	var books []Book
+	var ids []uint
+	err := rep.Stream(nil, &books, nil, 2, func(batch any) error {
+		for _, b := range *batch.(*[]Book) {
+			ids = append(ids, b.ID)
+			// the batches are copies, writes of fn do not change them
+			assert.NoError(t, rep.Update(nil, &Book{ID: 9}, map[string]any{"Title": "changed"}))
+			assert.Equal(t, "book", b.Title)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{2, 9, 10}, ids)
+}
+
+type Translation struct {
+	Namespace string `gorm:"primaryKey"`
+	Key       string `gorm:"primaryKey"`
+	Text      string
+}
+
+func TestMemoryRepositoryCompositeKey(t *testing.T) {
+	rep := NewMemoryRepository()
+	assert.NoError(t, rep.Create(nil, &Translation{Namespace: "a", Key: "b", Text: "ab"}))
+	assert.NoError(t, rep.Create(nil, &Translation{Namespace: "a", Key: "c", Text: "ac"}))
+	assert.NoError(t, rep.Create(nil, &Translation{Namespace: "d", Key: "e", Text: "de"}))
+
+	for _, id := range []any{"a,b", []any{"a", "b"}, []string{"a", "b"}, map[string]any{"Namespace": "a", "Key": "b"}} {
+		var tr Translation
+		assert.NoError(t, rep.Read(nil, &tr, id), id)
+		assert.Equal(t, "ab", tr.Text, id)
+	}
+	var tr Translation
+	assert.ErrorIs(t, rep.Read(nil, &tr, []any{"a"}), gorm.ErrRecordNotFound)
+	assert.ErrorIs(t, rep.Read(nil, &tr, map[string]any{"Namespace": "a"}), gorm.ErrRecordNotFound)
+
+	assert.NoError(t, rep.Delete(nil, &Translation{}, []any{"a", "b"}, map[string]any{"Namespace": "a", "Key": "c"}))
+	var left []Translation
+	count, err := rep.List(nil, &left, &qapi.Query{})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "de", left[0].Text)
+}
@@ -11,6 +11,10 @@ type Repository interface {
 	// If record is of type E, performs regular list, otherwise does smart select
 	List(db *gorm.DB, record any, query *qapi.Query, lang ...string) (int, error)
 
+	// Stream walks all records matching the query in batches of batchSize instead of loading them at once
+	// records must be a pointer to slice and it is refilled for every batch passed to fn
+	Stream(db *gorm.DB, records any, query *qapi.Query, batchSize int, fn func(batch any) error) error
+
 	// Read combines previous Read and ReadSmartSelect
 	// If record is of type *E, performs regular read, otherwise does smart select
 	Read(db *gorm.DB, record any, id any, preloads ...string) error
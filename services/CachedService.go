@@ -0,0 +1,234 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/cache"
+	"github.com/filllabs/sincap-common/db"
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/db/ownership"
+	"github.com/filllabs/sincap-common/logging"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"go.uber.org/zap"
+)
+
+var generationSeq uint64
+
+// DefaultCacheTTL is the ttl of the cached services created with a ttl <= 0
+const DefaultCacheTTL = time.Hour
+
+// CachedService decorates a Service by caching the results of Read (by id, preloads and the language of the context)
+// and List (by query and language).
+// Create, Update and Delete invalidate all cached results of the entity. Stream is never cached.
+// Values are gob encoded, so they are copies and unexported fields are not cached.
+//
+// Invalidation happens after the write returns, writes of the transactions started by the caller should use the
+// undecorated service or invalidate after commit.
+type CachedService[E any] struct {
+	Service[E]
+	backend cache.Backend
+	ttl     time.Duration
+	entity  string
+	// Scope returns the partition of the cached values for the context. Default is the tenant of the context and
+	// the user of its claims if the entity is owned (see ownership). Custom data scopes must be included here.
+	Scope func(ctx context.Context) string
+}
+
+// listEntry is the cached result of List
+type listEntry[E any] struct {
+	Count   int
+	Records []E
+}
+
+// NewCachedService returns a cached service for the given service. Values expire after the ttl. Values are never
+// deleted by the invalidations (see Invalidate), so they always expire: ttl <= 0 is DefaultCacheTTL.
+func NewCachedService[E any](service Service[E], backend cache.Backend, ttl time.Duration) *CachedService[E] {
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+	return &CachedService[E]{
+		Service: service,
+		backend: backend,
+		ttl:     ttl,
+		entity:  reflect.TypeOf((*E)(nil)).Elem().String(),
+		Scope:   defaultScope(ownership.IsOwned(reflect.TypeOf((*E)(nil)).Elem())),
+	}
+}
+
+// defaultScope partitions the cache by the tenant and, for the owned entities, by the user of the context
+func defaultScope(owned bool) func(ctx context.Context) string {
+	return func(ctx context.Context) string {
+		tenantID, _ := db.TenantFromContext(ctx)
+		if !owned {
+			return tenantID
+		}
+		if c, ok := claims.DecryptedFromContext(ctx); ok {
+			return tenantID + "/" + strconv.FormatUint(uint64(c.UserID), 10)
+		}
+		return tenantID + "/"
+	}
+}
+
+// List retrieves a collection of records based on the query parameters from the cache or the service
+func (s *CachedService[E]) List(ctx context.Context, record *[]E, query *qapi.Query, lang ...string) (int, error) {
+	key := s.key(ctx, "list", queryHash(query, lang))
+	var entry listEntry[E]
+	if key != "" && s.get(ctx, key, &entry) {
+		*record = entry.Records
+		return entry.Count, nil
+	}
+	count, err := s.Service.List(ctx, record, query, lang...)
+	if err == nil && key != "" {
+		s.set(ctx, key, listEntry[E]{Count: count, Records: *record})
+	}
+	return count, err
+}
+
+// Read retrieves a single record by its ID from the cache or the service
+func (s *CachedService[E]) Read(ctx context.Context, record *E, id any, preloads ...string) error {
+	// Read translates by the negotiated language of the context, see GormService.Read
+	lang, _ := translations.LanguageFromContext(ctx)
+	key := s.key(ctx, "read", fmt.Sprint(id), strings.Join(preloads, ","), lang)
+	var cached E
+	if key != "" && s.get(ctx, key, &cached) {
+		*record = cached
+		return nil
+	}
+	err := s.Service.Read(ctx, record, id, preloads...)
+	if err == nil && key != "" {
+		s.set(ctx, key, record)
+	}
+	return err
+}
+
+// Create inserts a new record and invalidates the cache of the entity
+func (s *CachedService[E]) Create(ctx context.Context, record *E) error {
+	defer s.Invalidate(ctx)
+	return s.Service.Create(ctx, record)
+}
+
+// Update modifies an existing record and invalidates the cache of the entity
+func (s *CachedService[E]) Update(ctx context.Context, record *E, fieldParams ...map[string]any) error {
+	defer s.Invalidate(ctx)
+	return s.Service.Update(ctx, record, fieldParams...)
+}
+
+// Delete removes one or more records and invalidates the cache of the entity
+func (s *CachedService[E]) Delete(ctx context.Context, record *E, ids ...any) error {
+	defer s.Invalidate(ctx)
+	return s.Service.Delete(ctx, record, ids...)
+}
+
+// Invalidate drops all cached values of the entity for all scopes by starting a new generation.
+// Values of the old generations are left to expire after the ttl of the service.
+func (s *CachedService[E]) Invalidate(ctx context.Context) {
+	if err := s.backend.Set(ctx, s.generationKey(), []byte(newGeneration()), 0); err != nil {
+		logging.Logger.Named("Cache").Error("Can't invalidate", zap.String("entity", s.entity), zap.Error(err))
+	}
+}
+
+func (s *CachedService[E]) generationKey() string {
+	return "service:" + s.entity + ":gen"
+}
+
+// key returns the cache key of the current generation, empty if the generation is not accessible
+func (s *CachedService[E]) key(ctx context.Context, parts ...string) string {
+	gen, found, err := s.backend.Get(ctx, s.generationKey())
+	if err != nil {
+		logging.Logger.Named("Cache").Error("Can't read generation", zap.String("entity", s.entity), zap.Error(err))
+		return ""
+	}
+	if !found {
+		gen = []byte(newGeneration())
+		if err := s.backend.Set(ctx, s.generationKey(), gen, 0); err != nil {
+			logging.Logger.Named("Cache").Error("Can't write generation", zap.String("entity", s.entity), zap.Error(err))
+			return ""
+		}
+	}
+	scope := ""
+	if s.Scope != nil {
+		scope = s.Scope(ctx)
+	}
+	return "service:" + s.entity + ":" + string(gen) + ":" + scope + ":" + strings.Join(parts, ":")
+}
+
+func (s *CachedService[E]) get(ctx context.Context, key string, value any) bool {
+	data, found, err := s.backend.Get(ctx, key)
+	if err != nil {
+		logging.Logger.Named("Cache").Error("Can't read", zap.String("key", key), zap.Error(err))
+		return false
+	}
+	if !found {
+		return false
+	}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(value); err != nil {
+		logging.Logger.Named("Cache").Error("Can't decode", zap.String("key", key), zap.Error(err))
+		return false
+	}
+	return true
+}
+
+func (s *CachedService[E]) set(ctx context.Context, key string, value any) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(value); err != nil {
+		logging.Logger.Named("Cache").Error("Can't encode", zap.String("key", key), zap.Error(err))
+		return
+	}
+	if err := s.backend.Set(ctx, key, buf.Bytes(), s.ttl); err != nil {
+		logging.Logger.Named("Cache").Error("Can't write", zap.String("key", key), zap.Error(err))
+	}
+}
+
+func newGeneration() string {
+	return strconv.FormatInt(time.Now().UnixNano(), 36) + "." + strconv.FormatUint(atomic.AddUint64(&generationSeq, 1), 36)
+}
+
+// queryHash returns the hash of the query where the order of the filters, fields and preloads does not matter
+func queryHash(query *qapi.Query, lang []string) string {
+	canonical := qapi.Query{}
+	if query != nil {
+		canonical = *query
+		canonical.TotalCount = 0
+		canonical.Fields = sorted(query.Fields)
+		canonical.Preloads = sorted(query.Preloads)
+		canonical.Filter = append([]qapi.Filter(nil), query.Filter...)
+		sort.Slice(canonical.Filter, func(i, j int) bool {
+			a, b := canonical.Filter[i], canonical.Filter[j]
+			if a.Name != b.Name {
+				return a.Name < b.Name
+			}
+			if a.Operation != b.Operation {
+				return a.Operation < b.Operation
+			}
+			return a.Value < b.Value
+		})
+	}
+	data, _ := json.Marshal(struct {
+		Query qapi.Query
+		Lang  []string
+	}{canonical, lang})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+func sorted(arr []string) []string {
+	if len(arr) == 0 {
+		return nil
+	}
+	arr = append([]string(nil), arr...)
+	sort.Strings(arr)
+	return arr
+}
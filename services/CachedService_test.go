@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/cache"
+	"github.com/filllabs/sincap-common/db"
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/db/util"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+)
+
+type Country struct {
+	ID   uint
+	Code string
+}
+
+// countingService serves fixed records and counts the calls
+type countingService struct {
+	Service[Country]
+	records []Country
+	reads   int
+	lists   int
+}
+
+func (s *countingService) List(ctx context.Context, record *[]Country, query *qapi.Query, lang ...string) (int, error) {
+	s.lists++
+	*record = append([]Country(nil), s.records...)
+	return len(s.records), nil
+}
+
+func (s *countingService) Read(ctx context.Context, record *Country, id any, preloads ...string) error {
+	s.reads++
+	*record = s.records[0]
+	return nil
+}
+
+func (s *countingService) Update(ctx context.Context, record *Country, fieldParams ...map[string]any) error {
+	s.records[0] = *record
+	return nil
+}
+
+func TestCachedService(t *testing.T) {
+	inner := &countingService{records: []Country{{ID: 1, Code: "TR"}, {ID: 2, Code: "DE"}}}
+	s := NewCachedService[Country](inner, cache.NewMemory(time.Minute), time.Minute)
+	ctx := context.Background()
+
+	var country Country
+	assert.NoError(t, s.Read(ctx, &country, 1))
+	assert.NoError(t, s.Read(ctx, &country, 1))
+	assert.Equal(t, "TR", country.Code)
+	assert.Equal(t, 1, inner.reads)
+
+	// cached values are copies
+	country.Code = "XX"
+	var again Country
+	assert.NoError(t, s.Read(ctx, &again, 1))
+	assert.Equal(t, "TR", again.Code)
+
+	// filter order does not matter
+	q1 := &qapi.Query{Filter: []qapi.Filter{{Name: "Code", Operation: qapi.EQ, Value: "TR"}, {Name: "ID", Operation: qapi.GT, Value: "0"}}}
+	q2 := &qapi.Query{Filter: []qapi.Filter{{Name: "ID", Operation: qapi.GT, Value: "0"}, {Name: "Code", Operation: qapi.EQ, Value: "TR"}}}
+	var countries []Country
+	count, err := s.List(ctx, &countries, q1)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	count, err = s.List(ctx, &countries, q2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, count)
+	assert.Len(t, countries, 2)
+	assert.Equal(t, 1, inner.lists)
+
+	// language of the context is a part of the read key
+	tr := context.WithValue(ctx, translations.LANG_KEY, "tr-TR")
+	assert.NoError(t, s.Read(tr, &country, 1))
+	assert.NoError(t, s.Read(context.WithValue(ctx, translations.LANG_KEY, "de-DE"), &country, 1))
+	assert.NoError(t, s.Read(tr, &country, 1))
+	assert.Equal(t, 3, inner.reads)
+
+	// language and scope are parts of the key
+	_, err = s.List(ctx, &countries, q1, "tr-TR")
+	assert.NoError(t, err)
+	_, err = s.List(db.WithTenant(ctx, "acme"), &countries, q1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, inner.lists)
+
+	// writes invalidate
+	assert.NoError(t, s.Update(ctx, &Country{ID: 1, Code: "AT"}))
+	assert.NoError(t, s.Read(ctx, &country, 1))
+	assert.Equal(t, "AT", country.Code)
+	assert.Equal(t, 4, inner.reads)
+	_, err = s.List(ctx, &countries, q1)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, inner.lists)
+}
+
+type Note struct {
+	util.Model
+	util.OwnedModel
+}
+
+func TestCachedServiceScope(t *testing.T) {
+	acme := db.WithTenant(context.Background(), "acme")
+	alice := claims.WithDecrypted(acme, &claims.DecryptedClaims{UserID: 1})
+	bob := claims.WithDecrypted(acme, &claims.DecryptedClaims{UserID: 2})
+
+	countries := NewCachedService[Country](nil, cache.NewMemory(time.Minute), time.Minute)
+	assert.Equal(t, "acme", countries.Scope(alice))
+	assert.Equal(t, countries.Scope(alice), countries.Scope(bob))
+
+	notes := NewCachedService[Note](nil, cache.NewMemory(time.Minute), time.Minute)
+	assert.Equal(t, "acme/1", notes.Scope(alice))
+	assert.NotEqual(t, notes.Scope(alice), notes.Scope(bob))
+	assert.NotEqual(t, notes.Scope(alice), notes.Scope(acme))
+}
+
+func TestCachedServiceTTL(t *testing.T) {
+	// values of the old generations must expire
+	assert.Equal(t, DefaultCacheTTL, NewCachedService[Country](nil, cache.NewMemory(time.Minute), 0).ttl)
+	assert.Equal(t, DefaultCacheTTL, NewCachedService[Country](nil, cache.NewMemory(time.Minute), -time.Second).ttl)
+	assert.Equal(t, time.Minute, NewCachedService[Country](nil, cache.NewMemory(time.Minute), time.Minute).ttl)
+}
+
+func TestQueryHash(t *testing.T) {
+	assert.Equal(t, queryHash(nil, nil), queryHash(&qapi.Query{}, nil))
+	assert.Equal(t, queryHash(&qapi.Query{Fields: []string{"ID", "Code"}}, nil), queryHash(&qapi.Query{Fields: []string{"Code", "ID"}}, nil))
+	assert.NotEqual(t, queryHash(&qapi.Query{Sort: []string{"ID", "Code"}}, nil), queryHash(&qapi.Query{Sort: []string{"Code", "ID"}}, nil))
+	assert.NotEqual(t, queryHash(&qapi.Query{Limit: 10}, nil), queryHash(&qapi.Query{Limit: 20}, nil))
+}
@@ -3,7 +3,10 @@ package services
 import (
 	"context"
 
+	"github.com/filllabs/sincap-common/db/mysql"
 	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/db/outbox"
+	"github.com/filllabs/sincap-common/events"
 	"github.com/filllabs/sincap-common/middlewares/qapi"
 	"github.com/filllabs/sincap-common/repositories"
 	"gorm.io/gorm"
@@ -12,46 +15,181 @@ import (
 // GormService implements Service interface using GORM
 type GormService struct {
 	dbCtxKey   string
-	repository repositories.GormRepository
+	repository repositories.Repository
+	bus        *events.Bus
+	outbox     bool
 }
 
 // NewGormService creates a new instance of GormService
 func NewGormService(dbCtxKey string) GormService {
 	return GormService{
 		dbCtxKey:   dbCtxKey,
-		repository: repositories.GormRepository{},
+		repository: &repositories.GormRepository{},
 	}
 }
 
+// WithEvents returns a copy of the service which publishes Created, Updated and Deleted events to the bus.
+// Before and after snapshots are read only if the entity has subscribers.
+func (s GormService) WithEvents(bus *events.Bus) GormService {
+	s.bus = bus
+	return s
+}
+
+// WithRepository returns a copy of the service which uses the given repository (e.g. repositories.MemoryRepository in tests).
+// The connection at the context is optional for the repositories which do not use it.
+func (s GormService) WithRepository(repository repositories.Repository) GormService {
+	s.repository = repository
+	return s
+}
+
+// WithOutbox returns a copy of the service which writes the Created, Updated and Deleted events to the outbox
+// in the same transaction of the change. Topics are "{Entity}.{type}" (e.g. "Order.created"), payloads are the events.
+func (s GormService) WithOutbox() GormService {
+	s.outbox = true
+	return s
+}
+
+// Transaction runs fn in a transaction of the context connection. The context given to fn carries the transaction,
+// so the calls of the services with it join the transaction. Events are published after commit and dropped on rollback.
+// The whole transaction is retried on deadlocks if the retry policy of mysql is enabled.
+func (s *GormService) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	var txCtx context.Context
+	err := mysql.Transaction(s.getDB(ctx), func(tx *gorm.DB) error {
+		txCtx = events.Buffer(context.WithValue(ctx, s.dbCtxKey, tx))
+		return fn(txCtx)
+	})
+	if txCtx == nil {
+		return err
+	}
+	if err != nil {
+		events.Discard(txCtx)
+		return err
+	}
+	events.Flush(txCtx)
+	return nil
+}
+
 // List retrieves a collection of records based on the query parameters
 func (s *GormService) List(ctx context.Context, record any, query *qapi.Query, lang ...string) (int, error) {
-	db := ctx.Value(s.dbCtxKey).(*gorm.DB)
-	if len(lang) > 0 {
-		return translations.List(db, record, query, lang)
-	}
-	return s.repository.List(db, record, query)
+	db := s.getDB(ctx)
+	return s.repository.List(db, record, query, lang...)
 }
 
-// Read retrieves a single record by its ID
+// Stream walks all records matching the query in batches and calls fn for each batch
+func (s *GormService) Stream(ctx context.Context, records any, query *qapi.Query, batchSize int, fn func(batch any) error) error {
+	db := s.getDB(ctx)
+	return s.repository.Stream(db, records, query, batchSize, fn)
+}
+
+// Read retrieves a single record by its ID. If the context has a language (see translations.TranslationMiddleware)
+// Translations fields are read in the language like List.
 func (s *GormService) Read(ctx context.Context, record any, id any, preloads ...string) error {
-	db := ctx.Value(s.dbCtxKey).(*gorm.DB)
+	db := s.getDB(ctx)
+	if _, isGorm := s.repository.(*repositories.GormRepository); isGorm {
+		if lang, ok := translations.LanguageFromContext(ctx); ok {
+			return translations.Read(db, record, id, []string{lang}, preloads...)
+		}
+	}
 	return s.repository.Read(db, record, id, preloads...)
 }
 
 // Create inserts a new record into the database
 func (s *GormService) Create(ctx context.Context, record any) error {
-	db := ctx.Value(s.dbCtxKey).(*gorm.DB)
-	return s.repository.Create(db, record)
+	return s.write(ctx, func(tx *gorm.DB, emit func(events.Event)) error {
+		if err := s.repository.Create(tx, record); err != nil {
+			return err
+		}
+		if c := s.changeOf(tx, record); c != nil {
+			emit(c.event(ctx, events.Created, nil, copyOf(record)))
+		}
+		return nil
+	})
 }
 
 // Update modifies an existing record
 func (s *GormService) Update(ctx context.Context, record any, fieldParams ...map[string]any) error {
-	db := ctx.Value(s.dbCtxKey).(*gorm.DB)
-	return s.repository.Update(db, record, fieldParams...)
+	return s.write(ctx, func(tx *gorm.DB, emit func(events.Event)) error {
+		c := s.changeOf(tx, record)
+		var before any
+		if c != nil && c.id != "" {
+			before = c.load(tx)
+		}
+		if err := s.repository.Update(tx, record, fieldParams...); err != nil {
+			return err
+		}
+		if c == nil {
+			return nil
+		}
+		if c.id == "" {
+			// bulk update, the record holds the conditions
+			emit(c.event(ctx, events.Updated, nil, copyOf(record)))
+			return nil
+		}
+		emit(c.event(ctx, events.Updated, before, c.load(tx)))
+		return nil
+	})
 }
 
 // Delete removes one or more records from the database
 func (s *GormService) Delete(ctx context.Context, record any, ids ...any) error {
-	db := ctx.Value(s.dbCtxKey).(*gorm.DB)
-	return s.repository.Delete(db, record, ids...)
+	return s.write(ctx, func(tx *gorm.DB, emit func(events.Event)) error {
+		c := s.changeOf(tx, record)
+		var before []any
+		if c != nil {
+			before = c.loadAll(tx, ids)
+		}
+		if err := s.repository.Delete(tx, record, ids...); err != nil {
+			return err
+		}
+		for _, old := range before {
+			emit(c.withRecord(old).event(ctx, events.Deleted, old, nil))
+		}
+		return nil
+	})
+}
+
+// write runs fn with the context connection and collects its events. If the outbox is enabled, fn and the outbox
+// messages of the events run in the same transaction. Events are published to the bus after fn succeeds.
+func (s *GormService) write(ctx context.Context, fn func(tx *gorm.DB, emit func(events.Event)) error) error {
+	db := s.getDB(ctx)
+	var emitted []events.Event
+	emit := func(e events.Event) {
+		emitted = append(emitted, e)
+	}
+	var err error
+	if s.outbox {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			emitted = nil
+			if err := fn(tx, emit); err != nil {
+				return err
+			}
+			for _, e := range emitted {
+				if err := outbox.Add(tx, e.Entity+"."+string(e.Type), e); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	} else {
+		err = fn(db, emit)
+	}
+	if err != nil {
+		return err
+	}
+	if s.bus != nil {
+		for _, e := range emitted {
+			s.bus.Publish(ctx, e)
+		}
+	}
+	return nil
+}
+
+// getDB returns the connection stored at the context bound to the context itself,
+// so gorm plugins (e.g. ownership) can read request scoped values.
+func (s *GormService) getDB(ctx context.Context) *gorm.DB {
+	db, _ := ctx.Value(s.dbCtxKey).(*gorm.DB)
+	if db == nil {
+		return nil
+	}
+	return db.WithContext(ctx)
 }
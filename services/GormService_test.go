@@ -0,0 +1,131 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/db/outbox"
+	"github.com/filllabs/sincap-common/events"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/filllabs/sincap-common/repositories"
+	"github.com/stretchr/testify/assert"
+)
+
+type Currency struct {
+	ID   uint
+	Code string
+	Rate float64
+}
+
+func TestGormServiceEvents(t *testing.T) {
+	DB := dbtest.Open(t, &Currency{})
+	bus := events.NewBus()
+	var got []events.Event
+	bus.Subscribe("Currency", func(ctx context.Context, e events.Event) error {
+		got = append(got, e)
+		return nil
+	})
+	s := NewGormService("db").WithEvents(bus)
+	ctx := context.WithValue(context.Background(), "db", DB)
+	ctx = claims.WithDecrypted(ctx, &claims.DecryptedClaims{UserID: 3, Username: "ayse"})
+
+	usd := Currency{Code: "USD", Rate: 1}
+	assert.NoError(t, s.Create(ctx, &usd))
+	assert.NoError(t, s.Update(ctx, &Currency{ID: usd.ID}, map[string]any{"Rate": 2.0}))
+	assert.NoError(t, s.Delete(ctx, &Currency{}, usd.ID))
+
+	assert.Len(t, got, 3)
+	assert.Equal(t, events.Created, got[0].Type)
+	assert.Equal(t, "Currency", got[0].Entity)
+	assert.Equal(t, "1", got[0].ID)
+	assert.Equal(t, uint(3), got[0].ActorID)
+	assert.Equal(t, "USD", got[0].After.(*Currency).Code)
+
+	assert.Equal(t, events.Updated, got[1].Type)
+	assert.Equal(t, 1.0, got[1].Before.(*Currency).Rate)
+	assert.Equal(t, 2.0, got[1].After.(*Currency).Rate)
+
+	assert.Equal(t, events.Deleted, got[2].Type)
+	assert.Equal(t, "1", got[2].ID)
+	assert.Equal(t, 2.0, got[2].Before.(*Currency).Rate)
+	assert.Nil(t, got[2].After)
+}
+
+func TestGormServiceTransactionEvents(t *testing.T) {
+	DB := dbtest.Open(t, &Currency{})
+	bus := events.NewBus()
+	var got []string
+	bus.Subscribe("", func(ctx context.Context, e events.Event) error {
+		got = append(got, e.ID)
+		return nil
+	})
+	s := NewGormService("db").WithEvents(bus)
+	ctx := context.WithValue(context.Background(), "db", DB)
+
+	err := s.Transaction(ctx, func(ctx context.Context) error {
+		assert.NoError(t, s.Create(ctx, &Currency{Code: "EUR"}))
+		assert.Empty(t, got)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1"}, got)
+
+	failure := errors.New("rollback")
+	err = s.Transaction(ctx, func(ctx context.Context) error {
+		assert.NoError(t, s.Create(ctx, &Currency{Code: "TRY"}))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"1"}, got)
+	var count int64
+	assert.NoError(t, DB.Model(&Currency{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestGormServiceOutbox(t *testing.T) {
+	DB := dbtest.Open(t, &Currency{})
+	assert.NoError(t, DB.AutoMigrate(&outbox.Message{}))
+	s := NewGormService("db").WithOutbox()
+	ctx := context.WithValue(context.Background(), "db", DB)
+
+	usd := Currency{Code: "USD", Rate: 1}
+	assert.NoError(t, s.Create(ctx, &usd))
+	assert.NoError(t, s.Delete(ctx, &Currency{}, usd.ID))
+
+	var msgs []outbox.Message
+	assert.NoError(t, DB.Order("ID").Find(&msgs).Error)
+	assert.Len(t, msgs, 2)
+	assert.Equal(t, "Currency.created", msgs[0].Topic)
+	assert.Equal(t, "Currency.deleted", msgs[1].Topic)
+	var e struct {
+		ID    string
+		After Currency
+	}
+	assert.NoError(t, json.Unmarshal(msgs[0].Payload, &e))
+	assert.Equal(t, "1", e.ID)
+	assert.Equal(t, "USD", e.After.Code)
+
+	// the message is rolled back with the change
+	assert.NoError(t, DB.Migrator().DropTable(&outbox.Message{}))
+	assert.Error(t, s.Create(ctx, &Currency{Code: "EUR"}))
+	var count int64
+	assert.NoError(t, DB.Model(&Currency{}).Count(&count).Error)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestGormServiceWithRepository(t *testing.T) {
+	s := NewGormService("db").WithRepository(repositories.NewMemoryRepository())
+	ctx := context.Background()
+
+	assert.NoError(t, s.Create(ctx, &Currency{Code: "USD", Rate: 1}))
+	assert.NoError(t, s.Create(ctx, &Currency{Code: "EUR", Rate: 1.1}))
+	var currencies []Currency
+	count, err := s.List(ctx, &currencies, &qapi.Query{Filter: []qapi.Filter{{Name: "Rate", Operation: qapi.GT, Value: "1"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "EUR", currencies[0].Code)
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+)
+
+// LanguageService manages the rows of the Language table (see translations.Language).
+// Languages are validated, only one of them is the default and the cached languages are invalidated after the writes.
+type LanguageService struct {
+	GormService
+}
+
+// NewLanguageService creates a new instance of LanguageService
+func NewLanguageService(dbCtxKey string) *LanguageService {
+	return &LanguageService{GormService: NewGormService(dbCtxKey)}
+}
+
+// List retrieves the languages based on the query parameters
+func (s *LanguageService) List(ctx context.Context, records *[]translations.Language, query *qapi.Query, _ ...string) (int, error) {
+	return s.GormService.List(ctx, records, query)
+}
+
+// Stream walks the languages matching the query in batches and calls fn for each batch
+func (s *LanguageService) Stream(ctx context.Context, records *[]translations.Language, query *qapi.Query, batchSize int, fn func(batch any) error) error {
+	return s.GormService.Stream(ctx, records, query, batchSize, fn)
+}
+
+// Read retrieves a language by its code
+func (s *LanguageService) Read(ctx context.Context, record *translations.Language, id any, preloads ...string) error {
+	return s.repository.Read(s.getDB(ctx), record, id, preloads...)
+}
+
+// Create validates and inserts the language, the others are not default anymore if it is the default
+func (s *LanguageService) Create(ctx context.Context, record *translations.Language) error {
+	if err := record.Validate(); err != nil {
+		return err
+	}
+	defer translations.InvalidateLanguages()
+	return s.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.GormService.Create(ctx, record); err != nil {
+			return err
+		}
+		if record.Default {
+			return translations.SetDefault(s.getDB(ctx), record.Code)
+		}
+		return nil
+	})
+}
+
+// Update validates and modifies the language, the others are not default anymore if it becomes the default
+func (s *LanguageService) Update(ctx context.Context, record *translations.Language, fieldParams ...map[string]any) error {
+	merged := mergeLanguage(*record, fieldParams)
+	if err := merged.Validate(); err != nil {
+		return err
+	}
+	defer translations.InvalidateLanguages()
+	return s.Transaction(ctx, func(ctx context.Context) error {
+		if err := s.GormService.Update(ctx, record, fieldParams...); err != nil {
+			return err
+		}
+		if merged.Default {
+			return translations.SetDefault(s.getDB(ctx), merged.Code)
+		}
+		return nil
+	})
+}
+
+// Delete removes one or more languages
+func (s *LanguageService) Delete(ctx context.Context, record *translations.Language, ids ...any) error {
+	defer translations.InvalidateLanguages()
+	return s.GormService.Delete(ctx, record, ids...)
+}
+
+// mergeLanguage returns the language with the values of the partial updates
+func mergeLanguage(l translations.Language, fieldParams []map[string]any) translations.Language {
+	for _, fields := range fieldParams {
+		for name, value := range fields {
+			text, _ := value.(string)
+			switch name {
+			case "Code":
+				l.Code = text
+			case "Direction":
+				l.Direction = text
+			case "Fallbacks":
+				l.Fallbacks = text
+			case "Default":
+				l.Default, _ = value.(bool)
+			}
+		}
+	}
+	return l
+}
@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/filllabs/sincap-common/db/mysql/translations"
+	"github.com/filllabs/sincap-common/internal/dbtest"
+	"github.com/filllabs/sincap-common/middlewares/qapi"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLanguageService(t *testing.T) {
+	DB := dbtest.Open(t, &translations.Language{})
+	translations.InvalidateLanguages()
+	defer translations.InvalidateLanguages()
+	s := NewLanguageService("db")
+	ctx := context.WithValue(context.Background(), "db", DB)
+
+	assert.NoError(t, s.Create(ctx, &translations.Language{Code: "en-US", Name: "English", Default: true}))
+	assert.NoError(t, s.Create(ctx, &translations.Language{Code: "ar-SA", Name: "Arabic", NativeName: "العربية", Direction: translations.RTL}))
+	assert.NoError(t, s.Create(ctx, &translations.Language{Code: "de-DE", Name: "German", Fallbacks: "en-US"}))
+	assert.ErrorIs(t, s.Create(ctx, &translations.Language{Code: "fr'FR"}), translations.ErrInvalidLanguage)
+	assert.ErrorIs(t, s.Create(ctx, &translations.Language{Code: "fr-FR", Direction: "up"}), translations.ErrInvalidDirection)
+	assert.ErrorIs(t, s.Create(ctx, &translations.Language{Code: "fr-FR", Fallbacks: "fr-FR"}), translations.ErrInvalidLanguage)
+
+	codes, err := translations.ListCodes(DB)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en-US", "ar-SA", "de-DE"}, codes)
+	assert.Equal(t, "en-US", translations.DefaultLanguage())
+
+	// changes invalidate the cached languages
+	assert.NoError(t, s.Update(ctx, &translations.Language{Code: "de-DE"}, map[string]any{"Default": true}))
+	assert.NoError(t, s.Update(ctx, &translations.Language{Code: "ar-SA"}, map[string]any{"Enabled": false}))
+	codes, err = translations.ListCodes(DB)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en-US", "de-DE"}, codes)
+	assert.Equal(t, "de-DE", translations.DefaultLanguage())
+	assert.Equal(t, []string{"ar-SA", "de-DE"}, translations.Fallbacks(DB, "ar-SA"))
+
+	var languages []translations.Language
+	count, err := s.List(ctx, &languages, &qapi.Query{Filter: []qapi.Filter{{Name: "Default", Operation: qapi.EQ, Value: "true"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, "de-DE", languages[0].Code)
+
+	var language translations.Language
+	assert.NoError(t, s.Read(ctx, &language, "ar-SA"))
+	assert.Equal(t, translations.RTL, language.Direction)
+	assert.False(t, language.IsEnabled())
+
+	assert.NoError(t, s.Delete(ctx, &translations.Language{}, "de-DE"))
+	codes, err = translations.ListCodes(DB)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"en-US"}, codes)
+	assert.Equal(t, translations.DEFAULT_LANG_CODE, translations.DefaultLanguage())
+
+	assert.NoError(t, translations.SetDefaultLanguage("tr-TR"))
+	assert.Equal(t, "tr-TR", translations.DefaultLanguage())
+	assert.NoError(t, translations.SetDefaultLanguage(translations.DEFAULT_LANG_CODE))
+	assert.ErrorIs(t, translations.SetDefaultLanguage("x'y"), translations.ErrInvalidLanguage)
+}
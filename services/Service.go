@@ -13,6 +13,10 @@ type Service[E any] interface {
 	// Returns the total count of records and any error encountered
 	List(ctx context.Context, record *[]E, query *qapi.Query, lang ...string) (int, error)
 
+	// Stream walks all records matching the query in batches of batchSize
+	// The batch slice is reused for every call of fn, Offset, Limit and Sort are ignored
+	Stream(ctx context.Context, record *[]E, query *qapi.Query, batchSize int, fn func(batch any) error) error
+
 	// Read retrieves a single record by its ID
 	// Accepts optional preload parameters for eager loading related data
 	Read(ctx context.Context, record *E, id any, preloads ...string) error
@@ -34,6 +38,11 @@ type HasList[E any] interface {
 	List(ctx context.Context, record *[]E, query *qapi.Query, lang ...string) (int, error)
 }
 
+// HasStream checks if the service implements Stream
+type HasStream[E any] interface {
+	Stream(ctx context.Context, record *[]E, query *qapi.Query, batchSize int, fn func(batch any) error) error
+}
+
 // HasRead checks if the service implements Read
 type HasRead[E any] interface {
 	Read(ctx context.Context, record *E, id any, preloads ...string) error
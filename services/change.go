@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/filllabs/sincap-common/auth/claims"
+	"github.com/filllabs/sincap-common/db/mysql"
+	"github.com/filllabs/sincap-common/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+// change helps GormService to publish the events of a record
+type change struct {
+	schema *schema.Schema
+	ctx    context.Context
+	id     string
+	keys   []any
+}
+
+// changeOf returns nil if there is no connection or the service neither has an outbox nor a bus with the subscribers of the entity
+func (s *GormService) changeOf(db *gorm.DB, record any) *change {
+	if db == nil || (s.bus == nil && !s.outbox) {
+		return nil
+	}
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(record); err != nil || len(stmt.Schema.PrimaryFields) == 0 {
+		return nil
+	}
+	if !s.outbox && !s.bus.HasSubscribers(stmt.Schema.Table) {
+		return nil
+	}
+	c := &change{schema: stmt.Schema, ctx: db.Statement.Context}
+	return c.withRecord(record)
+}
+
+// withRecord returns a copy of the change holding the primary keys of the record
+func (c *change) withRecord(record any) *change {
+	copied := *c
+	copied.id, copied.keys = "", nil
+	rv := reflect.Indirect(reflect.ValueOf(record))
+	if rv.Kind() != reflect.Struct {
+		return &copied
+	}
+	ids := make([]string, 0, len(c.schema.PrimaryFields))
+	for _, pk := range c.schema.PrimaryFields {
+		value, isZero := pk.ValueOf(c.ctx, rv)
+		if isZero {
+			return &copied
+		}
+		copied.keys = append(copied.keys, value)
+		ids = append(ids, fmt.Sprint(value))
+	}
+	copied.id = strings.Join(ids, ",")
+	return &copied
+}
+
+// load reads the current row of the record, nil if it is not found
+func (c *change) load(db *gorm.DB) any {
+	exprs := make([]clause.Expression, len(c.keys))
+	for i, pk := range c.schema.PrimaryFields {
+		exprs[i] = clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: pk.DBName}, Value: c.keys[i]}
+	}
+	record := reflect.New(c.schema.ModelType).Interface()
+	if err := db.Session(&gorm.Session{NewDB: true}).Clauses(clause.Where{Exprs: exprs}).Take(record).Error; err != nil {
+		return nil
+	}
+	return record
+}
+
+// loadAll reads the rows which will be deleted by the record or the ids
+func (c *change) loadAll(db *gorm.DB, ids []any) []any {
+	if len(ids) == 0 {
+		if c.id == "" {
+			return nil
+		}
+		if record := c.load(db); record != nil {
+			return []any{record}
+		}
+		return nil
+	}
+	rows := reflect.New(reflect.SliceOf(reflect.PtrTo(c.schema.ModelType)))
+	keys, err := mysql.PrimaryKeys(db, rows.Interface(), ids...)
+	if err != nil {
+		return nil
+	}
+	if err := db.Session(&gorm.Session{NewDB: true}).Clauses(clause.Where{Exprs: []clause.Expression{keys}}).Find(rows.Interface()).Error; err != nil {
+		return nil
+	}
+	records := make([]any, rows.Elem().Len())
+	for i := range records {
+		records[i] = rows.Elem().Index(i).Interface()
+	}
+	return records
+}
+
+func (c *change) event(ctx context.Context, typ events.Type, before any, after any) events.Event {
+	e := events.Event{Type: typ, Entity: c.schema.Table, ID: c.id, Before: before, After: after, Time: time.Now()}
+	if claim, ok := claims.DecryptedFromContext(ctx); ok {
+		e.ActorID = claim.UserID
+		e.ActorName = claim.Username
+	}
+	return e
+}
+
+// copyOf returns a shallow copy of the record so subscribers do not share it with the caller
+func copyOf(record any) any {
+	rv := reflect.ValueOf(record)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return record
+	}
+	copied := reflect.New(rv.Elem().Type())
+	copied.Elem().Set(rv.Elem())
+	return copied.Interface()
+}
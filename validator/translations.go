@@ -0,0 +1,261 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/filllabs/sincap-common/logging"
+	validator "github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+)
+
+// ErrInvalidTranslationsRule is returned for the params of the translations tag with invalid limits
+var ErrInvalidTranslationsRule = errors.New("validator: invalid translations limit")
+
+// fallbackLanguage is the language of the translations tag if there is no LanguageSource
+const fallbackLanguage = "en-US"
+
+// LanguageSource provides the languages of the translations tag. The translations package sets itself as the Languages
+// with the enabled languages of the Language table.
+type LanguageSource interface {
+	// Language returns the language of the context, strings are the texts of the language
+	Language(ctx context.Context) string
+	// DefaultLanguage returns the language required by default
+	DefaultLanguage() string
+	// Enabled returns the languages required by *
+	Enabled() ([]string, error)
+}
+
+// Translated is implemented by the values with texts by languages (e.g. translations.Translations), see RegisterTranslated
+type Translated interface {
+	Map() map[string]string
+}
+
+// Languages provides the languages required by the translations tag. en-US is required if it is nil,
+// the default language if Enabled fails.
+var Languages LanguageSource
+
+// RegisterTranslated registers the types implementing Translated, their texts are validated by the translations tag
+func RegisterTranslated(types ...interface{}) {
+	Validate.RegisterCustomTypeFunc(translatedValues, types...)
+}
+
+// texts are the values of the Translated fields, they replace all the translations of the record
+type texts map[string]string
+
+var textsType = reflect.TypeOf(texts{})
+
+// translationLimit is a language of the translations tag with the length limits of its text, 0 max is unlimited
+type translationLimit struct {
+	lang     string
+	min, max int
+}
+
+// translationRules caches the parsed params of the translations tag
+var translationRules sync.Map
+
+// checkedTypes caches the results of CheckTranslations by the struct types
+var checkedTypes sync.Map
+
+// translatedValues converts the Translated values to texts for the translations tag, Map may have a pointer receiver
+func translatedValues(field reflect.Value) interface{} {
+	ptr := reflect.New(field.Type())
+	ptr.Elem().Set(field)
+	t, ok := ptr.Interface().(Translated)
+	if !ok {
+		return nil
+	}
+	return texts(t.Map())
+}
+
+// translationsRule checks the required languages and the lengths of the texts of the translations
+//
+//	translations                          all enabled languages (see Languages) are required
+//	translations=en-US tr-TR              the languages are required, default is the default language and * the enabled ones
+//	translations=default:1-100 *:200      with the lengths of the texts (:max or :min-max)
+//
+// Values of the maps (e.g. the bodies of ValidateMap) with a single language are merged by translations.Update, so
+// only the length of the language is checked. Strings are the texts of the language of the context.
+// Invalid params fail the validation, use CheckTranslations to find them before.
+func translationsRule(ctx context.Context, fl validator.FieldLevel) bool {
+	rule, err := parseTranslationRule(fl.Param())
+	if err != nil {
+		logging.Logger.Named("Validator").Error("Invalid translations rule", zap.String("field", fl.StructFieldName()), zap.Error(err))
+		return false
+	}
+	limits := requiredTranslations(rule)
+	field := fl.Field()
+	switch field.Kind() {
+	case reflect.String:
+		limit, ok := limits[language(ctx)]
+		return !ok || limit.check(field.String(), true)
+	case reflect.Map:
+		if field.Type().Key().Kind() != reflect.String {
+			return false
+		}
+	default:
+		return false
+	}
+	values := make(map[string]reflect.Value, field.Len())
+	iter := field.MapRange()
+	for iter.Next() {
+		values[iter.Key().String()] = iter.Value()
+	}
+	merged := field.Type() != textsType && len(values) == 1
+	for lang, limit := range limits {
+		value, ok := values[lang]
+		if !ok && merged {
+			continue
+		}
+		if value.Kind() == reflect.Interface && !value.IsNil() {
+			value = value.Elem()
+		}
+		if !limit.check(value.String(), value.Kind() == reflect.String) {
+			return false
+		}
+	}
+	return true
+}
+
+// check reports whether the text is given and its length is in the limits
+func (l translationLimit) check(text string, given bool) bool {
+	if !given || strings.TrimSpace(text) == "" {
+		return false
+	}
+	n := utf8.RuneCountInString(text)
+	return n >= l.min && (l.max == 0 || n <= l.max)
+}
+
+// CheckTranslations checks the params of the translations tags of the struct and its nested structs.
+// Results are cached by the types, so it may be called whenever a struct is validated.
+func CheckTranslations(s interface{}) error {
+	t := reflect.TypeOf(s)
+	if t == nil {
+		return nil
+	}
+	if err, ok := checkedTypes.Load(t); ok {
+		err, _ := err.(error)
+		return err
+	}
+	err := checkType(t, map[reflect.Type]bool{})
+	checkedTypes.Store(t, err)
+	return err
+}
+
+func checkType(t reflect.Type, visited map[reflect.Type]bool) error {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return nil
+	}
+	visited[t] = true
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if err := CheckRules(field.Tag.Get("validate")); err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		if err := checkType(field.Type, visited); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckRules checks the params of the translations tags of the validate tag (e.g. the rules of ValidateMap)
+func CheckRules(tag string) error {
+	for _, rules := range strings.Split(tag, ",") {
+		for _, rule := range strings.Split(rules, "|") {
+			if strings.HasPrefix(rule, "translations=") {
+				if _, err := parseTranslationRule(strings.TrimPrefix(rule, "translations=")); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// parseTranslationRule parses the param of the translations tag
+func parseTranslationRule(param string) ([]translationLimit, error) {
+	if rule, ok := translationRules.Load(param); ok {
+		return rule.([]translationLimit), nil
+	}
+	tokens := strings.Fields(param)
+	if len(tokens) == 0 {
+		tokens = []string{"*"}
+	}
+	rule := make([]translationLimit, len(tokens))
+	for i, token := range tokens {
+		lang, limit, hasLimit := strings.Cut(token, ":")
+		rule[i].lang = lang
+		if !hasLimit {
+			continue
+		}
+		min, max, hasMin := strings.Cut(limit, "-")
+		if !hasMin {
+			min, max = "0", limit
+		}
+		var err error
+		if rule[i].min, err = strconv.Atoi(min); err != nil {
+			return nil, fmt.Errorf("%w %s", ErrInvalidTranslationsRule, token)
+		}
+		if rule[i].max, err = strconv.Atoi(max); err != nil {
+			return nil, fmt.Errorf("%w %s", ErrInvalidTranslationsRule, token)
+		}
+	}
+	translationRules.Store(param, rule)
+	return rule, nil
+}
+
+// requiredTranslations returns the limits by the required languages, the later ones of the rule override
+func requiredTranslations(rule []translationLimit) map[string]translationLimit {
+	limits := make(map[string]translationLimit, len(rule))
+	for _, limit := range rule {
+		switch limit.lang {
+		case "*":
+			for _, lang := range enabledLanguages() {
+				limits[lang] = limit
+			}
+		case "default":
+			limits[defaultLanguage()] = limit
+		default:
+			limits[limit.lang] = limit
+		}
+	}
+	return limits
+}
+
+// enabledLanguages returns the enabled languages of Languages, the default language if there are none
+func enabledLanguages() []string {
+	if Languages != nil {
+		langs, err := Languages.Enabled()
+		if err == nil && len(langs) > 0 {
+			return langs
+		}
+		if err != nil {
+			logging.Logger.Named("Validator").Warn("Can't read the languages", zap.Error(err))
+		}
+	}
+	return []string{defaultLanguage()}
+}
+
+func defaultLanguage() string {
+	if Languages == nil {
+		return fallbackLanguage
+	}
+	return Languages.DefaultLanguage()
+}
+
+func language(ctx context.Context) string {
+	if Languages == nil {
+		return fallbackLanguage
+	}
+	return Languages.Language(ctx)
+}
@@ -0,0 +1,101 @@
+package validator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type mockTranslations struct {
+	texts map[string]string
+}
+
+func (t *mockTranslations) Map() map[string]string {
+	return t.texts
+}
+
+type langKey struct{}
+
+type mockLanguages struct {
+	enabled func() ([]string, error)
+}
+
+func (l mockLanguages) Language(ctx context.Context) string {
+	if lang, ok := ctx.Value(langKey{}).(string); ok {
+		return lang
+	}
+	return l.DefaultLanguage()
+}
+
+func (mockLanguages) DefaultLanguage() string {
+	return "en-US"
+}
+
+func (l mockLanguages) Enabled() ([]string, error) {
+	if l.enabled == nil {
+		return nil, nil
+	}
+	return l.enabled()
+}
+
+func init() {
+	RegisterTranslated(mockTranslations{})
+}
+
+type mockProduct struct {
+	Name        *mockTranslations `validate:"translations=default tr-TR:2-5"`
+	Description *mockTranslations `validate:"omitempty,translations"`
+}
+
+type mockInvalid struct {
+	Products []mockProduct
+	Name     *mockTranslations `validate:"required,translations=en-US:x"`
+}
+
+func newTranslations(texts map[string]string) *mockTranslations {
+	return &mockTranslations{texts: texts}
+}
+
+func TestTranslations(t *testing.T) {
+	Languages = mockLanguages{}
+	defer func() { Languages = nil }()
+	valid := newTranslations(map[string]string{"en-US": "Pen", "tr-TR": "Kalem", "de-DE": "Stift"})
+
+	assert.NoError(t, Validate.Struct(mockProduct{Name: valid}))
+	assert.Error(t, Validate.Struct(mockProduct{}))
+	assert.Error(t, Validate.Struct(mockProduct{Name: newTranslations(map[string]string{"tr-TR": "Kalem"})}))
+	assert.Error(t, Validate.Struct(mockProduct{Name: newTranslations(map[string]string{"en-US": "Pen", "tr-TR": "Kurşun kalem"})}))
+	assert.Error(t, Validate.Struct(mockProduct{Name: newTranslations(map[string]string{"en-US": " ", "tr-TR": "Kalem"})}))
+
+	Languages = mockLanguages{enabled: func() ([]string, error) { return []string{"en-US", "de-DE"}, nil }}
+	assert.NoError(t, Validate.Struct(mockProduct{Name: valid, Description: valid}))
+	assert.Error(t, Validate.Struct(mockProduct{Name: valid, Description: newTranslations(map[string]string{"en-US": "Pen"})}))
+	Languages = mockLanguages{enabled: func() ([]string, error) { return nil, errors.New("no db") }}
+	assert.NoError(t, Validate.Struct(mockProduct{Name: valid, Description: newTranslations(map[string]string{"en-US": "Pen"})}))
+
+	rules := map[string]interface{}{"Name": "translations=default tr-TR:2-5"}
+	// single languages are merged
+	assert.Empty(t, Validate.ValidateMap(map[string]interface{}{"Name": map[string]interface{}{"de-DE": "Stift"}}, rules))
+	assert.NotEmpty(t, Validate.ValidateMap(map[string]interface{}{"Name": map[string]interface{}{"tr-TR": "Kurşun kalem"}}, rules))
+	assert.NotEmpty(t, Validate.ValidateMap(map[string]interface{}{"Name": map[string]interface{}{"en-US": nil}}, rules))
+	// multiple languages replace all
+	assert.NotEmpty(t, Validate.ValidateMap(map[string]interface{}{"Name": map[string]interface{}{"en-US": "Pen", "de-DE": "Stift"}}, rules))
+	assert.Empty(t, Validate.ValidateMap(map[string]interface{}{"Name": map[string]interface{}{"en-US": "Pen", "tr-TR": "Kalem"}}, rules))
+	// strings are in the language of the context
+	ctx := context.WithValue(context.Background(), langKey{}, "tr-TR")
+	assert.NotEmpty(t, Validate.ValidateMapCtx(ctx, map[string]interface{}{"Name": "Kurşun kalem"}, rules))
+	assert.Empty(t, Validate.ValidateMapCtx(ctx, map[string]interface{}{"Name": "Kalem"}, rules))
+
+	assert.Error(t, Validate.Var(map[string]string{"en-US": "Pen"}, "translations=en-US:x"))
+}
+
+func TestCheckTranslations(t *testing.T) {
+	assert.NoError(t, CheckTranslations(&mockProduct{}))
+	assert.NoError(t, CheckTranslations([]mockProduct{}))
+	assert.ErrorIs(t, CheckTranslations(&mockInvalid{}), ErrInvalidTranslationsRule)
+	assert.ErrorIs(t, CheckTranslations(mockInvalid{}), ErrInvalidTranslationsRule)
+	assert.ErrorIs(t, CheckRules("required,translations=default *:1-x"), ErrInvalidTranslationsRule)
+	assert.NoError(t, CheckRules("omitempty|translations=default *:1-10"))
+}
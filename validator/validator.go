@@ -1,4 +1,4 @@
-// Package validator provides commonly needed valitator utilities and a validator instance with some implemented valitations (iban, phone, plate, etc.)
+// Package validator provides commonly needed valitator utilities and a validator instance with some implemented valitations (iban, phone, plate, translations, etc.)
 package validator
 
 import (
@@ -14,6 +14,7 @@ func init() {
 	Validate.RegisterValidation("iban", iban)
 	Validate.RegisterValidation("phone", phone)
 	Validate.RegisterValidation("plate", plate)
+	Validate.RegisterValidationCtx("translations", translationsRule)
 }
 
 var ibanRegexp = regexp.MustCompile("([A-Za-z]{2})([0-9]{24})")